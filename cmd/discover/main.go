@@ -0,0 +1,48 @@
+// Command discover prints the endpoints of the Trusted Artifact Signer
+// deployment in the current cluster, e.g.
+//
+//	go run ./cmd/discover -format dotenv > .env
+//	eval "$(go run ./cmd/discover -format shell)"
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/securesign/sigstore-e2e/pkg/discovery"
+	"github.com/securesign/sigstore-e2e/pkg/kubernetes"
+)
+
+func main() {
+	opts := discovery.DefaultOptions()
+	format := flag.String("format", discovery.FormatDotenv, "output format: "+strings.Join(discovery.Formats, ", "))
+	flag.StringVar(&opts.Namespace, "namespace", "", "namespace of the Securesign resources (default: all namespaces)")
+	flag.StringVar(&opts.KeycloakNamespace, "keycloak-namespace", opts.KeycloakNamespace, "namespace of the Keycloak route, empty to skip")
+	flag.StringVar(&opts.Realm, "realm", opts.Realm, "Keycloak realm of the OIDC issuer")
+	flag.Parse()
+
+	if err := run(context.Background(), opts, *format); err != nil {
+		fmt.Fprintln(os.Stderr, "discover:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, opts discovery.Options, format string) error {
+	endpoints, err := discovery.Discover(ctx, kubernetes.GetClient(), opts)
+	if err != nil {
+		return err
+	}
+	vars := endpoints.Vars()
+	// values from the environment or the selected profile take precedence
+	for key := range vars {
+		if value := api.GetValueFor(key); value != "" {
+			vars[key] = value
+		}
+	}
+	vars[api.OidcClientID] = api.GetValueFor(api.OidcClientID)
+	return discovery.Write(os.Stdout, discovery.WithCLIVars(vars), format)
+}
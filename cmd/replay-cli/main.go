@@ -0,0 +1,14 @@
+// Command replay-cli is the fake CLI installed by the replay and record
+// strategies. It is started by their launcher with the tool, the recordings
+// directory and the mode in its environment; see package replay.
+package main
+
+import (
+	"os"
+
+	"github.com/securesign/sigstore-e2e/pkg/strategy/replay"
+)
+
+func main() {
+	os.Exit(replay.RunFake(os.Args[1:], os.Stdout, os.Stderr))
+}
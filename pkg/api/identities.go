@@ -0,0 +1,77 @@
+package api
+
+import (
+	"slices"
+	"strings"
+)
+
+// Identities lists the names of OIDC identities besides the default one, e.g.
+// OIDC_IDENTITIES=other. The settings of an identity are the OIDC keys
+// prefixed with IDENTITY_<NAME>_, e.g. IDENTITY_OTHER_OIDC_USER=jane.
+const Identities = "OIDC_IDENTITIES"
+
+// DefaultIdentity is the identity configured by the unprefixed OIDC keys.
+const DefaultIdentity = "default"
+
+// IdentityKeys can be set per identity. Keys other than OIDC_TOKEN and
+// OIDC_REFRESH_TOKEN fall back to the value of the default identity.
+var IdentityKeys = []string{
+	OidcIssuerURL, OidcToken, OidcUser, OidcPassword, OidcUserDomain,
+	OidcRealm, OidcClientID, OidcClientSecret, OidcGrantType, OidcRefreshToken,
+}
+
+// ConfiguredIdentities returns the default identity followed by the names in OIDC_IDENTITIES.
+func ConfiguredIdentities() []string {
+	names := []string{DefaultIdentity}
+	for _, name := range strings.Split(Values.GetString(Identities), ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// IdentityKey returns the name of key for identity.
+func IdentityKey(identity string, key string) string {
+	if identity == "" || identity == DefaultIdentity {
+		return key
+	}
+	return "IDENTITY_" + strings.ToUpper(identity) + "_" + key
+}
+
+// GetIdentityValueFor returns the value of key for identity.
+func GetIdentityValueFor(identity string, key string) string {
+	if identity == "" {
+		identity = DefaultIdentity
+	}
+	if value := GetValueFor(IdentityKey(identity, key)); value != "" || identity == DefaultIdentity {
+		return value
+	}
+	if key == OidcToken || key == OidcRefreshToken {
+		// tokens belong to a single identity
+		return ""
+	}
+	return GetValueFor(key)
+}
+
+// schemaKey returns the key an identity key is derived from.
+func schemaKey(key string) string {
+	key = strings.ToUpper(key)
+	for _, identity := range ConfiguredIdentities()[1:] {
+		if base, ok := strings.CutPrefix(key, IdentityKey(identity, "")); ok && slices.Contains(IdentityKeys, base) {
+			return base
+		}
+	}
+	return key
+}
+
+// identityKeys returns the keys of the identities besides the default one.
+func identityKeys() []string {
+	var keys []string
+	for _, identity := range ConfiguredIdentities()[1:] {
+		for _, key := range IdentityKeys {
+			keys = append(keys, IdentityKey(identity, key))
+		}
+	}
+	return keys
+}
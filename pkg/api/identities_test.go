@@ -0,0 +1,49 @@
+package api
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestIdentities(t *testing.T) {
+	withValues(t)
+	t.Setenv(Identities, "Other, other,mismatch")
+	t.Setenv(OidcUser, "jdoe")
+	t.Setenv(OidcToken, "default-token")
+	t.Setenv(OidcPassword, "secure")
+	t.Setenv("IDENTITY_OTHER_OIDC_USER", "jane")
+	t.Setenv("IDENTITY_OTHER_OIDC_PASSWORD", "other-password")
+	t.Setenv("IDENTITY_MISMATCH_OIDC_ISSUER_URL", "not a url")
+
+	if got := ConfiguredIdentities(); !slices.Equal(got, []string{DefaultIdentity, "other", "mismatch"}) {
+		t.Fatalf("ConfiguredIdentities() = %v", got)
+	}
+	tests := []struct {
+		identity, key, want string
+	}{
+		{DefaultIdentity, OidcUser, "jdoe"},
+		{"", OidcUser, "jdoe"},
+		{"other", OidcUser, "jane"},
+		{"mismatch", OidcUser, "jdoe"},
+		{"mismatch", OidcPassword, "secure"},
+		// tokens are not shared between identities
+		{"other", OidcToken, ""},
+		{DefaultIdentity, OidcToken, "default-token"},
+	}
+	for _, tt := range tests {
+		if got := GetIdentityValueFor(tt.identity, tt.key); got != tt.want {
+			t.Errorf("GetIdentityValueFor(%q, %s) = %q, want %q", tt.identity, tt.key, got, tt.want)
+		}
+	}
+
+	if keys := SecretKeys(); !slices.Contains(keys, "IDENTITY_OTHER_OIDC_PASSWORD") || slices.Contains(keys, "IDENTITY_OTHER_OIDC_USER") {
+		t.Errorf("SecretKeys() = %v", keys)
+	}
+	if got := Settings()["IDENTITY_OTHER_OIDC_USER"]; got != "jane" {
+		t.Errorf("Settings()[IDENTITY_OTHER_OIDC_USER] = %q", got)
+	}
+	if err := Validate(); err == nil || !strings.Contains(err.Error(), "IDENTITY_MISMATCH_OIDC_ISSUER_URL") {
+		t.Errorf("Validate() = %v", err)
+	}
+}
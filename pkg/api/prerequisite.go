@@ -2,9 +2,94 @@ package api
 
 import (
 	"context"
+	"fmt"
+	"reflect"
 )
 
 type TestPrerequisite interface {
 	Setup(ctx context.Context) error
 	Destroy(ctx context.Context) error
 }
+
+// ReadinessChecker is implemented by prerequisites that need time after Setup
+// before they can be used (e.g. a registry container accepting connections).
+// Ready returns nil once the prerequisite is usable.
+type ReadinessChecker interface {
+	Ready(ctx context.Context) error
+}
+
+// Dependent is implemented by prerequisites that require other prerequisites
+// to be installed first (e.g. a signed test image requires a registry and cosign).
+type Dependent interface {
+	DependsOn() []TestPrerequisite
+}
+
+// Named is implemented by prerequisites that report a human-readable name.
+type Named interface {
+	GetName() string
+}
+
+// PrerequisiteName returns the reporting name of p.
+func PrerequisiteName(p TestPrerequisite) string {
+	if n, ok := p.(Named); ok && n.GetName() != "" {
+		return n.GetName()
+	}
+	return fmt.Sprintf("%T", p)
+}
+
+// ResolvePrerequisites returns the given prerequisites together with their
+// transitive dependencies, ordered so that every prerequisite comes after the
+// ones it depends on. The relative order of independent prerequisites is kept.
+// Each prerequisite appears only once, even if several others depend on it.
+// Prerequisites are told apart by ==, so they must be comparable, e.g.
+// pointers; other prerequisites are reported as an error.
+func ResolvePrerequisites(prerequisites ...TestPrerequisite) ([]TestPrerequisite, error) {
+	const (
+		visiting = iota + 1
+		done
+	)
+	state := map[TestPrerequisite]int{}
+	ordered := make([]TestPrerequisite, 0, len(prerequisites))
+
+	var visit func(p TestPrerequisite, path []TestPrerequisite) error
+	visit = func(p TestPrerequisite, path []TestPrerequisite) error {
+		if !reflect.ValueOf(p).Comparable() {
+			return fmt.Errorf("prerequisite %s is not comparable, use a pointer to it", PrerequisiteName(p))
+		}
+		switch state[p] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle detected: %s", cyclePath(append(path, p)))
+		}
+		state[p] = visiting
+		if d, ok := p.(Dependent); ok {
+			for _, dep := range d.DependsOn() {
+				if err := visit(dep, append(path, p)); err != nil {
+					return err
+				}
+			}
+		}
+		state[p] = done
+		ordered = append(ordered, p)
+		return nil
+	}
+
+	for _, p := range prerequisites {
+		if err := visit(p, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+func cyclePath(path []TestPrerequisite) string {
+	var s string
+	for i, p := range path {
+		if i > 0 {
+			s += " -> "
+		}
+		s += PrerequisiteName(p)
+	}
+	return s
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type fakePrerequisite struct {
+	name string
+	deps []TestPrerequisite
+}
+
+func (f *fakePrerequisite) Setup(_ context.Context) error   { return nil }
+func (f *fakePrerequisite) Destroy(_ context.Context) error { return nil }
+func (f *fakePrerequisite) GetName() string                 { return f.name }
+func (f *fakePrerequisite) DependsOn() []TestPrerequisite   { return f.deps }
+
+func names(prerequisites []TestPrerequisite) string {
+	s := make([]string, 0, len(prerequisites))
+	for _, p := range prerequisites {
+		s = append(s, PrerequisiteName(p))
+	}
+	return strings.Join(s, ",")
+}
+
+func TestResolvePrerequisites(t *testing.T) {
+	registry := &fakePrerequisite{name: "registry"}
+	cosign := &fakePrerequisite{name: "cosign"}
+	image := &fakePrerequisite{name: "image", deps: []TestPrerequisite{registry, cosign}}
+	rekorCli := &fakePrerequisite{name: "rekor-cli"}
+
+	ordered, err := ResolvePrerequisites(image, rekorCli, cosign)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := names(ordered), "registry,cosign,image,rekor-cli"; got != want {
+		t.Fatalf("got order %s, want %s", got, want)
+	}
+}
+
+func TestResolvePrerequisitesCycle(t *testing.T) {
+	a := &fakePrerequisite{name: "a"}
+	b := &fakePrerequisite{name: "b", deps: []TestPrerequisite{a}}
+	a.deps = []TestPrerequisite{b}
+
+	_, err := ResolvePrerequisites(a)
+	if err == nil {
+		t.Fatal("expected error for dependency cycle")
+	}
+	if !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Fatalf("expected cycle path in error, got %v", err)
+	}
+}
+
+// sliceValuePrerequisite is not comparable, as it holds a slice by value.
+type sliceValuePrerequisite struct {
+	deps []TestPrerequisite
+}
+
+func (s sliceValuePrerequisite) Setup(_ context.Context) error   { return nil }
+func (s sliceValuePrerequisite) Destroy(_ context.Context) error { return nil }
+
+func TestResolvePrerequisitesNotComparable(t *testing.T) {
+	dep := &fakePrerequisite{name: "dep"}
+	_, err := ResolvePrerequisites(dep, sliceValuePrerequisite{deps: []TestPrerequisite{dep}})
+	if err == nil || !strings.Contains(err.Error(), "api.sliceValuePrerequisite is not comparable") {
+		t.Fatalf("expected an error for a non-comparable prerequisite, got %v", err)
+	}
+}
+
+func TestPrerequisiteName(t *testing.T) {
+	if got := PrerequisiteName(&fakePrerequisite{name: "cosign"}); got != "cosign" {
+		t.Fatalf("got %q, want cosign", got)
+	}
+	if got := PrerequisiteName(&fakePrerequisite{}); got != "*api.fakePrerequisite" {
+		t.Fatalf("got %q, want type name fallback", got)
+	}
+}
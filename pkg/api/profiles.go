@@ -0,0 +1,107 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// Profile selects a named profile from the profiles file.
+	Profile = "E2E_PROFILE"
+	// ProfilesFile overrides the location of the profiles file (YAML or JSON).
+	ProfilesFile = "E2E_PROFILES_FILE"
+)
+
+// profileErr is the error of loading the profile selected with E2E_PROFILE at
+// startup. It is reported by Validate instead of failing the import.
+var profileErr error
+
+// ProfileError returns the error of loading the selected profile, if any.
+func ProfileError() error {
+	return profileErr
+}
+
+// DefaultProfilesFile returns ~/.config/sigstore-e2e/profiles.yaml, or the
+// equivalent user config directory of the platform.
+func DefaultProfilesFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "sigstore-e2e", "profiles.yaml")
+}
+
+// LoadProfile merges the values of profile from the profiles file at path into
+// Values. Profile values take precedence over defaults; environment variables
+// still override them. The file maps profile names to keys and values:
+//
+//	staging:
+//	  TUF_URL: https://tuf.staging.example.com
+//	  CLI_STRATEGY: openshift
+func LoadProfile(path string, profile string) error {
+	file := viper.New()
+	file.SetConfigFile(path)
+	if err := file.ReadInConfig(); err != nil {
+		return fmt.Errorf("cannot read profiles file %s: %w", path, err)
+	}
+	if !file.IsSet(profile) {
+		return fmt.Errorf("profile %q not found in %s (available: %s)", profile, path, strings.Join(profileNames(file), ", "))
+	}
+	values := file.GetStringMap(profile)
+	if len(values) == 0 {
+		return fmt.Errorf("profile %q in %s is not a map of configuration keys", profile, path)
+	}
+	settings := make(map[string]any, len(values))
+	for k, v := range values {
+		settings[strings.ToUpper(k)] = fmt.Sprint(v)
+	}
+	return Values.MergeConfigMap(settings)
+}
+
+func profileNames(file *viper.Viper) []string {
+	names := make([]string, 0)
+	for name := range file.AllSettings() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadSelectedProfile loads the profile selected with E2E_PROFILE, if any.
+func loadSelectedProfile() error {
+	profile := Values.GetString(Profile)
+	if profile == "" {
+		return nil
+	}
+	path := Values.GetString(ProfilesFile)
+	if path == "" {
+		path = DefaultProfilesFile()
+	}
+	if path == "" {
+		return errors.New("cannot determine the profiles file, set " + ProfilesFile)
+	}
+	return LoadProfile(path, profile)
+}
+
+// Settings returns the effective value of every key in Schema, of the keys of
+// the configured identities and of every key set in the selected profile. Secrets are not masked and secret
+// references are returned as they are, not resolved.
+func Settings() map[string]string {
+	settings := map[string]string{}
+	for _, k := range Schema {
+		settings[k.Name] = Values.GetString(k.Name)
+	}
+	for _, k := range identityKeys() {
+		settings[k] = Values.GetString(k)
+	}
+	for _, k := range Values.AllKeys() {
+		settings[strings.ToUpper(k)] = Values.GetString(k)
+	}
+	return settings
+}
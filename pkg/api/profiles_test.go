@@ -0,0 +1,122 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+const profilesYAML = `
+staging:
+  TUF_URL: https://tuf.staging.example.com
+  CLI_STRATEGY: openshift
+  HEADLESS_UI: false
+production:
+  TUF_URL: https://tuf.example.com
+`
+
+func withValues(t *testing.T) {
+	t.Helper()
+	old := Values
+	Values = viper.New()
+	Values.SetDefault(CliStrategy, "local")
+	Values.AutomaticEnv()
+	t.Cleanup(func() { Values = old })
+}
+
+func writeProfiles(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadProfile(t *testing.T) {
+	withValues(t)
+	t.Setenv(TufURL, "")
+	t.Setenv(CliStrategy, "")
+	path := writeProfiles(t, "profiles.yaml", profilesYAML)
+
+	if err := LoadProfile(path, "staging"); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{
+		TufURL:      "https://tuf.staging.example.com",
+		CliStrategy: "openshift",
+		HeadlessUI:  "false",
+	} {
+		if got := GetValueFor(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if got := Settings()[TufURL]; got != "https://tuf.staging.example.com" {
+		t.Errorf("Settings()[%s] = %q", TufURL, got)
+	}
+}
+
+func TestLoadProfileEnvironmentOverrides(t *testing.T) {
+	withValues(t)
+	t.Setenv(TufURL, "https://tuf.local")
+	path := writeProfiles(t, "profiles.yaml", profilesYAML)
+
+	if err := LoadProfile(path, "production"); err != nil {
+		t.Fatal(err)
+	}
+	if got := GetValueFor(TufURL); got != "https://tuf.local" {
+		t.Errorf("%s = %q, want the environment value", TufURL, got)
+	}
+}
+
+func TestLoadProfileJSON(t *testing.T) {
+	withValues(t)
+	t.Setenv(TufURL, "")
+	path := writeProfiles(t, "profiles.json", `{"dev": {"TUF_URL": "http://localhost:8080"}}`)
+
+	if err := LoadProfile(path, "dev"); err != nil {
+		t.Fatal(err)
+	}
+	if got := GetValueFor(TufURL); got != "http://localhost:8080" {
+		t.Errorf("%s = %q", TufURL, got)
+	}
+}
+
+func TestLoadProfileErrors(t *testing.T) {
+	withValues(t)
+	path := writeProfiles(t, "profiles.yaml", profilesYAML)
+
+	tests := []struct {
+		path    string
+		profile string
+		wantErr string
+	}{
+		{path, "qa", "available: production, staging"},
+		{filepath.Join(t.TempDir(), "missing.yaml"), "staging", "cannot read profiles file"},
+		{writeProfiles(t, "scalar.yaml", "dev: true\n"), "dev", "not a map"},
+	}
+	for _, tt := range tests {
+		err := LoadProfile(tt.path, tt.profile)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("LoadProfile(%s, %s) = %v, want error containing %q", tt.path, tt.profile, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSelectedProfileError(t *testing.T) {
+	withValues(t)
+	defer func(err error) { profileErr = err }(profileErr)
+	t.Setenv(Profile, "qa")
+	t.Setenv(ProfilesFile, writeProfiles(t, "profiles.yaml", profilesYAML))
+
+	profileErr = loadSelectedProfile()
+	if profileErr == nil {
+		t.Fatal("expected an error loading a missing profile")
+	}
+	if err := Validate(); err == nil || !strings.Contains(err.Error(), `profile "qa" not found`) {
+		t.Errorf("Validate() = %v, want the profile error", err)
+	}
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type is the type of a configuration value.
+type Type string
+
+const (
+	TypeString   Type = "string"
+	TypeURL      Type = "url"
+	TypeBool     Type = "bool"
+	TypeDuration Type = "duration"
+	TypeEnum     Type = "enum"
+)
+
+// Key describes a configuration key.
+type Key struct {
+	Name        string
+	Type        Type
+	Description string
+	// Required keys must be set for every suite.
+	Required bool
+	// Secret values are masked in logs and reports.
+	Secret bool
+	// Suites lists the test suites using the key; empty means all suites.
+	Suites []string
+	// Values are the allowed values of an enum.
+	Values []string
+}
+
+// Schema describes every configuration key.
+var Schema = []Key{
+	{Name: TufURL, Type: TypeURL, Required: true, Description: "URL of the TUF repository of the Sigstore deployment"},
+	{Name: FulcioURL, Type: TypeURL, Description: "URL of the Fulcio certificate authority", Suites: []string{"gitsign", "rekorsearchui"}},
+	{Name: RekorURL, Type: TypeURL, Description: "URL of the Rekor transparency log", Suites: []string{"gitsign", "rekorcli", "rekorsearchui"}},
+	{Name: RekorPublicKey, Type: TypeString, Description: "PEM file of the Rekor public key, when it is not distributed by TUF_URL"},
+	{Name: RekorUIURL, Type: TypeURL, Description: "URL of the Rekor search UI", Suites: []string{"rekorsearchui"}},
+	{Name: TsaURL, Type: TypeURL, Description: "URL of the timestamp authority", Suites: []string{"cosign"}},
+	{Name: OidcIssuerURL, Type: TypeURL, Description: "URL of the OIDC issuer (Keycloak realm)"},
+	{Name: OidcToken, Type: TypeString, Secret: true, Description: "OIDC identity token; when set, no token is requested from the issuer"},
+	{Name: OidcUser, Type: TypeString, Description: "user requesting OIDC tokens"},
+	{Name: OidcPassword, Type: TypeString, Secret: true, Description: "password of OIDC_USER"},
+	{Name: OidcUserDomain, Type: TypeString, Description: "email domain of OIDC_USER, used to match certificate identities"},
+	{Name: OidcRealm, Type: TypeString, Description: "Keycloak realm and client requesting OIDC tokens"},
+	{Name: OidcClientID, Type: TypeString, Description: "OIDC client ID used by the CLIs"},
+	{Name: OidcGrantType, Type: TypeEnum, Values: []string{"password", "client_credentials", "refresh_token"},
+		Description: "grant used to request OIDC tokens when OIDC_TOKEN is not set"},
+	{Name: OidcClientSecret, Type: TypeString, Secret: true, Description: "secret of the client requesting OIDC tokens, required by the client_credentials grant"},
+	{Name: OidcRefreshToken, Type: TypeString, Secret: true, Description: "refresh token used by the refresh_token grant"},
+	{Name: Identities, Type: TypeString, Description: "comma-separated names of further OIDC identities, configured with IDENTITY_<NAME>_OIDC_* keys"},
+	{Name: GithubToken, Type: TypeString, Secret: true, Description: "authorization token for the GitHub client", Suites: []string{"gitsign"}},
+	{Name: GithubUsername, Type: TypeString, Description: "GitHub user pushing signed commits", Suites: []string{"gitsign"}},
+	{Name: GithubOwner, Type: TypeString, Description: "owner of the GitHub repository used by the gitsign suite", Suites: []string{"gitsign"}},
+	{Name: GithubRepo, Type: TypeString, Description: "GitHub repository used by the gitsign suite", Suites: []string{"gitsign"}},
+	{Name: CliStrategy, Type: TypeEnum, Values: []string{"local", "openshift", "cli_server", "cgw", "container", "git", "record", "replay"},
+		Description: "how CLI binaries are obtained"},
+	{Name: CLIServerURL, Type: TypeURL, Description: "CLI server used by the cli_server strategy"},
+	{Name: CGWURL, Type: TypeURL, Description: "content gateway URL, including the RHTAS version, used by the cgw strategy"},
+	{Name: ContainerImage, Type: TypeString, Description: "image the container strategy extracts CLIs from"},
+	{Name: ContainerPath, Type: TypeString, Description: "directory of the CLIs in CONTAINER_IMAGE"},
+	{Name: GitURL, Type: TypeURL, Description: "repository the git strategy builds CLIs from"},
+	{Name: GitBranch, Type: TypeString, Description: "branch checked out by the git strategy"},
+	{Name: GitBuildDir, Type: TypeString, Description: "directory the git strategy builds in"},
+	{Name: ReplayDir, Type: TypeString, Description: "directory of the recordings of the record and replay strategies"},
+	{Name: ReplayCLI, Type: TypeString, Description: "replay-cli binary run by the record and replay strategies (make replay-cli), looked up on PATH when unset"},
+	{Name: ReplayStrategy, Type: TypeEnum, Values: []string{"local", "openshift", "cli_server", "cgw", "container", "git"},
+		Description: "strategy providing the binaries recorded by the record strategy"},
+	{Name: ManualImageSetup, Type: TypeBool, Description: "use TARGET_IMAGE_NAME instead of copying a test image"},
+	{Name: TargetImageName, Type: TypeString, Description: "image signed when MANUAL_IMAGE_SETUP is true"},
+	{Name: CosignImage, Type: TypeString, Description: "cosign image used by the benchmarks", Suites: []string{"benchmark"}},
+	{Name: RegistryImage, Type: TypeString, Description: "registry image used by the benchmarks", Suites: []string{"benchmark"}},
+	{Name: HeadlessUI, Type: TypeBool, Description: "run browsers without a window", Suites: []string{"rekorsearchui"}},
+	{Name: TestFirefox, Type: TypeBool, Description: "run the UI tests in Firefox", Suites: []string{"rekorsearchui"}},
+	{Name: TestSafari, Type: TypeBool, Description: "run the UI tests in Safari", Suites: []string{"rekorsearchui"}},
+	{Name: TestEdge, Type: TypeBool, Description: "run the UI tests in Edge", Suites: []string{"rekorsearchui"}},
+	{Name: TranscriptDir, Type: TypeString, Description: "directory of CLI transcripts, resource usage and build inventories"},
+	{Name: UsageThresholds, Type: TypeString, Description: "resource limits per command, e.g. 'cosign verify:rss=256Mi,wall=30s'"},
+	{Name: BuildExpectations, Type: TypeString, Description: "build requirements per CLI, e.g. 'cosign:fips,go>=1.22'"},
+	{Name: DockerRegistryUsername, Type: TypeString, Description: "user logging in to registry.redhat.io"},
+	{Name: DockerRegistryPassword, Type: TypeString, Secret: true, Description: "password of REGISTRY_USERNAME"},
+	{Name: Discovery, Type: TypeBool, Description: "discover unset service URLs from the Securesign resources in the current cluster"},
+	{Name: TufDiscovery, Type: TypeBool, Description: "discover unset service URLs from trusted_root.json and signing_config.json in the TUF repository at TUF_URL"},
+	{Name: TufRootJSON, Type: TypeString, Description: "pinned TUF root verifying TUF_URL, by default its root.json is trusted on first use"},
+	{Name: LocalStack, Type: TypeBool, Description: "run the suites against Sigstore components started as local containers instead of a cluster"},
+	{Name: LocalStackImages, Type: TypeString, Description: "comma-separated component=image overrides of the LOCAL_STACK images"},
+	{Name: Preflight, Type: TypeBool, Description: "probe the configured services before the suites and skip the suites depending on failed ones"},
+	{Name: Profile, Type: TypeString, Description: "profile selected from the profiles file"},
+	{Name: ProfilesFile, Type: TypeString, Description: "location of the profiles file, by default ~/.config/sigstore-e2e/profiles.yaml"},
+}
+
+// Lookup returns the schema of key.
+func Lookup(key string) (Key, bool) {
+	i := slices.IndexFunc(Schema, func(k Key) bool { return k.Name == key })
+	if i < 0 {
+		return Key{}, false
+	}
+	return Schema[i], true
+}
+
+// RequiredKeys returns the names of the keys every suite needs.
+func RequiredKeys() []string {
+	var keys []string
+	for _, k := range Schema {
+		if k.Required {
+			keys = append(keys, k.Name)
+		}
+	}
+	return keys
+}
+
+// SecretKeys returns the names of the keys holding secrets, including the
+// secrets of the configured identities.
+func SecretKeys() []string {
+	var keys []string
+	for _, k := range Schema {
+		if k.Secret {
+			keys = append(keys, k.Name)
+		}
+	}
+	for _, key := range identityKeys() {
+		if k, ok := Lookup(schemaKey(key)); ok && k.Secret {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Validate returns an error if value is not a valid value of k. Empty values are valid.
+func (k Key) Validate(value string) error {
+	if value == "" {
+		return nil
+	}
+	var err error
+	switch k.Type {
+	case TypeURL:
+		var u *url.URL
+		if u, err = url.Parse(value); err == nil && (u.Scheme == "" || u.Host == "") {
+			err = fmt.Errorf("expected an absolute URL, got %q", value)
+		}
+	case TypeBool:
+		_, err = strconv.ParseBool(value)
+	case TypeDuration:
+		_, err = time.ParseDuration(value)
+	case TypeEnum:
+		if !slices.Contains(k.Values, value) {
+			err = fmt.Errorf("expected one of %s, got %q", strings.Join(k.Values, ", "), value)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s %s: %w", k.Type, k.Name, err)
+	}
+	return nil
+}
+
+// Validate checks the value of every key in Schema and reports a selected
+// profile that could not be loaded.
+func Validate() error {
+	errs := []error{profileErr}
+	for _, k := range Schema {
+		value, err := ResolveValueFor(k.Name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		errs = append(errs, k.Validate(value))
+	}
+	for _, key := range identityKeys() {
+		k, _ := Lookup(schemaKey(key))
+		k.Name = key
+		value, err := ResolveValueFor(key)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		errs = append(errs, k.Validate(value))
+	}
+	return errors.Join(errs...)
+}
+
+// Help describes k and how to provide it.
+func (k Key) Help() string {
+	help := fmt.Sprintf("%s (%s): %s.", k.Name, k.Type, k.Description)
+	if k.Type == TypeEnum {
+		help += " One of " + strings.Join(k.Values, ", ") + "."
+	}
+	if len(k.Suites) > 0 {
+		help += " Used by " + strings.Join(k.Suites, ", ") + "."
+	}
+	return help + fmt.Sprintf(" Export it as environment variable %s or set it in the selected profile.", k.Name)
+}
+
+// GetBool returns the value of a bool key, or false if it is not a valid bool.
+func GetBool(key string) bool {
+	b, err := strconv.ParseBool(GetValueFor(key))
+	return err == nil && b
+}
+
+// GetDuration returns the value of a duration key, or 0 if it is not a valid duration.
+func GetDuration(key string) time.Duration {
+	d, err := time.ParseDuration(GetValueFor(key))
+	if err != nil {
+		return 0
+	}
+	return d
+}
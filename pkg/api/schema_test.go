@@ -0,0 +1,85 @@
+package api
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSchemaKeysAreUnique(t *testing.T) {
+	seen := map[string]bool{}
+	for _, k := range Schema {
+		if seen[k.Name] {
+			t.Errorf("duplicate key %s", k.Name)
+		}
+		seen[k.Name] = true
+		if k.Description == "" {
+			t.Errorf("key %s has no description", k.Name)
+		}
+		if k.Type == TypeEnum && len(k.Values) == 0 {
+			t.Errorf("enum %s has no values", k.Name)
+		}
+	}
+}
+
+func TestKeyValidate(t *testing.T) {
+	tests := []struct {
+		key     string
+		value   string
+		wantErr bool
+	}{
+		{TufURL, "", false},
+		{TufURL, "https://tuf.example.com", false},
+		{TufURL, "tuf.example.com", true},
+		{TufURL, "://", true},
+		{HeadlessUI, "false", false},
+		{HeadlessUI, "yes", true},
+		{CliStrategy, "openshift", false},
+		{CliStrategy, "download", true},
+		{OidcUser, "anything", false},
+	}
+	for _, tt := range tests {
+		k, ok := Lookup(tt.key)
+		if !ok {
+			t.Fatalf("key %s not in schema", tt.key)
+		}
+		if err := k.Validate(tt.value); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%s=%q) = %v, want error %v", tt.key, tt.value, err, tt.wantErr)
+		}
+	}
+
+	if err := (Key{Name: "TIMEOUT", Type: TypeDuration}).Validate("5x"); err == nil {
+		t.Error("expected invalid duration")
+	}
+}
+
+func TestKeyHelp(t *testing.T) {
+	k, _ := Lookup(CliStrategy)
+	help := k.Help()
+	for _, want := range []string{"CLI_STRATEGY (enum)", "cli_server", "Export it as environment variable CLI_STRATEGY"} {
+		if !strings.Contains(help, want) {
+			t.Errorf("expected %q in %q", want, help)
+		}
+	}
+}
+
+func TestRequiredAndSecretKeys(t *testing.T) {
+	if !slices.Equal(RequiredKeys(), []string{TufURL}) {
+		t.Errorf("RequiredKeys() = %v", RequiredKeys())
+	}
+	for _, k := range []string{OidcPassword, OidcToken, GithubToken, DockerRegistryPassword} {
+		if !slices.Contains(SecretKeys(), k) {
+			t.Errorf("expected %s to be secret", k)
+		}
+	}
+}
+
+func TestGetBool(t *testing.T) {
+	withValues(t)
+	for value, want := range map[string]bool{"true": true, "1": true, "false": false, "yes": false, "": false} {
+		t.Setenv(HeadlessUI, value)
+		if got := GetBool(HeadlessUI); got != want {
+			t.Errorf("GetBool(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Schemes of secret references, e.g. OIDC_PASSWORD=file:///run/secrets/oidc.
+const (
+	SecretSchemeFile = "file"
+	SecretSchemeEnv  = "env"
+	// SecretSchemeK8s references a key of a Kubernetes secret as
+	// k8s://namespace/secret#key. Its source is registered by pkg/kubernetes.
+	SecretSchemeK8s = "k8s"
+)
+
+var secretSchemes = []string{SecretSchemeFile, SecretSchemeEnv, SecretSchemeK8s}
+
+// SecretSource resolves a secret reference to the secret value.
+type SecretSource func(ctx context.Context, ref *url.URL) (string, error)
+
+// SecretTimeout bounds the resolution of a single secret reference.
+var SecretTimeout = 30 * time.Second
+
+var (
+	secretsMu     sync.Mutex
+	secretSources = map[string]SecretSource{
+		SecretSchemeFile: fileSecret,
+		SecretSchemeEnv:  envSecret,
+	}
+	// resolvedSecrets caches the value of every resolved reference, so a
+	// secret is read once per run. Failures are not cached.
+	resolvedSecrets = map[string]string{}
+)
+
+// RegisterSecretSource makes references with scheme resolvable.
+func RegisterSecretSource(scheme string, source SecretSource) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	secretSources[scheme] = source
+}
+
+// IsSecretReference reports whether value references a secret instead of
+// holding it.
+func IsSecretReference(value string) bool {
+	scheme, _, found := strings.Cut(value, "://")
+	return found && slices.Contains(secretSchemes, scheme)
+}
+
+// resolvesReferences reports whether references in the value of key are
+// resolved. URL keys are taken as they are, a file:// URL is a valid mirror.
+func resolvesReferences(key string) bool {
+	k, ok := Lookup(schemaKey(key))
+	return !ok || k.Type != TypeURL
+}
+
+// ResolveValueFor returns the value of key with a secret reference replaced by
+// the secret it points to.
+func ResolveValueFor(key string) (string, error) {
+	return resolveValueFor(context.Background(), key)
+}
+
+// ResolveSecrets resolves the secret references of every configured key, so
+// that the configuration checks report unresolvable ones before they are
+// used. Each reference is resolved within ctx and SecretTimeout.
+func ResolveSecrets(ctx context.Context) error {
+	settings := Settings()
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var errs []error
+	for _, key := range keys {
+		if _, err := resolveValueFor(ctx, key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func resolveValueFor(ctx context.Context, key string) (string, error) {
+	value := Values.GetString(key)
+	if !resolvesReferences(key) || !IsSecretReference(value) {
+		return value, nil
+	}
+	secret, err := resolveSecret(ctx, value)
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve %s: %w", strings.ToUpper(key), err)
+	}
+	return secret, nil
+}
+
+// resolveSecret returns the cached value of ref or reads it from its source.
+// The source is called without holding secretsMu, as it may be slow.
+func resolveSecret(ctx context.Context, ref string) (string, error) {
+	secretsMu.Lock()
+	value, ok := resolvedSecrets[ref]
+	secretsMu.Unlock()
+	if ok {
+		return value, nil
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		// do not echo the reference, a mistyped one may contain the secret itself
+		return "", fmt.Errorf("invalid %s reference", strings.SplitN(ref, ":", 2)[0])
+	}
+	secretsMu.Lock()
+	source, ok := secretSources[u.Scheme]
+	secretsMu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("no source for %s:// references is registered", u.Scheme)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, SecretTimeout)
+	defer cancel()
+	if value, err = source(ctx, u); err != nil {
+		return "", err
+	}
+	secretsMu.Lock()
+	resolvedSecrets[ref] = value
+	secretsMu.Unlock()
+	return value, nil
+}
+
+// ResolvedSecrets returns the values of the references resolved so far.
+func ResolvedSecrets() []string {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	var values []string
+	for _, value := range resolvedSecrets {
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func fileSecret(_ context.Context, ref *url.URL) (string, error) {
+	data, err := os.ReadFile(ref.Path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func envSecret(_ context.Context, ref *url.URL) (string, error) {
+	value, ok := os.LookupEnv(ref.Host)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", ref.Host)
+	}
+	return value, nil
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func withSecretCache(t *testing.T) {
+	t.Helper()
+	withValues(t)
+	secretsMu.Lock()
+	old := resolvedSecrets
+	resolvedSecrets = map[string]string{}
+	secretsMu.Unlock()
+	t.Cleanup(func() {
+		secretsMu.Lock()
+		resolvedSecrets = old
+		secretsMu.Unlock()
+	})
+}
+
+func TestResolveValueFor(t *testing.T) {
+	withSecretCache(t)
+	path := filepath.Join(t.TempDir(), "oidc")
+	if err := os.WriteFile(path, []byte("s3cr3t-password\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OTHER_TOKEN", "other-token")
+
+	tests := []struct {
+		key     string
+		value   string
+		want    string
+		wantErr string
+	}{
+		{OidcPassword, "plain", "plain", ""},
+		{OidcPassword, "file://" + path, "s3cr3t-password", ""},
+		{GithubToken, "env://OTHER_TOKEN", "other-token", ""},
+		{GithubToken, "env://MISSING_TOKEN", "", "MISSING_TOKEN is not set"},
+		{DockerRegistryPassword, "file://" + path + ".missing", "", "no such file"},
+		// URLs are not references
+		{TufURL, "file:///srv/tuf", "file:///srv/tuf", ""},
+		{OidcPassword, "https://example.com", "https://example.com", ""},
+	}
+	for _, tt := range tests {
+		t.Setenv(tt.key, tt.value)
+		got, err := ResolveValueFor(tt.key)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), tt.key) {
+				t.Errorf("ResolveValueFor(%s=%s) error = %v, want %q", tt.key, tt.value, err, tt.wantErr)
+			}
+			if GetValueFor(tt.key) != "" {
+				t.Errorf("GetValueFor(%s) is not empty for an unresolvable reference", tt.key)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ResolveValueFor(%s=%s) = %q, %v, want %q", tt.key, tt.value, got, err, tt.want)
+		}
+	}
+
+	if secrets := ResolvedSecrets(); !slices.Contains(secrets, "s3cr3t-password") || !slices.Contains(secrets, "other-token") {
+		t.Errorf("ResolvedSecrets() = %v", secrets)
+	}
+	if got := Settings()[OidcPassword]; got != "https://example.com" {
+		t.Errorf("Settings()[%s] = %q", OidcPassword, got)
+	}
+}
+
+func TestResolveValueForCaches(t *testing.T) {
+	withSecretCache(t)
+	calls := 0
+	RegisterSecretSource("test", func(_ context.Context, ref *url.URL) (string, error) {
+		calls++
+		if ref.Host == "flaky" && calls == 1 {
+			return "", errors.New("temporarily unavailable")
+		}
+		return ref.Host + "-value", nil
+	})
+	t.Cleanup(func() {
+		secretsMu.Lock()
+		delete(secretSources, "test")
+		secretsMu.Unlock()
+	})
+	secretSchemes = append(secretSchemes, "test")
+	t.Cleanup(func() { secretSchemes = secretSchemes[:len(secretSchemes)-1] })
+
+	t.Setenv(OidcPassword, "test://oidc")
+	for range 3 {
+		if got := GetValueFor(OidcPassword); got != "oidc-value" {
+			t.Fatalf("GetValueFor() = %q", got)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("source called %d times, want 1", calls)
+	}
+
+	// failures are retried on next use
+	calls = 0
+	t.Setenv(GithubToken, "test://flaky")
+	if _, err := ResolveValueFor(GithubToken); err == nil {
+		t.Fatal("expected the first resolution to fail")
+	}
+	if got := GetValueFor(GithubToken); got != "flaky-value" || calls != 2 {
+		t.Errorf("GetValueFor() = %q after %d calls, want flaky-value after 2", got, calls)
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	withSecretCache(t)
+	var deadline bool
+	RegisterSecretSource("test", func(ctx context.Context, ref *url.URL) (string, error) {
+		_, deadline = ctx.Deadline()
+		if ref.Host == "missing" {
+			return "", errors.New("not found")
+		}
+		return ref.Host + "-value", nil
+	})
+	t.Cleanup(func() {
+		secretsMu.Lock()
+		delete(secretSources, "test")
+		secretsMu.Unlock()
+	})
+	secretSchemes = append(secretSchemes, "test")
+	t.Cleanup(func() { secretSchemes = secretSchemes[:len(secretSchemes)-1] })
+
+	t.Setenv(OidcPassword, "test://oidc")
+	if err := ResolveSecrets(t.Context()); err != nil {
+		t.Fatalf("ResolveSecrets() = %v", err)
+	}
+	if !deadline {
+		t.Error("expected the source to be called with a deadline")
+	}
+	if !slices.Contains(ResolvedSecrets(), "oidc-value") {
+		t.Errorf("ResolvedSecrets() = %v", ResolvedSecrets())
+	}
+
+	t.Setenv(GithubToken, "test://missing")
+	if err := ResolveSecrets(t.Context()); err == nil || !strings.Contains(err.Error(), "cannot resolve "+GithubToken+": not found") {
+		t.Errorf("ResolveSecrets() = %v", err)
+	}
+}
+
+func TestValidateReportsUnresolvedSecrets(t *testing.T) {
+	withSecretCache(t)
+	t.Setenv(OidcToken, "k8s://tas/oidc#token")
+	// pkg/kubernetes is not imported, so no k8s source is registered
+	err := Validate()
+	if err == nil || !strings.Contains(err.Error(), "cannot resolve "+OidcToken) {
+		t.Fatalf("Validate() = %v", err)
+	}
+	if strings.Contains(err.Error(), "tas/oidc") {
+		t.Errorf("error should not echo the reference: %v", err)
+	}
+}
@@ -1,34 +1,55 @@
 package api
 
-import "github.com/spf13/viper"
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
 
 const (
-	FulcioURL        = "FULCIO_URL"
-	RekorURL         = "REKOR_URL"
-	RekorUIURL       = "SIGSTORE_REKOR_UI_URL"
-	TufURL           = "TUF_URL"
-	OidcIssuerURL    = "OIDC_ISSUER_URL"
-	OidcToken        = "OIDC_TOKEN"
-	OidcUser         = "OIDC_USER"
-	OidcPassword     = "OIDC_PASSWORD"
-	OidcUserDomain   = "OIDC_USER_DOMAIN"
-	OidcRealm        = "KEYCLOAK_REALM"
-	GithubToken      = "TEST_GITHUB_TOKEN" // #nosec G101: Potential hardcoded credentials (gosec)
-	GithubUsername   = "TEST_GITHUB_USER"
-	GithubOwner      = "TEST_GITHUB_OWNER"
-	GithubRepo       = "TEST_GITHUB_REPO"
-	CliStrategy      = "CLI_STRATEGY"
-	CLIServerURL     = "CLI_SERVER_URL"
-	CGWURL           = "CGW_URL"
-	ManualImageSetup = "MANUAL_IMAGE_SETUP"
-	TargetImageName  = "TARGET_IMAGE_NAME"
-	CosignImage      = "COSIGN_IMAGE"
-	RegistryImage    = "REGISTRY_IMAGE"
-	TsaURL           = "TSA_URL"
-	HeadlessUI       = "HEADLESS_UI"
-	TestFirefox      = "TEST_FIREFOX"
-	TestSafari       = "TEST_SAFARI"
-	TestEdge         = "TEST_EDGE"
+	FulcioURL         = "FULCIO_URL"
+	RekorURL          = "REKOR_URL"
+	RekorUIURL        = "SIGSTORE_REKOR_UI_URL"
+	RekorPublicKey    = "REKOR_PUBLIC_KEY"
+	TufURL            = "TUF_URL"
+	OidcIssuerURL     = "OIDC_ISSUER_URL"
+	OidcToken         = "OIDC_TOKEN"
+	OidcUser          = "OIDC_USER"
+	OidcPassword      = "OIDC_PASSWORD"
+	OidcUserDomain    = "OIDC_USER_DOMAIN"
+	OidcRealm         = "KEYCLOAK_REALM"
+	OidcClientID      = "OIDC_CLIENT_ID"
+	OidcClientSecret  = "OIDC_CLIENT_SECRET" // #nosec G101: Potential hardcoded credentials (gosec)
+	OidcGrantType     = "OIDC_GRANT_TYPE"
+	OidcRefreshToken  = "OIDC_REFRESH_TOKEN" // #nosec G101: Potential hardcoded credentials (gosec)
+	GithubToken       = "TEST_GITHUB_TOKEN"  // #nosec G101: Potential hardcoded credentials (gosec)
+	GithubUsername    = "TEST_GITHUB_USER"
+	GithubOwner       = "TEST_GITHUB_OWNER"
+	GithubRepo        = "TEST_GITHUB_REPO"
+	CliStrategy       = "CLI_STRATEGY"
+	CLIServerURL      = "CLI_SERVER_URL"
+	CGWURL            = "CGW_URL"
+	ManualImageSetup  = "MANUAL_IMAGE_SETUP"
+	TargetImageName   = "TARGET_IMAGE_NAME"
+	CosignImage       = "COSIGN_IMAGE"
+	RegistryImage     = "REGISTRY_IMAGE"
+	TsaURL            = "TSA_URL"
+	HeadlessUI        = "HEADLESS_UI"
+	TestFirefox       = "TEST_FIREFOX"
+	TestSafari        = "TEST_SAFARI"
+	TestEdge          = "TEST_EDGE"
+	TranscriptDir     = "TRANSCRIPT_DIR"
+	UsageThresholds   = "USAGE_THRESHOLDS"
+	ReplayDir         = "REPLAY_DIR"
+	ReplayCLI         = "REPLAY_CLI"
+	BuildExpectations = "BUILD_EXPECTATIONS"
+	ReplayStrategy    = "REPLAY_STRATEGY"
+	Discovery         = "E2E_DISCOVERY"
+	TufDiscovery      = "TUF_DISCOVERY"
+	TufRootJSON       = "TUF_ROOT_JSON"
+	LocalStack        = "LOCAL_STACK"
+	LocalStackImages  = "LOCAL_STACK_IMAGES"
+	Preflight         = "E2E_PREFLIGHT"
 
 	ContainerImage = "CONTAINER_IMAGE"
 	ContainerPath  = "CONTAINER_PATH"
@@ -47,23 +68,43 @@ func init() {
 	Values = viper.New()
 
 	Values.SetDefault(OidcRealm, "trusted-artifact-signer")
+	Values.SetDefault(OidcClientID, "trusted-artifact-signer")
 	Values.SetDefault(OidcUser, "jdoe")
 	Values.SetDefault(OidcPassword, "secure")
 	Values.SetDefault(OidcUserDomain, "redhat.com")
+	Values.SetDefault(OidcGrantType, "password")
 	Values.SetDefault(GithubUsername, "ignore")
 	Values.SetDefault(GithubOwner, "securesign")
 	Values.SetDefault(GithubRepo, "e2e-gitsign-test")
 	Values.SetDefault(CliStrategy, "local")
 	Values.SetDefault(HeadlessUI, "true")
 	Values.SetDefault(ManualImageSetup, "false")
+	Values.SetDefault(LocalStack, "false")
+	Values.SetDefault(Preflight, "true")
 	Values.SetDefault(CosignImage, "registry.redhat.io/rhtas/cosign-rhel9:1.0.2")
 	Values.SetDefault(TestFirefox, "true")
 	Values.SetDefault(TestSafari, "true")
 	Values.SetDefault(TestEdge, "true")
 	Values.SetDefault(RegistryImage, "registry:2.8.3")
+	Values.SetDefault(TranscriptDir, "transcripts")
+	Values.SetDefault(ReplayDir, "recordings")
+	Values.SetDefault(ReplayStrategy, "local")
+	Values.SetDefault(Discovery, "true")
+	Values.SetDefault(TufDiscovery, "false")
 	Values.AutomaticEnv()
+
+	if err := loadSelectedProfile(); err != nil {
+		profileErr = fmt.Errorf("cannot load configuration profile: %w", err)
+	}
 }
 
+// GetValueFor returns the value of key. Secret references such as
+// file:///run/secrets/oidc are resolved; a reference that cannot be resolved
+// yields an empty value and is reported by Validate.
 func GetValueFor(key string) string {
-	return Values.GetString(key)
+	value, err := ResolveValueFor(key)
+	if err != nil {
+		return ""
+	}
+	return value
 }
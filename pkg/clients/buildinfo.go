@@ -0,0 +1,240 @@
+package clients
+
+import (
+	"bufio"
+	"bytes"
+	"debug/buildinfo"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Crypto backends a Go binary can be built with.
+const (
+	CryptoGo           = "go"
+	CryptoBoring       = "boringcrypto"
+	CryptoOpenSSL      = "openssl"
+	CryptoFIPS140      = "fips140"
+	opensslBackendMark = "golang-fips/openssl"
+)
+
+// Module is a Go module compiled into a binary.
+type Module struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	Sum     string `json:"sum,omitempty"`
+}
+
+// BuildInfo is what a Go binary reports about how it was built.
+type BuildInfo struct {
+	Path        string `json:"path"`
+	GoVersion   string `json:"goVersion"`
+	Main        Module `json:"main"`
+	VCSRevision string `json:"vcsRevision,omitempty"`
+	VCSModified bool   `json:"vcsModified,omitempty"`
+	CGOEnabled  bool   `json:"cgoEnabled"`
+	Experiments string `json:"goExperiment,omitempty"`
+	Tags        string `json:"tags,omitempty"`
+	// CryptoBackend is one of CryptoGo, CryptoBoring, CryptoOpenSSL or CryptoFIPS140.
+	CryptoBackend string   `json:"cryptoBackend"`
+	Deps          []Module `json:"deps"`
+}
+
+// FIPSCapable reports whether the binary uses a crypto backend that can run in FIPS mode.
+func (b *BuildInfo) FIPSCapable() bool {
+	return b.CryptoBackend != CryptoGo
+}
+
+// ErrNotGoBinary is returned by ReadBuildInfo for binaries without Go build info.
+var ErrNotGoBinary = errors.New("not a Go binary")
+
+// ReadBuildInfo inspects the Go binary at path.
+func ReadBuildInfo(path string) (*BuildInfo, error) {
+	info, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", path, ErrNotGoBinary, err)
+	}
+	b := &BuildInfo{
+		Path:      path,
+		GoVersion: info.GoVersion,
+		Main:      Module{Path: info.Main.Path, Version: info.Main.Version, Sum: info.Main.Sum},
+	}
+	fips140 := ""
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			b.VCSRevision = s.Value
+		case "vcs.modified":
+			b.VCSModified = s.Value == "true"
+		case "CGO_ENABLED":
+			b.CGOEnabled = s.Value == "1"
+		case "GOEXPERIMENT":
+			b.Experiments = s.Value
+		case "-tags":
+			b.Tags = s.Value
+		case "GOFIPS140":
+			fips140 = s.Value
+		}
+	}
+	for _, dep := range info.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		b.Deps = append(b.Deps, Module{Path: dep.Path, Version: dep.Version, Sum: dep.Sum})
+	}
+
+	openssl, err := containsMark(path, opensslBackendMark)
+	if err != nil {
+		return nil, err
+	}
+	b.CryptoBackend = cryptoBackend(b, fips140, openssl)
+	return b, nil
+}
+
+func cryptoBackend(b *BuildInfo, fips140 string, openssl bool) string {
+	experiments := b.Experiments + "," + experimentsOf(b.GoVersion)
+	switch {
+	case openssl:
+		return CryptoOpenSSL
+	case strings.Contains(experiments, "boringcrypto"):
+		return CryptoBoring
+	case fips140 != "" && fips140 != "off":
+		return CryptoFIPS140
+	default:
+		return CryptoGo
+	}
+}
+
+var goVersionExperimentRegexp = regexp.MustCompile(`X:(\S+)`)
+
+// experimentsOf returns the experiments listed in a Go version such as
+// "go1.22.7 (Red Hat 1.22.7-1.el9) X:strictfipsruntime".
+func experimentsOf(goVersion string) string {
+	m := goVersionExperimentRegexp.FindStringSubmatch(goVersion)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// containsMark reports whether the file at path contains mark, without reading it at once.
+func containsMark(path string, mark string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close() //nolint:errcheck
+
+	const chunkSize = 1 << 20
+	reader := bufio.NewReaderSize(file, chunkSize)
+	buf := make([]byte, chunkSize+len(mark))
+	carry := 0
+	for {
+		n, err := io.ReadFull(reader, buf[carry:])
+		if bytes.Contains(buf[:carry+n], []byte(mark)) {
+			return true, nil
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		// keep the tail so a mark spanning two chunks is still found
+		carry = copy(buf, buf[carry+n-len(mark):carry+n])
+	}
+}
+
+// BuildExpectation is what the build of a tool must satisfy. Zero fields are not checked.
+type BuildExpectation struct {
+	FIPS         bool
+	CGO          bool
+	VCS          bool
+	MinGoVersion string
+}
+
+// Check returns an error describing every expectation b does not meet.
+func (e BuildExpectation) Check(b *BuildInfo) error {
+	var errs []error
+	if e.FIPS && !b.FIPSCapable() {
+		errs = append(errs, errors.New("not built with a FIPS capable crypto backend"))
+	}
+	if e.CGO && !b.CGOEnabled {
+		errs = append(errs, errors.New("not built with CGO_ENABLED=1"))
+	}
+	if e.VCS && b.VCSRevision == "" {
+		errs = append(errs, errors.New("no VCS revision recorded"))
+	}
+	if e.MinGoVersion != "" {
+		caps := &Capabilities{Version: normalizeGoVersion(b.GoVersion)}
+		if !caps.AtLeast(normalizeGoVersion(e.MinGoVersion)) {
+			errs = append(errs, fmt.Errorf("built with %s, expected go%s or later", b.GoVersion, strings.TrimPrefix(e.MinGoVersion, "go")))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ParseBuildExpectations parses expectations in the form
+//
+//	cosign:fips,go>=1.22;gitsign:fips,vcs
+//
+// keyed by tool. Supported terms are fips, cgo, vcs and go>=<version>.
+func ParseBuildExpectations(s string) (map[string]BuildExpectation, error) {
+	expectations := map[string]BuildExpectation{}
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		tool, terms, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("expectation %q: expected '<tool>:<terms>'", entry)
+		}
+		var e BuildExpectation
+		for _, term := range strings.Split(terms, ",") {
+			term = strings.TrimSpace(term)
+			switch {
+			case term == "fips":
+				e.FIPS = true
+			case term == "cgo":
+				e.CGO = true
+			case term == "vcs":
+				e.VCS = true
+			case strings.HasPrefix(term, "go>="):
+				e.MinGoVersion = strings.TrimPrefix(term, "go>=")
+				if _, ok := versionParts(normalizeGoVersion(e.MinGoVersion)); !ok {
+					return nil, fmt.Errorf("expectation %q: invalid Go version %q", entry, e.MinGoVersion)
+				}
+			default:
+				return nil, fmt.Errorf("expectation %q: unknown term %q (expected fips, cgo, vcs or go>=<version>)", entry, term)
+			}
+		}
+		expectations[strings.TrimSpace(tool)] = e
+	}
+	return expectations, nil
+}
+
+var goReleaseRegexp = regexp.MustCompile(`^(?:go)?(\d+\.\d+)(\.\d+)?`)
+
+// normalizeGoVersion turns "go1.22" or "1.22.7 X:boringcrypto" into a semantic version.
+func normalizeGoVersion(v string) string {
+	m := goReleaseRegexp.FindStringSubmatch(v)
+	if m == nil {
+		return ""
+	}
+	if m[2] == "" {
+		return m[1] + ".0"
+	}
+	return m[1] + m[2]
+}
+
+// BuildInfo inspects the installed binary. The client must be set up.
+func (c *cli) BuildInfo() (*BuildInfo, error) {
+	if c.pathToCLI == "" {
+		return nil, fmt.Errorf("%s is not set up", c.Name)
+	}
+	return ReadBuildInfo(c.pathToCLI)
+}
@@ -0,0 +1,127 @@
+package clients
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestReadBuildInfo(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(src, []byte("package main\n\nfunc main() {}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	bin := filepath.Join(dir, "tool")
+	cmd := exec.Command("go", "build", "-o", bin, src)
+	cmd.Env = append(os.Environ(), "CGO_ENABLED=0", "GOFLAGS=")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build: %v\n%s", err, out)
+	}
+
+	info, err := ReadBuildInfo(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.GoVersion != runtime.Version() {
+		t.Errorf("GoVersion = %q, want %q", info.GoVersion, runtime.Version())
+	}
+	if info.CGOEnabled {
+		t.Error("expected CGO to be disabled")
+	}
+	if info.CryptoBackend == CryptoOpenSSL {
+		t.Errorf("unexpected crypto backend %s", info.CryptoBackend)
+	}
+}
+
+func TestReadBuildInfoNotGo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tool")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadBuildInfo(path); !errors.Is(err, ErrNotGoBinary) {
+		t.Fatalf("expected ErrNotGoBinary, got %v", err)
+	}
+}
+
+func TestCryptoBackend(t *testing.T) {
+	tests := []struct {
+		name    string
+		info    BuildInfo
+		fips140 string
+		openssl bool
+		want    string
+	}{
+		{"upstream", BuildInfo{GoVersion: "go1.22.7"}, "", false, CryptoGo},
+		{"boringcrypto setting", BuildInfo{GoVersion: "go1.22.7", Experiments: "boringcrypto"}, "", false, CryptoBoring},
+		{"boringcrypto version", BuildInfo{GoVersion: "go1.22.7 X:boringcrypto"}, "", false, CryptoBoring},
+		{"openssl", BuildInfo{GoVersion: "go1.22.7 (Red Hat 1.22.7-1.el9) X:strictfipsruntime"}, "", true, CryptoOpenSSL},
+		{"fips140", BuildInfo{GoVersion: "go1.24.1"}, "v1.0.0", false, CryptoFIPS140},
+		{"fips140 off", BuildInfo{GoVersion: "go1.24.1"}, "off", false, CryptoGo},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cryptoBackend(&tt.info, tt.fips140, tt.openssl); got != tt.want {
+				t.Errorf("cryptoBackend() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainsMarkAcrossChunks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bin")
+	data := make([]byte, 1<<20+64)
+	copy(data[1<<20-5:], "golang-fips/openssl")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	found, err := containsMark(path, "golang-fips/openssl")
+	if err != nil || !found {
+		t.Fatalf("containsMark() = %v, %v; want true", found, err)
+	}
+	found, err = containsMark(path, "boringssl")
+	if err != nil || found {
+		t.Fatalf("containsMark() = %v, %v; want false", found, err)
+	}
+}
+
+func TestParseBuildExpectations(t *testing.T) {
+	expectations, err := ParseBuildExpectations("cosign:fips,go>=1.22; gitsign:vcs,cgo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := expectations["cosign"]; !e.FIPS || e.MinGoVersion != "1.22" {
+		t.Errorf("unexpected cosign expectation: %+v", e)
+	}
+	if e := expectations["gitsign"]; !e.VCS || !e.CGO || e.FIPS {
+		t.Errorf("unexpected gitsign expectation: %+v", e)
+	}
+
+	for _, invalid := range []string{"cosign", "cosign:fast", "cosign:go>=latest"} {
+		if _, err := ParseBuildExpectations(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+func TestBuildExpectationCheck(t *testing.T) {
+	info := &BuildInfo{GoVersion: "go1.21.13", CryptoBackend: CryptoGo}
+	err := BuildExpectation{FIPS: true, VCS: true, MinGoVersion: "1.22"}.Check(info)
+	if err == nil {
+		t.Fatal("expected expectations to fail")
+	}
+	for _, want := range []string{"FIPS", "VCS revision", "expected go1.22 or later"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+
+	info = &BuildInfo{GoVersion: "go1.22.7 (Red Hat 1.22.7-1.el9) X:strictfipsruntime", CryptoBackend: CryptoOpenSSL, VCSRevision: "abc"}
+	if err := (BuildExpectation{FIPS: true, VCS: true, MinGoVersion: "1.22"}).Check(info); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,284 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Capability is a feature a CLI binary may or may not provide. It is provided
+// when the binary is at least MinVersion and Subcommand accepts Flag, with
+// Default as its default when set; empty fields are not checked.
+type Capability struct {
+	Name       string
+	Subcommand string
+	Flag       string
+	Default    string
+	MinVersion string
+}
+
+func (c Capability) String() string {
+	return c.Name
+}
+
+// For returns the capability checked against subcommand instead, for flags
+// that several subcommands share (e.g. verify and verify-blob).
+func (c Capability) For(subcommand string) Capability {
+	c.Subcommand = subcommand
+	return c
+}
+
+var (
+	CosignNewBundleFormat  = Capability{Name: "new bundle format", Subcommand: "sign-blob", Flag: "--new-bundle-format"}
+	CosignSignedTimestamps = Capability{Name: "signed timestamps", Subcommand: "verify", Flag: "--use-signed-timestamps"}
+	CosignTrustedRoot      = Capability{Name: "trusted root", Subcommand: "verify", Flag: "--trusted-root"}
+	CosignReferrersMode    = Capability{Name: "OCI referrers mode", Subcommand: "sign", Flag: "--registry-referrers-mode"}
+
+	// CosignOCIReferrersDefault is probed through the default of 'sign
+	// --new-bundle-format': since cosign v3.0.0 it is true, and sign then stores
+	// the signature as a Sigstore bundle attached as an OCI 1.1 referrer.
+	CosignOCIReferrersDefault = Capability{Name: "OCI referrers by default", Subcommand: "sign", Flag: "--new-bundle-format", Default: "true"}
+)
+
+var (
+	versionRegexp  = regexp.MustCompile(`\bv?(\d+)\.(\d+)\.(\d+)([-+][0-9A-Za-z.+-]*)?`)
+	helpFlagRegexp = regexp.MustCompile(`(?m)^\s+(?:-\w, )?(--[a-z0-9][a-z0-9-]*)`)
+	// helpDefaultRegexp matches a flag listed with its default, either as
+	// "--flag=value:" (cosign) or as "--flag type  usage (default value)" (cobra).
+	helpDefaultRegexp = regexp.MustCompile(`(?m)^\s+(?:-\w, )?(--[a-z0-9][a-z0-9-]*)(?:=(.*):|\s.*\(default (.*)\))\s*$`)
+)
+
+// MissingCapabilityError is returned by Capabilities.Check when the binary was
+// probed and does not provide the capability.
+type MissingCapabilityError struct {
+	Capability Capability
+	Reason     string
+}
+
+func (e *MissingCapabilityError) Error() string {
+	return fmt.Sprintf("%s requires %s", e.Capability, e.Reason)
+}
+
+// Capabilities describes what a probed CLI binary supports. Help output is
+// probed lazily, once per subcommand.
+type Capabilities struct {
+	// Version is the semantic version reported by the binary, or empty when it could not be parsed.
+	Version string
+	// VersionErr is set when the version command could not be run or printed no version.
+	VersionErr error
+
+	mu     sync.Mutex
+	help   func(subcommand string) ([]byte, error)
+	probed map[string]*helpOutput
+}
+
+// helpOutput is what the help of a subcommand lists, or why it could not be obtained.
+type helpOutput struct {
+	flags    map[string]bool
+	defaults map[string]string
+	err      error
+}
+
+// ParseVersion returns the first semantic version in output, without the "v" prefix.
+func ParseVersion(output []byte) string {
+	m := versionRegexp.FindSubmatch(output)
+	if m == nil {
+		return ""
+	}
+	return strings.TrimPrefix(string(m[0]), "v")
+}
+
+// ParseHelpFlags returns the long flags listed in the output of '<subcommand> --help'.
+func ParseHelpFlags(output []byte) map[string]bool {
+	flags := map[string]bool{}
+	for _, m := range helpFlagRegexp.FindAllSubmatch(output, -1) {
+		flags[string(m[1])] = true
+	}
+	return flags
+}
+
+// ParseHelpDefaults returns the defaults of the long flags listed in the
+// output of '<subcommand> --help', unquoted. Flags listed without a default
+// (e.g. boolean flags that are off in cobra's help) are left out.
+func ParseHelpDefaults(output []byte) map[string]string {
+	defaults := map[string]string{}
+	for _, m := range helpDefaultRegexp.FindAllSubmatch(output, -1) {
+		value := string(m[2])
+		if m[3] != nil {
+			value = string(m[3])
+		}
+		defaults[string(m[1])] = strings.Trim(value, `'"`)
+	}
+	return defaults
+}
+
+// HasFlag reports whether subcommand accepts flag (e.g. "--bundle"). It is
+// false when the help of subcommand could not be probed.
+func (c *Capabilities) HasFlag(subcommand string, flag string) bool {
+	flags, err := c.helpFlags(subcommand)
+	return err == nil && flags[flag]
+}
+
+// FlagDefault returns the default value of flag as listed in the help of
+// subcommand. ok is false when the help could not be probed or lists no
+// default for flag.
+func (c *Capabilities) FlagDefault(subcommand string, flag string) (value string, ok bool) {
+	probed := c.probe(subcommand)
+	if probed.err != nil {
+		return "", false
+	}
+	value, ok = probed.defaults[flag]
+	return value, ok
+}
+
+// helpFlags returns the flags of subcommand, or an error when its help output
+// could not be obtained or lists no flags.
+func (c *Capabilities) helpFlags(subcommand string) (map[string]bool, error) {
+	probed := c.probe(subcommand)
+	return probed.flags, probed.err
+}
+
+// probe parses the help of subcommand the first time it is queried.
+func (c *Capabilities) probe(subcommand string) *helpOutput {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if probed, ok := c.probed[subcommand]; ok {
+		return probed
+	}
+	if c.probed == nil {
+		c.probed = map[string]*helpOutput{}
+	}
+	probed := &helpOutput{flags: map[string]bool{}, defaults: map[string]string{}}
+	if c.help == nil {
+		probed.err = errors.New("no help output")
+	} else if output, err := c.help(subcommand); err != nil {
+		probed.err = err
+	} else {
+		probed.flags = ParseHelpFlags(output)
+		probed.defaults = ParseHelpDefaults(output)
+		if len(probed.flags) == 0 {
+			probed.err = fmt.Errorf("no flags in the output of '%s --help'", subcommand)
+		}
+	}
+	c.probed[subcommand] = probed
+	return probed
+}
+
+// AtLeast reports whether the binary version is at least version. An unknown
+// version is treated as older than any version.
+func (c *Capabilities) AtLeast(version string) bool {
+	have, ok := versionParts(c.Version)
+	if !ok {
+		return false
+	}
+	want, ok := versionParts(version)
+	if !ok {
+		return false
+	}
+	for i := range have {
+		if have[i] != want[i] {
+			return have[i] > want[i]
+		}
+	}
+	return true
+}
+
+// Check returns nil when capability is provided, a *MissingCapabilityError
+// when the binary does not provide it, or another error when the binary could
+// not be probed for it.
+func (c *Capabilities) Check(capability Capability) error {
+	if capability.MinVersion != "" {
+		if c.VersionErr != nil {
+			return fmt.Errorf("cannot check %s: %w", capability, c.VersionErr)
+		}
+		if !c.AtLeast(capability.MinVersion) {
+			version := c.Version
+			if version == "" {
+				version = "unknown version"
+			}
+			return &MissingCapabilityError{Capability: capability, Reason: fmt.Sprintf("version %s or later, found %s", capability.MinVersion, version)}
+		}
+	}
+	if capability.Flag != "" {
+		flags, err := c.helpFlags(capability.Subcommand)
+		if err != nil {
+			return fmt.Errorf("cannot check %s: %w", capability, err)
+		}
+		if !flags[capability.Flag] {
+			return &MissingCapabilityError{Capability: capability, Reason: fmt.Sprintf("'%s %s'", capability.Subcommand, capability.Flag)}
+		}
+		if capability.Default != "" {
+			value, ok := c.FlagDefault(capability.Subcommand, capability.Flag)
+			if !ok {
+				value = "none"
+			}
+			if value != capability.Default {
+				return &MissingCapabilityError{Capability: capability, Reason: fmt.Sprintf("'%s %s' to default to %s, found %s",
+					capability.Subcommand, capability.Flag, capability.Default, value)}
+			}
+		}
+	}
+	return nil
+}
+
+// Supports reports whether all capabilities are provided.
+func (c *Capabilities) Supports(capabilities ...Capability) bool {
+	for _, capability := range capabilities {
+		if c.Check(capability) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func versionParts(version string) ([3]int, bool) {
+	var parts [3]int
+	m := versionRegexp.FindStringSubmatch(version)
+	if m == nil {
+		return parts, false
+	}
+	for i := range parts {
+		parts[i], _ = strconv.Atoi(m[i+1])
+	}
+	return parts, true
+}
+
+// Capabilities probes the binary the first time it is called: its version
+// now and the help of each subcommand when it is first queried. The client
+// must be set up.
+func (c *cli) Capabilities(ctx context.Context) *Capabilities {
+	c.capabilitiesOnce.Do(func() {
+		caps := &Capabilities{
+			help: func(subcommand string) ([]byte, error) {
+				args := append(strings.Fields(subcommand), "--help")
+				return probeOutput(c.Command(ctx, args...))
+			},
+		}
+		if c.versionCommand == "" {
+			caps.VersionErr = fmt.Errorf("%s has no version command", c.Name)
+		} else if output, err := probeOutput(c.Command(ctx, c.versionCommand)); err != nil {
+			caps.VersionErr = err
+		} else if caps.Version = ParseVersion(output); caps.Version == "" {
+			caps.VersionErr = fmt.Errorf("no version in the output of '%s %s'", c.Name, c.versionCommand)
+		}
+		c.capabilities = caps
+	})
+	return c.capabilities
+}
+
+// probeOutput runs cmd and returns its combined output. Some tools exit
+// non-zero after printing help, so only a command that could not run at all
+// is an error.
+func probeOutput(cmd *Cmd) ([]byte, error) {
+	output, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, err
+	}
+	return output, nil
+}
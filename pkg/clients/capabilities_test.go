@@ -0,0 +1,197 @@
+package clients
+
+import (
+	"errors"
+	"maps"
+	"strings"
+	"testing"
+)
+
+const cosignVersionOutput = `  ______   ______        _______. __    _______ .__   __.
+ /      | /  __  \      /       ||  |  /  _____||  \ |  |
+cosign: A tool for Container Signing, Verification and Storage in an OCI registry.
+
+GitVersion:    v2.4.1
+GitCommit:     9a4cfe1aae777984c07ce373d97a65428bbff734
+GoVersion:     go1.22.7
+`
+
+const cosignVerifyHelp = `Verify a signature on the supplied container image
+
+Flags:
+      --allow-http-registry                  whether to allow using HTTP protocol while connecting to registries.
+      --certificate-identity string          The identity expected in a valid Fulcio certificate.
+  -h, --help                                 help for verify
+      --use-signed-timestamps                use signed timestamps if available
+`
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{cosignVersionOutput, "2.4.1"},
+		{"gitsign version v0.10.1\n", "0.10.1"},
+		{"tuftool 0.12.0\n", "0.12.0"},
+		{"GitVersion: v3.0.0-rc.1\n", "3.0.0-rc.1"},
+		{"devel\n", ""},
+	}
+	for _, tt := range tests {
+		if got := ParseVersion([]byte(tt.output)); got != tt.want {
+			t.Errorf("ParseVersion(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestParseHelpFlags(t *testing.T) {
+	flags := ParseHelpFlags([]byte(cosignVerifyHelp))
+	for _, flag := range []string{"--allow-http-registry", "--certificate-identity", "--help", "--use-signed-timestamps"} {
+		if !flags[flag] {
+			t.Errorf("expected %s in help flags %v", flag, flags)
+		}
+	}
+	if len(flags) != 4 {
+		t.Errorf("expected 4 flags, got %v", flags)
+	}
+}
+
+func TestCapabilitiesCheck(t *testing.T) {
+	probes := map[string]int{}
+	caps := &Capabilities{
+		Version: "2.4.1",
+		help: func(subcommand string) ([]byte, error) {
+			probes[subcommand]++
+			switch subcommand {
+			case "verify":
+				return []byte(cosignVerifyHelp), nil
+			case "sign":
+				return []byte("Options:\n    --new-bundle-format=false:\n\texpect the signature in a Sigstore bundle\n"), nil
+			}
+			return []byte("Flags:\n  -h, --help   help for " + subcommand + "\n"), nil
+		},
+	}
+
+	tests := []struct {
+		capability Capability
+		wantErr    string
+	}{
+		{CosignSignedTimestamps, ""},
+		{CosignTrustedRoot, "requires 'verify --trusted-root'"},
+		{CosignNewBundleFormat, "requires 'sign-blob --new-bundle-format'"},
+		{CosignOCIReferrersDefault, "requires 'sign --new-bundle-format' to default to true, found false"},
+		{Capability{Name: "new", MinVersion: "3.0.0"}, "requires version 3.0.0 or later, found 2.4.1"},
+		{Capability{Name: "old", MinVersion: "2.4.0"}, ""},
+		{Capability{Name: "same", MinVersion: "v2.4.1"}, ""},
+	}
+	for _, tt := range tests {
+		err := caps.Check(tt.capability)
+		var missing *MissingCapabilityError
+		if err != nil && !errors.As(err, &missing) {
+			t.Errorf("Check(%s) = %v, want a *MissingCapabilityError", tt.capability, err)
+		}
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("Check(%s) unexpected error: %v", tt.capability, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("Check(%s) = %v, want error containing %q", tt.capability, err, tt.wantErr)
+		}
+	}
+
+	if caps.Supports(CosignSignedTimestamps, CosignTrustedRoot) {
+		t.Error("expected Supports to fail when one capability is missing")
+	}
+	if probes["verify"] != 1 {
+		t.Errorf("expected help of verify to be probed once, got %d", probes["verify"])
+	}
+}
+
+func TestCapabilitiesUnknownVersion(t *testing.T) {
+	caps := &Capabilities{}
+	if caps.AtLeast("0.0.1") {
+		t.Error("expected an unknown version to be older than any version")
+	}
+	if err := caps.Check(Capability{Name: "new", MinVersion: "3.0.0"}); err == nil || !strings.Contains(err.Error(), "unknown version") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCapabilitiesProbeFailure(t *testing.T) {
+	caps := &Capabilities{
+		VersionErr: errors.New(`exec: "cosign": executable file not found in $PATH`),
+		help: func(subcommand string) ([]byte, error) {
+			if subcommand == "sign-blob" {
+				return []byte("Error: unknown command\n"), nil
+			}
+			return nil, errors.New(`exec: "cosign": executable file not found in $PATH`)
+		},
+	}
+
+	tests := []struct {
+		capability Capability
+		wantErr    string
+	}{
+		{CosignOCIReferrersDefault, "executable file not found"},
+		{CosignSignedTimestamps, "executable file not found"},
+		{CosignNewBundleFormat, "no flags in the output of 'sign-blob --help'"},
+	}
+	for _, tt := range tests {
+		err := caps.Check(tt.capability)
+		var missing *MissingCapabilityError
+		if err == nil || errors.As(err, &missing) || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Check(%s) = %v, want a probe error containing %q", tt.capability, err, tt.wantErr)
+		}
+	}
+	if caps.HasFlag("verify", "--use-signed-timestamps") {
+		t.Error("expected HasFlag to be false when the help could not be probed")
+	}
+}
+
+func TestParseHelpDefaults(t *testing.T) {
+	cosignHelp := `Options:
+    --bundle='':
+	write everything required to verify the blob to a FILE or to stdout
+    --fulcio-url='https://fulcio.sigstore.dev':
+	address of sigstore PKI server
+    --new-bundle-format=false:
+	output bundle in new format that contains all verification material
+    -y, --yes=false:
+	skip confirmation prompts for non-destructive operations
+`
+	cobraHelp := `Flags:
+      --new-bundle-format                 expect a Sigstore bundle (default true)
+      --registry-referrers-mode string    mode for fetching references (default "legacy")
+      --use-signed-timestamps             verify rfc3161 timestamps
+`
+	tests := []struct {
+		help string
+		want map[string]string
+	}{
+		{cosignHelp, map[string]string{"--bundle": "", "--fulcio-url": "https://fulcio.sigstore.dev", "--new-bundle-format": "false", "--yes": "false"}},
+		{cobraHelp, map[string]string{"--new-bundle-format": "true", "--registry-referrers-mode": "legacy"}},
+	}
+	for _, tt := range tests {
+		if got := ParseHelpDefaults([]byte(tt.help)); !maps.Equal(got, tt.want) {
+			t.Errorf("ParseHelpDefaults() = %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func TestFlagDefault(t *testing.T) {
+	caps := &Capabilities{
+		help: func(subcommand string) ([]byte, error) {
+			if subcommand == "sign-blob" {
+				return []byte("Options:\n    --new-bundle-format=true:\n\toutput bundle in new format\n"), nil
+			}
+			return nil, errors.New("probe failed")
+		},
+	}
+	if value, ok := caps.FlagDefault("sign-blob", "--new-bundle-format"); !ok || value != "true" {
+		t.Errorf("FlagDefault() = %q, %v, want \"true\"", value, ok)
+	}
+	if _, ok := caps.FlagDefault("sign-blob", "--bundle"); ok {
+		t.Error("expected no default for an unlisted flag")
+	}
+	if _, ok := caps.FlagDefault("verify", "--new-bundle-format"); ok {
+		t.Error("expected no default when the help could not be probed")
+	}
+}
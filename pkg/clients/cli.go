@@ -2,9 +2,11 @@ package clients
 
 import (
 	"context"
-	"os/exec"
+	"strings"
+	"sync"
 
 	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/securesign/sigstore-e2e/pkg/redact"
 	"github.com/securesign/sigstore-e2e/pkg/strategy"
 	"github.com/sirupsen/logrus"
 )
@@ -14,6 +16,13 @@ type cli struct {
 	pathToCLI      string
 	setupStrategy  SetupStrategy
 	versionCommand string
+	env            *Environment
+	// retryPolicies maps a subcommand (e.g. "initialize" or "download signature")
+	// to the policy its failures are retried with.
+	retryPolicies map[string]*RetryPolicy
+
+	capabilitiesOnce sync.Once
+	capabilities     *Capabilities
 }
 
 type SetupStrategy = strategy.Strategy
@@ -28,35 +37,99 @@ func PreferredSetupStrategy() SetupStrategy {
 	return s
 }
 
-func (c *cli) Command(ctx context.Context, args ...string) *exec.Cmd {
-	cmd := exec.CommandContext(ctx, c.pathToCLI, args...) // #nosec G204 - we don't expect the code to be running on PROD ENV
-
-	cmd.Stdout = logrus.NewEntry(logrus.StandardLogger()).WithField("app", c.Name).WriterLevel(logrus.InfoLevel)
-	cmd.Stderr = logrus.NewEntry(logrus.StandardLogger()).WithField("app", c.Name).WriterLevel(logrus.ErrorLevel)
-
+// Command prepares the CLI with args. Its output is logged and the invocation
+// is reported to the observers registered with ObserveInvocations.
+func (c *cli) Command(ctx context.Context, args ...string) *Cmd {
+	cmd := command(ctx, c.Name, c.pathToCLI, args...)
+	cmd.Env = c.Env().Environ()
 	return cmd
 }
 
 func (c *cli) CommandOutput(ctx context.Context, args ...string) ([]byte, error) {
-	cmd := exec.CommandContext(ctx, c.pathToCLI, args...) // #nosec G204 - we don't expect the code to be running on PROD ENV
-	output, err := cmd.CombinedOutput()
+	output, err := c.Command(ctx, args...).CombinedOutput()
 	entry := logrus.WithField("app", c.Name)
 	if err != nil {
-		entry.Error(string(output))
+		entry.Error(redact.String(string(output)))
 		return nil, err
 	}
 
-	entry.Info(string(output))
+	entry.Info(redact.String(string(output)))
 
 	return output, err
 }
 
+// run executes the CLI and returns stdout and stderr separately. Both streams
+// are still logged; a failed invocation is returned as a *CommandError.
+func (c *cli) run(ctx context.Context, args ...string) ([]byte, []byte, error) {
+	return c.runIn(ctx, "", args...)
+}
+
+// runIn is like run, but executes the CLI in the working directory dir.
+// Transient failures are retried according to the policy of the subcommand.
+func (c *cli) runIn(ctx context.Context, dir string, args ...string) ([]byte, []byte, error) {
+	return c.runCmd(ctx, c.Name, args, func() *Cmd {
+		cmd := c.Command(ctx, args...)
+		cmd.Dir = dir
+		return cmd
+	})
+}
+
+// runCmd runs the commands created by newCmd for args of tool (e.g. git
+// running this CLI as a helper), retrying them according to the policy of the
+// subcommand in args.
+func (c *cli) runCmd(ctx context.Context, tool string, args []string, newCmd func() *Cmd) ([]byte, []byte, error) {
+	var stdout, stderr []byte
+	err := c.RetryPolicy(subcommand(args)).Do(ctx, func() error {
+		cmd := newCmd()
+		err := cmd.Run()
+		stdout, stderr = cmd.StdoutBytes(), cmd.StderrBytes()
+		if err != nil {
+			return newCommandError(tool, args, stderr, err)
+		}
+		return nil
+	})
+	return stdout, stderr, err
+}
+
+// RetryPolicy returns the policy for subcommand, falling back to the policy of
+// its first word (e.g. "tree" for "tree <image>"). It is nil if failures are not retried.
+func (c *cli) RetryPolicy(subcommand string) *RetryPolicy {
+	if p, ok := c.retryPolicies[subcommand]; ok {
+		return p
+	}
+	first, _, _ := strings.Cut(subcommand, " ")
+	return c.retryPolicies[first]
+}
+
+// SetRetryPolicy overrides the policy of subcommand. A nil policy disables retries.
+func (c *cli) SetRetryPolicy(subcommand string, p *RetryPolicy) {
+	if c.retryPolicies == nil {
+		c.retryPolicies = map[string]*RetryPolicy{}
+	}
+	c.retryPolicies[subcommand] = p
+}
+
+// Env returns the environment the CLI runs with. Changes apply to subsequent commands.
+func (c *cli) Env() *Environment {
+	if c.env == nil {
+		c.env = NewEnvironment(c.Name)
+	}
+	return c.env
+}
+
+func (c *cli) GetName() string {
+	return c.Name
+}
+
 func (c *cli) WithSetupStrategy(s SetupStrategy) *cli {
 	c.setupStrategy = s
 	return c
 }
 
 func (c *cli) Setup(ctx context.Context) error {
+	if err := c.Env().Prepare(c.Name); err != nil {
+		return err
+	}
 	var err error
 	c.pathToCLI, err = c.setupStrategy(ctx, c.Name)
 	if err == nil {
@@ -71,5 +144,10 @@ func (c *cli) Setup(ctx context.Context) error {
 }
 
 func (c *cli) Destroy(_ context.Context) error {
-	return nil
+	if c.pathToCLI != "" {
+		if err := strategy.Release(c.pathToCLI); err != nil {
+			return err
+		}
+	}
+	return c.Env().Remove()
 }
@@ -1,5 +1,14 @@
 package clients
 
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
 type Cosign struct {
 	*cli
 }
@@ -10,5 +19,532 @@ func NewCosign() *Cosign {
 			Name:           "cosign",
 			setupStrategy:  PreferredSetupStrategy(),
 			versionCommand: "version",
+			retryPolicies: map[string]*RetryPolicy{
+				"initialize":         TUFRetryPolicy,
+				"sign":               SubmissionRetryPolicy,
+				"sign-blob":          SubmissionRetryPolicy,
+				"attest":             SubmissionRetryPolicy,
+				"verify-blob":        NetworkRetryPolicy,
+				"verify":             RegistryRetryPolicy,
+				"verify-attestation": RegistryRetryPolicy,
+				"download":           RegistryRetryPolicy,
+				"tree":               RegistryRetryPolicy,
+			},
 		}}
 }
+
+var tlogIndexRegexp = regexp.MustCompile(`tlog entry created with index: (\d+)`)
+
+// InitializeOptions configures 'cosign initialize'. Empty fields fall back to
+// cosign's own defaults (or COSIGN_MIRROR/COSIGN_ROOT).
+type InitializeOptions struct {
+	Mirror string
+	Root   string
+}
+
+func (o InitializeOptions) Args() []string {
+	args := []string{"initialize"}
+	if o.Mirror != "" {
+		args = append(args, "--mirror", o.Mirror)
+	}
+	if o.Root != "" {
+		args = append(args, "--root", o.Root)
+	}
+	return args
+}
+
+// SignOptions configures 'cosign sign'.
+type SignOptions struct {
+	IdentityToken     string
+	Key               string
+	Annotations       map[string]string
+	AllowHTTPRegistry bool
+	NoUpload          bool
+	// OCIReferrers attaches the signature as an OCI 1.1 referrer of the image.
+	OCIReferrers bool
+	ExtraArgs    []string
+}
+
+// Args returns the cosign arguments for signing image, without the binary name.
+// It can be used to run cosign outside of this client (e.g. in a container).
+// The binary is not probed, so capability dependent flags are always passed.
+func (o SignOptions) Args(image string) []string {
+	args, _ := o.args(image, nil) // cannot fail without capabilities
+	return args
+}
+
+func (o SignOptions) args(image string, caps func() *Capabilities) ([]string, error) {
+	args := []string{"sign", "--yes"}
+	if o.IdentityToken != "" {
+		args = append(args, "--identity-token="+o.IdentityToken)
+	}
+	if o.Key != "" {
+		args = append(args, "--key", o.Key)
+	}
+	for _, k := range sortedKeys(o.Annotations) {
+		args = append(args, "-a", k+"="+o.Annotations[k])
+	}
+	if o.AllowHTTPRegistry {
+		args = append(args, "--allow-http-registry=true")
+	}
+	if o.NoUpload {
+		args = append(args, "--upload=false")
+	}
+	if o.OCIReferrers {
+		flags, err := referrersArgs(caps, "sign")
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, flags...)
+	}
+	args = append(args, o.ExtraArgs...)
+	return append(args, image), nil
+}
+
+// SignResult is the outcome of a signing operation.
+type SignResult struct {
+	// LogIndex is the Rekor log index of the created entry, or -1 when cosign did not report one.
+	LogIndex int64
+}
+
+// SignBlobOptions configures 'cosign sign-blob'.
+type SignBlobOptions struct {
+	IdentityToken string
+	Key           string
+	// BundlePath is where the bundle is written. When empty, the bundle is
+	// written to a temporary file that is removed once it has been read, and
+	// only SignBlobResult.BundleJSON holds it.
+	BundlePath string
+	// NewBundleFormat requires a Sigstore bundle. When false, cosign writes
+	// the bundle format it defaults to.
+	NewBundleFormat bool
+	ExtraArgs       []string
+}
+
+func (o SignBlobOptions) Args(blob string) []string {
+	args, _ := o.args(blob, nil) // cannot fail without capabilities
+	return args
+}
+
+func (o SignBlobOptions) args(blob string, caps func() *Capabilities) ([]string, error) {
+	args := []string{"sign-blob", "--yes"}
+	if o.IdentityToken != "" {
+		args = append(args, "--identity-token="+o.IdentityToken)
+	}
+	if o.Key != "" {
+		args = append(args, "--key", o.Key)
+	}
+	if o.BundlePath != "" {
+		args = append(args, "--bundle", o.BundlePath)
+	}
+	if o.NewBundleFormat {
+		flags, err := enableFlag(caps, CosignNewBundleFormat)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, flags...)
+	}
+	args = append(args, o.ExtraArgs...)
+	return append(args, blob), nil
+}
+
+// SignBlobResult is the outcome of 'cosign sign-blob'.
+type SignBlobResult struct {
+	SignResult
+	// Signature is the base64 encoded signature printed by cosign.
+	Signature string
+	Bundle    *Bundle
+	// BundleJSON is the bundle as written by cosign.
+	BundleJSON []byte
+	// BundlePath is SignBlobOptions.BundlePath, empty if the bundle was
+	// written to a temporary file.
+	BundlePath string
+}
+
+// VerifyOptions holds the identity constraints and trust material shared by the verify commands.
+type VerifyOptions struct {
+	CertificateIdentity         string
+	CertificateIdentityRegexp   string
+	CertificateOIDCIssuer       string
+	CertificateOIDCIssuerRegexp string
+	Key                         string
+	UseSignedTimestamps         bool
+	ExtraArgs                   []string
+}
+
+func (o VerifyOptions) flags(subcommand string, caps func() *Capabilities) ([]string, error) {
+	var args []string
+	if o.CertificateIdentity != "" {
+		args = append(args, "--certificate-identity", o.CertificateIdentity)
+	}
+	if o.CertificateIdentityRegexp != "" {
+		args = append(args, "--certificate-identity-regexp", o.CertificateIdentityRegexp)
+	}
+	if o.CertificateOIDCIssuer != "" {
+		args = append(args, "--certificate-oidc-issuer", o.CertificateOIDCIssuer)
+	}
+	if o.CertificateOIDCIssuerRegexp != "" {
+		args = append(args, "--certificate-oidc-issuer-regexp", o.CertificateOIDCIssuerRegexp)
+	}
+	if o.Key != "" {
+		args = append(args, "--key", o.Key)
+	}
+	if o.UseSignedTimestamps {
+		flags, err := enableFlag(caps, CosignSignedTimestamps.For(subcommand))
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, flags...)
+	}
+	return append(args, o.ExtraArgs...), nil
+}
+
+func (o VerifyOptions) Args(image string) []string {
+	args, _ := o.args(image, nil) // cannot fail without capabilities
+	return args
+}
+
+func (o VerifyOptions) args(image string, caps func() *Capabilities) ([]string, error) {
+	flags, err := o.flags("verify", caps)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]string{"verify"}, flags...), image), nil
+}
+
+// VerifyBlobOptions configures 'cosign verify-blob'.
+type VerifyBlobOptions struct {
+	VerifyOptions
+	BundlePath      string
+	SignaturePath   string
+	CertificatePath string
+	// NewBundleFormat requires BundlePath to be a Sigstore bundle. When
+	// false, cosign expects the bundle format it defaults to.
+	NewBundleFormat bool
+}
+
+func (o VerifyBlobOptions) Args(blob string) []string {
+	args, _ := o.args(blob, nil) // cannot fail without capabilities
+	return args
+}
+
+func (o VerifyBlobOptions) args(blob string, caps func() *Capabilities) ([]string, error) {
+	flags, err := o.flags("verify-blob", caps)
+	if err != nil {
+		return nil, err
+	}
+	args := append([]string{"verify-blob"}, flags...)
+	if o.BundlePath != "" {
+		args = append(args, "--bundle", o.BundlePath)
+	}
+	if o.SignaturePath != "" {
+		args = append(args, "--signature", o.SignaturePath)
+	}
+	if o.CertificatePath != "" {
+		args = append(args, "--certificate", o.CertificatePath)
+	}
+	if o.NewBundleFormat {
+		flags, err := enableFlag(caps, CosignNewBundleFormat.For("verify-blob"))
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, flags...)
+	}
+	return append(args, blob), nil
+}
+
+// VerifyBlobResult is the outcome of a successful 'cosign verify-blob'.
+type VerifyBlobResult struct {
+	// Bundle is the verified bundle, nil when a detached signature was verified.
+	Bundle *Bundle
+	// LogIndex is the Rekor log index of the verified entry, or -1 when it is unknown.
+	LogIndex int64
+	// CertificateIdentity is the email or URI of the signing certificate,
+	// empty when the blob was verified with a key.
+	CertificateIdentity string
+}
+
+// AttestOptions configures 'cosign attest'.
+type AttestOptions struct {
+	IdentityToken string
+	Key           string
+	PredicatePath string
+	Type          string
+	// OCIReferrers attaches the attestation as an OCI 1.1 referrer of the image.
+	OCIReferrers bool
+	ExtraArgs    []string
+}
+
+func (o AttestOptions) Args(image string) []string {
+	args, _ := o.args(image, nil) // cannot fail without capabilities
+	return args
+}
+
+func (o AttestOptions) args(image string, caps func() *Capabilities) ([]string, error) {
+	args := []string{"attest", "--yes"}
+	if o.IdentityToken != "" {
+		args = append(args, "--identity-token="+o.IdentityToken)
+	}
+	if o.Key != "" {
+		args = append(args, "--key", o.Key)
+	}
+	args = append(args, "--predicate", o.PredicatePath)
+	if o.Type != "" {
+		args = append(args, "--type", o.Type)
+	}
+	if o.OCIReferrers {
+		flags, err := referrersArgs(caps, "attest")
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, flags...)
+	}
+	args = append(args, o.ExtraArgs...)
+	return append(args, image), nil
+}
+
+// VerifyAttestationOptions configures 'cosign verify-attestation'.
+type VerifyAttestationOptions struct {
+	VerifyOptions
+	Type string
+}
+
+func (o VerifyAttestationOptions) Args(image string) []string {
+	args, _ := o.args(image, nil) // cannot fail without capabilities
+	return args
+}
+
+func (o VerifyAttestationOptions) args(image string, caps func() *Capabilities) ([]string, error) {
+	flags, err := o.flags("verify-attestation", caps)
+	if err != nil {
+		return nil, err
+	}
+	args := append([]string{"verify-attestation"}, flags...)
+	if o.Type != "" {
+		args = append(args, "--type", o.Type)
+	}
+	return append(args, image), nil
+}
+
+// enableFlag returns the flag of capability unless the binary already turns
+// it on by default, or a *MissingCapabilityError when the subcommand does not
+// accept it. Without capabilities (caps is nil) the flag is always returned.
+func enableFlag(caps func() *Capabilities, capability Capability) ([]string, error) {
+	if caps == nil {
+		return []string{capability.Flag}, nil
+	}
+	probed := caps()
+	if err := probed.Check(capability); err != nil {
+		return nil, err
+	}
+	if value, _ := probed.FlagDefault(capability.Subcommand, capability.Flag); value == "true" {
+		return nil, nil
+	}
+	return []string{capability.Flag}, nil
+}
+
+// referrersArgs returns the arguments that make subcommand attach to the image
+// as OCI 1.1 referrers: none when the binary does so by default, otherwise the
+// experimental referrers mode, which cosign only accepts with COSIGN_EXPERIMENTAL=1.
+func referrersArgs(caps func() *Capabilities, subcommand string) ([]string, error) {
+	if caps != nil {
+		probed := caps()
+		if probed.Supports(CosignOCIReferrersDefault) {
+			return nil, nil
+		}
+		if err := probed.Check(CosignReferrersMode.For(subcommand)); err != nil {
+			return nil, err
+		}
+	}
+	return []string{"--registry-referrers-mode=oci-1-1"}, nil
+}
+
+// experimentalEnv returns the environment overrides that enable the
+// experimental features of cosign, if enabled.
+func experimentalEnv(enabled bool) []string {
+	if !enabled {
+		return nil
+	}
+	return []string{"COSIGN_EXPERIMENTAL=1"}
+}
+
+// probe returns the capabilities of the binary, probed when first called, so
+// that options which do not depend on them do not run it.
+func (c *Cosign) probe(ctx context.Context) func() *Capabilities {
+	return func() *Capabilities {
+		return c.Capabilities(ctx)
+	}
+}
+
+// runWith is like run, with overrides added to the environment.
+func (c *Cosign) runWith(ctx context.Context, overrides []string, args ...string) ([]byte, []byte, error) {
+	return c.runCmd(ctx, c.Name, args, func() *Cmd {
+		cmd := c.Command(ctx, args...)
+		cmd.Env = c.Env().Environ(overrides...)
+		return cmd
+	})
+}
+
+func (c *Cosign) Initialize(ctx context.Context, opts InitializeOptions) error {
+	_, _, err := c.run(ctx, opts.Args()...)
+	return err
+}
+
+func (c *Cosign) Sign(ctx context.Context, image string, opts SignOptions) (*SignResult, error) {
+	args, err := opts.args(image, c.probe(ctx))
+	if err != nil {
+		return nil, err
+	}
+	_, stderr, err := c.runWith(ctx, experimentalEnv(opts.OCIReferrers), args...)
+	if err != nil {
+		return nil, err
+	}
+	return &SignResult{LogIndex: parseLogIndex(stderr)}, nil
+}
+
+func (c *Cosign) SignBlob(ctx context.Context, blob string, opts SignBlobOptions) (*SignBlobResult, error) {
+	bundlePath := opts.BundlePath
+	if opts.BundlePath == "" {
+		dir, err := os.MkdirTemp("", "cosign-bundle")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+		opts.BundlePath = filepath.Join(dir, "bundle.json")
+	}
+
+	args, err := opts.args(blob, c.probe(ctx))
+	if err != nil {
+		return nil, err
+	}
+	stdout, stderr, err := c.run(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(opts.BundlePath)
+	if err != nil {
+		return nil, err
+	}
+	bundle, err := ParseBundle(data)
+	if err != nil {
+		return nil, err
+	}
+	result := &SignBlobResult{
+		SignResult: SignResult{LogIndex: parseLogIndex(stderr)},
+		Signature:  lastLine(string(stdout)),
+		Bundle:     bundle,
+		BundleJSON: data,
+		BundlePath: bundlePath,
+	}
+	if result.LogIndex < 0 {
+		result.LogIndex = bundle.LogIndex()
+	}
+	return result, nil
+}
+
+func (c *Cosign) Verify(ctx context.Context, image string, opts VerifyOptions) ([]VerifyPayload, error) {
+	args, err := opts.args(image, c.probe(ctx))
+	if err != nil {
+		return nil, err
+	}
+	stdout, _, err := c.run(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseVerifyOutput(stdout)
+}
+
+// VerifyBlob verifies blob and describes the verified signature from the
+// bundle, if one was given.
+func (c *Cosign) VerifyBlob(ctx context.Context, blob string, opts VerifyBlobOptions) (*VerifyBlobResult, error) {
+	args, err := opts.args(blob, c.probe(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := c.run(ctx, args...); err != nil {
+		return nil, err
+	}
+
+	result := &VerifyBlobResult{LogIndex: -1}
+	if opts.BundlePath == "" {
+		return result, nil
+	}
+	data, err := os.ReadFile(opts.BundlePath)
+	if err != nil {
+		return nil, err
+	}
+	if result.Bundle, err = ParseBundle(data); err != nil {
+		return nil, err
+	}
+	result.LogIndex = result.Bundle.LogIndex()
+	if result.Bundle.HasCertificate() {
+		if result.CertificateIdentity, err = result.Bundle.CertificateIdentity(); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (c *Cosign) Attest(ctx context.Context, image string, opts AttestOptions) (*SignResult, error) {
+	args, err := opts.args(image, c.probe(ctx))
+	if err != nil {
+		return nil, err
+	}
+	_, stderr, err := c.runWith(ctx, experimentalEnv(opts.OCIReferrers), args...)
+	if err != nil {
+		return nil, err
+	}
+	return &SignResult{LogIndex: parseLogIndex(stderr)}, nil
+}
+
+func (c *Cosign) VerifyAttestation(ctx context.Context, image string, opts VerifyAttestationOptions) ([]Attestation, error) {
+	args, err := opts.args(image, c.probe(ctx))
+	if err != nil {
+		return nil, err
+	}
+	stdout, _, err := c.run(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAttestations(stdout)
+}
+
+// Tree lists the supply chain artifacts attached to image.
+func (c *Cosign) Tree(ctx context.Context, image string) (*Tree, error) {
+	stdout, _, err := c.run(ctx, "tree", image)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTree(stdout), nil
+}
+
+// Download returns all signature bundles attached to image.
+func (c *Cosign) Download(ctx context.Context, image string) ([]Bundle, error) {
+	stdout, _, err := c.run(ctx, "download", "signature", image)
+	if err != nil {
+		return nil, err
+	}
+	return ParseBundles(stdout)
+}
+
+func parseLogIndex(output []byte) int64 {
+	match := tlogIndexRegexp.FindSubmatch(output)
+	if match == nil {
+		return -1
+	}
+	idx, err := strconv.ParseInt(string(match[1]), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return idx
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
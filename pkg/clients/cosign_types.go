@@ -0,0 +1,351 @@
+package clients
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// JSONInt64 decodes integers encoded either as JSON numbers or as strings,
+// as protobuf JSON does for 64-bit values.
+type JSONInt64 int64
+
+func (i *JSONInt64) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*i = JSONInt64(v)
+	return nil
+}
+
+// RekorBundle is the legacy cosign transparency log bundle.
+type RekorBundle struct {
+	SignedEntryTimestamp string `json:"SignedEntryTimestamp"`
+	Payload              struct {
+		Body           string    `json:"body"`
+		IntegratedTime JSONInt64 `json:"integratedTime"`
+		LogIndex       JSONInt64 `json:"logIndex"`
+		LogID          string    `json:"logID"`
+	} `json:"Payload"`
+}
+
+type TlogEntry struct {
+	LogIndex JSONInt64 `json:"logIndex"`
+	LogID    struct {
+		KeyID string `json:"keyId"`
+	} `json:"logId"`
+	KindVersion struct {
+		Kind    string `json:"kind"`
+		Version string `json:"version"`
+	} `json:"kindVersion"`
+	IntegratedTime    JSONInt64       `json:"integratedTime"`
+	InclusionPromise  json.RawMessage `json:"inclusionPromise,omitempty"`
+	InclusionProof    json.RawMessage `json:"inclusionProof,omitempty"`
+	CanonicalizedBody string          `json:"canonicalizedBody"`
+}
+
+type rawBytes struct {
+	RawBytes string `json:"rawBytes"`
+}
+
+// Bundle is a signature bundle as produced by cosign. Both the Sigstore
+// protobuf bundle format and the legacy cosign formats are decoded.
+type Bundle struct {
+	MediaType            string `json:"mediaType"`
+	VerificationMaterial struct {
+		Certificate          *rawBytes `json:"certificate,omitempty"`
+		X509CertificateChain *struct {
+			Certificates []rawBytes `json:"certificates"`
+		} `json:"x509CertificateChain,omitempty"`
+		TlogEntries []TlogEntry `json:"tlogEntries"`
+	} `json:"verificationMaterial"`
+	MessageSignature *struct {
+		MessageDigest struct {
+			Algorithm string `json:"algorithm"`
+			Digest    string `json:"digest"`
+		} `json:"messageDigest"`
+		Signature string `json:"signature"`
+	} `json:"messageSignature,omitempty"`
+	DSSEEnvelope json.RawMessage `json:"dsseEnvelope,omitempty"`
+
+	// Legacy formats: 'cosign download signature' and 'cosign sign-blob --bundle'.
+	Base64Signature string          `json:"base64Signature,omitempty"`
+	Cert            json.RawMessage `json:"cert,omitempty"`
+	LegacyBundle    *RekorBundle    `json:"Bundle,omitempty"`
+	RekorBundle     *RekorBundle    `json:"rekorBundle,omitempty"`
+}
+
+// ParseBundle decodes a single bundle.
+func ParseBundle(data []byte) (*Bundle, error) {
+	var b Bundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("cannot parse bundle: %w", err)
+	}
+	return &b, nil
+}
+
+// ParseBundles decodes a stream of concatenated bundles. Any text before the
+// first JSON object is ignored.
+func ParseBundles(data []byte) ([]Bundle, error) {
+	start := bytes.IndexByte(data, '{')
+	if start < 0 {
+		return nil, errors.New("no JSON object in bundle output")
+	}
+	var bundles []Bundle
+	decoder := json.NewDecoder(bytes.NewReader(data[start:]))
+	for decoder.More() {
+		var b Bundle
+		if err := decoder.Decode(&b); err != nil {
+			return bundles, fmt.Errorf("cannot parse bundle: %w", err)
+		}
+		bundles = append(bundles, b)
+	}
+	return bundles, nil
+}
+
+// LatestBundle returns the bundle with the highest log index, or nil if none
+// of the bundles has a transparency log entry.
+func LatestBundle(bundles []Bundle) *Bundle {
+	var latest *Bundle
+	for i := range bundles {
+		if bundles[i].LogIndex() < 0 {
+			continue
+		}
+		if latest == nil || bundles[i].LogIndex() > latest.LogIndex() {
+			latest = &bundles[i]
+		}
+	}
+	return latest
+}
+
+// LogIndex returns the Rekor log index recorded in the bundle, or -1.
+func (b *Bundle) LogIndex() int64 {
+	switch {
+	case len(b.VerificationMaterial.TlogEntries) > 0:
+		return int64(b.VerificationMaterial.TlogEntries[0].LogIndex)
+	case b.LegacyBundle != nil:
+		return int64(b.LegacyBundle.Payload.LogIndex)
+	case b.RekorBundle != nil:
+		return int64(b.RekorBundle.Payload.LogIndex)
+	}
+	return -1
+}
+
+// Certificate returns the signing certificate embedded in the bundle.
+func (b *Bundle) Certificate() (*x509.Certificate, error) {
+	var der []byte
+	var err error
+	switch {
+	case b.VerificationMaterial.Certificate != nil:
+		der, err = base64.StdEncoding.DecodeString(b.VerificationMaterial.Certificate.RawBytes)
+	case b.VerificationMaterial.X509CertificateChain != nil && len(b.VerificationMaterial.X509CertificateChain.Certificates) > 0:
+		der, err = base64.StdEncoding.DecodeString(b.VerificationMaterial.X509CertificateChain.Certificates[0].RawBytes)
+	case len(b.Cert) > 0:
+		der, err = legacyCertificate(b.Cert)
+	default:
+		return nil, errors.New("bundle has no certificate")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(der)
+}
+
+// HasCertificate reports whether the bundle embeds a signing certificate
+// rather than being verifiable with a key only.
+func (b *Bundle) HasCertificate() bool {
+	return b.VerificationMaterial.Certificate != nil ||
+		(b.VerificationMaterial.X509CertificateChain != nil && len(b.VerificationMaterial.X509CertificateChain.Certificates) > 0) ||
+		len(b.Cert) > 0
+}
+
+// CertificateIdentity returns the email or URI subject alternative name of the signing certificate.
+func (b *Bundle) CertificateIdentity() (string, error) {
+	cert, err := b.Certificate()
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case len(cert.EmailAddresses) > 0:
+		return cert.EmailAddresses[0], nil
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String(), nil
+	}
+	return "", errors.New("certificate has no email or URI identity")
+}
+
+func legacyCertificate(raw json.RawMessage) ([]byte, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, errors.New("unsupported legacy certificate encoding")
+	}
+	data := []byte(s)
+	if decoded, err := base64.StdEncoding.DecodeString(s); err == nil {
+		data = decoded
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("certificate is not PEM encoded")
+	}
+	return block.Bytes, nil
+}
+
+// VerifyPayload is a simple signing payload printed by 'cosign verify'.
+type VerifyPayload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+	Optional struct {
+		Bundle  *RekorBundle `json:"Bundle,omitempty"`
+		Issuer  string       `json:"Issuer,omitempty"`
+		Subject string       `json:"Subject,omitempty"`
+	} `json:"optional"`
+}
+
+// LogIndex returns the Rekor log index of the verified signature, or -1.
+func (p *VerifyPayload) LogIndex() int64 {
+	if p.Optional.Bundle == nil {
+		return -1
+	}
+	return int64(p.Optional.Bundle.Payload.LogIndex)
+}
+
+// ParseVerifyOutput decodes the JSON array printed by 'cosign verify'.
+func ParseVerifyOutput(data []byte) ([]VerifyPayload, error) {
+	start := bytes.IndexByte(data, '[')
+	if start < 0 {
+		return nil, errors.New("no JSON array in verify output")
+	}
+	var payloads []VerifyPayload
+	if err := json.NewDecoder(bytes.NewReader(data[start:])).Decode(&payloads); err != nil {
+		return nil, fmt.Errorf("cannot parse verify output: %w", err)
+	}
+	return payloads, nil
+}
+
+// Attestation is a DSSE envelope printed by 'cosign verify-attestation'.
+// Payload holds the decoded in-toto statement.
+type Attestation struct {
+	PayloadType string `json:"payloadType"`
+	Payload     []byte `json:"payload"`
+	Signatures  []struct {
+		KeyID string `json:"keyid"`
+		Sig   string `json:"sig"`
+	} `json:"signatures"`
+}
+
+type InTotoStatement struct {
+	Type          string `json:"_type"`
+	PredicateType string `json:"predicateType"`
+	Subject       []struct {
+		Name   string            `json:"name"`
+		Digest map[string]string `json:"digest"`
+	} `json:"subject"`
+	Predicate json.RawMessage `json:"predicate"`
+}
+
+func (a *Attestation) Statement() (*InTotoStatement, error) {
+	var s InTotoStatement
+	if err := json.Unmarshal(a.Payload, &s); err != nil {
+		return nil, fmt.Errorf("cannot parse in-toto statement: %w", err)
+	}
+	return &s, nil
+}
+
+// ParseAttestations decodes the stream of envelopes printed by 'cosign verify-attestation'.
+func ParseAttestations(data []byte) ([]Attestation, error) {
+	start := bytes.IndexByte(data, '{')
+	if start < 0 {
+		return nil, errors.New("no JSON object in attestation output")
+	}
+	var attestations []Attestation
+	decoder := json.NewDecoder(bytes.NewReader(data[start:]))
+	for decoder.More() {
+		var a Attestation
+		if err := decoder.Decode(&a); err != nil {
+			return attestations, fmt.Errorf("cannot parse attestation: %w", err)
+		}
+		attestations = append(attestations, a)
+	}
+	return attestations, nil
+}
+
+// Tree is the parsed output of 'cosign tree'. Depending on the cosign version
+// and registry, artifacts are either listed by tag-based section or as OCI referrers.
+type Tree struct {
+	OCIReferrers bool
+	Signatures   []string
+	Attestations []string
+	SBOMs        []string
+	// Referrers maps the artifact type to the digests listed via OCI referrers.
+	Referrers map[string][]string
+}
+
+var (
+	treeDigestRegexp   = regexp.MustCompile(`🍒 (\w+:[0-9a-f]{64})`)
+	treeReferrerRegexp = regexp.MustCompile(`🔗\s*(.*?)\s*artifacts via OCI referrer`)
+)
+
+// Artifacts returns the total number of artifacts in the tree.
+func (t *Tree) Artifacts() int {
+	n := len(t.Signatures) + len(t.Attestations) + len(t.SBOMs)
+	for _, digests := range t.Referrers {
+		n += len(digests)
+	}
+	return n
+}
+
+func ParseTree(data []byte) *Tree {
+	tree := &Tree{Referrers: map[string][]string{}}
+	var section *[]string
+	referrer := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		switch {
+		case strings.Contains(line, "Signatures for an image tag:"):
+			section = &tree.Signatures
+			continue
+		case strings.Contains(line, "Attestations for an image tag:"):
+			section = &tree.Attestations
+			continue
+		case strings.Contains(line, "SBOMs for an image tag:"):
+			section = &tree.SBOMs
+			continue
+		case strings.Contains(line, "OCI referrer") || strings.Contains(line, "via OCI"):
+			tree.OCIReferrers = true
+			section = nil
+			referrer = ""
+			if m := treeReferrerRegexp.FindStringSubmatch(line); m != nil {
+				referrer = m[1]
+			}
+			continue
+		}
+
+		m := treeDigestRegexp.FindStringSubmatch(line)
+		switch {
+		case m == nil:
+		case section != nil:
+			*section = append(*section, m[1])
+		case tree.OCIReferrers:
+			tree.Referrers[referrer] = append(tree.Referrers[referrer], m[1])
+		}
+	}
+	return tree
+}
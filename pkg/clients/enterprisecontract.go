@@ -1,5 +1,7 @@
 package clients
 
+import "context"
+
 type EnterpriseContract struct {
 	*cli
 }
@@ -10,5 +12,14 @@ func NewEnterpriseContract() *EnterpriseContract {
 			Name:           "ec",
 			setupStrategy:  PreferredSetupStrategy(),
 			versionCommand: "version",
+			retryPolicies: map[string]*RetryPolicy{
+				"sigstore initialize": TUFRetryPolicy,
+			},
 		}}
 }
+
+// Initialize runs 'ec sigstore initialize', which takes the same options as 'cosign initialize'.
+func (c *EnterpriseContract) Initialize(ctx context.Context, opts InitializeOptions) error {
+	_, _, err := c.run(ctx, append([]string{"sigstore"}, opts.Args()...)...)
+	return err
+}
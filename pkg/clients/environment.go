@@ -0,0 +1,175 @@
+package clients
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/securesign/sigstore-e2e/pkg/api"
+)
+
+// passthroughVars are host variables a CLI needs to reach the cluster (proxies,
+// CA bundles), to find its helpers and the registry credentials of podman
+// (REGISTRY_AUTH_FILE, by default below XDG_RUNTIME_DIR). Everything else of
+// the host environment is dropped.
+var passthroughVars = []string{
+	"PATH",
+	"TMPDIR",
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY",
+	"http_proxy", "https_proxy", "no_proxy",
+	"SSL_CERT_FILE", "SSL_CERT_DIR",
+	"DOCKER_HOST", "DOCKER_CONFIG",
+	"REGISTRY_AUTH_FILE", "XDG_RUNTIME_DIR",
+	"KUBECONFIG",
+}
+
+// Environment is the environment a CLI runs with. Every client gets its own
+// home directory, so state written by one CLI (e.g. the TUF root stored by
+// 'cosign initialize') is never seen by another client or by a later run.
+type Environment struct {
+	// Home is the private home directory of the client. HOME, TUF_ROOT and the
+	// XDG directories (USERPROFILE and APPDATA on Windows) point below it. It
+	// is empty until Prepare is called.
+	Home string
+	// Vars are the declared variables. They take precedence over the
+	// passthrough variables of the host, but not over the home directories.
+	Vars map[string]string
+}
+
+// toolVars maps each CLI to the variables it reads configuration keys from:
+// cosign binds COSIGN_<FLAG> to its flags and gitsign reads GITSIGN_<KEY>.
+// The Sigstore libraries linked into cosign, gitsign and ec read the Rekor
+// public key from SIGSTORE_REKOR_PUBLIC_KEY. Other CLIs get their
+// configuration as flags.
+var toolVars = map[string]map[string]string{
+	"cosign": {
+		api.TufURL:         "COSIGN_MIRROR",
+		api.FulcioURL:      "COSIGN_FULCIO_URL",
+		api.RekorURL:       "COSIGN_REKOR_URL",
+		api.OidcIssuerURL:  "COSIGN_OIDC_ISSUER",
+		api.OidcClientID:   "COSIGN_OIDC_CLIENT_ID",
+		api.RekorPublicKey: "SIGSTORE_REKOR_PUBLIC_KEY",
+	},
+	"gitsign": {
+		api.FulcioURL:      "GITSIGN_FULCIO_URL",
+		api.RekorURL:       "GITSIGN_REKOR_URL",
+		api.OidcIssuerURL:  "GITSIGN_OIDC_ISSUER",
+		api.OidcClientID:   "GITSIGN_OIDC_CLIENT_ID",
+		api.RekorPublicKey: "SIGSTORE_REKOR_PUBLIC_KEY",
+	},
+	"ec": {
+		api.RekorPublicKey: "SIGSTORE_REKOR_PUBLIC_KEY",
+	},
+}
+
+// NewEnvironment returns an environment declaring the Sigstore service URLs
+// and the OIDC client taken from api.Values, as read by the CLI called tool.
+func NewEnvironment(tool string) *Environment {
+	return &Environment{Vars: DefaultEnvironmentVars(tool)}
+}
+
+// DefaultEnvironmentVars maps the configured services to the variables read by
+// the CLI called tool. Unset values are left out.
+func DefaultEnvironmentVars(tool string) map[string]string {
+	vars := map[string]string{}
+	for key, name := range toolVars[tool] {
+		if value := api.GetValueFor(key); value != "" {
+			vars[name] = value
+		}
+	}
+	if tool == "cosign" {
+		vars["COSIGN_YES"] = "true"
+		if tufURL := api.GetValueFor(api.TufURL); tufURL != "" {
+			vars["COSIGN_ROOT"] = tufURL + "/root.json"
+		}
+	}
+	return vars
+}
+
+// Prepare creates the private home directory of the client called name.
+// Calling it again keeps the existing directory.
+func (e *Environment) Prepare(name string) error {
+	if e.Home != "" {
+		return nil
+	}
+	home, err := os.MkdirTemp("", "e2e-"+name+"-home")
+	if err != nil {
+		return err
+	}
+	e.Home = home
+	return nil
+}
+
+// Remove deletes the home directory together with everything the CLI stored in it.
+func (e *Environment) Remove() error {
+	if e.Home == "" {
+		return nil
+	}
+	err := os.RemoveAll(e.Home)
+	e.Home = ""
+	return err
+}
+
+// Set declares a variable, e.g. to point a single client to a different service.
+func (e *Environment) Set(key, value string) {
+	if e.Vars == nil {
+		e.Vars = map[string]string{}
+	}
+	e.Vars[key] = value
+}
+
+// Environ returns the environment in the form used by exec.Cmd.Env, sorted by
+// key. Each of overrides is a "KEY=value" pair replacing the variable of the same name.
+func (e *Environment) Environ(overrides ...string) []string {
+	vars := map[string]string{}
+	for _, key := range passthroughVars {
+		if value, ok := os.LookupEnv(key); ok {
+			vars[key] = value
+		}
+	}
+	for key, value := range e.Vars {
+		vars[key] = value
+	}
+	if e.Home != "" {
+		for key, value := range e.homeVars() {
+			vars[key] = value
+		}
+	}
+	for _, kv := range overrides {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			vars[key] = value
+		}
+	}
+
+	env := make([]string, 0, len(vars))
+	for _, key := range sortedKeys(vars) {
+		env = append(env, key+"="+vars[key])
+	}
+	return env
+}
+
+func (e *Environment) homeVars() map[string]string {
+	vars := map[string]string{
+		"HOME":                e.Home,
+		"TUF_ROOT":            filepath.Join(e.Home, ".sigstore", "root"),
+		"XDG_CONFIG_HOME":     filepath.Join(e.Home, ".config"),
+		"XDG_CACHE_HOME":      filepath.Join(e.Home, ".cache"),
+		"XDG_DATA_HOME":       filepath.Join(e.Home, ".local", "share"),
+		"XDG_STATE_HOME":      filepath.Join(e.Home, ".local", "state"),
+		"GIT_CONFIG_NOSYSTEM": "1",
+	}
+	if runtime.GOOS == "windows" {
+		vars["USERPROFILE"] = e.Home
+		vars["APPDATA"] = filepath.Join(e.Home, "AppData", "Roaming")
+		vars["LOCALAPPDATA"] = filepath.Join(e.Home, "AppData", "Local")
+	}
+	// Registry credentials live in the real home; keep using them unless
+	// DOCKER_CONFIG already points elsewhere.
+	if _, ok := os.LookupEnv("DOCKER_CONFIG"); !ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			vars["DOCKER_CONFIG"] = filepath.Join(home, ".docker")
+		}
+	}
+	return vars
+}
@@ -0,0 +1,153 @@
+package clients
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/securesign/sigstore-e2e/pkg/api"
+)
+
+func envValue(env []string, key string) (string, bool) {
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func newTestCli(name string) *cli {
+	return &cli{
+		Name: name,
+		setupStrategy: func(_ context.Context, _ string) (string, error) {
+			return "/bin/true", nil
+		},
+	}
+}
+
+// setValues sets configuration values for the duration of a test and restores
+// the previous ones afterwards.
+func setValues(t *testing.T, values map[string]string) {
+	t.Helper()
+	for key, value := range values {
+		previous := api.Values.Get(key)
+		api.Values.Set(key, value)
+		t.Cleanup(func() { api.Values.Set(key, previous) })
+	}
+}
+
+func TestDefaultEnvironmentVars(t *testing.T) {
+	setValues(t, map[string]string{
+		api.TufURL:         "https://tuf.example.com",
+		api.RekorURL:       "https://rekor.example.com",
+		api.RekorPublicKey: "/tmp/rekor.pub",
+		api.FulcioURL:      "",
+		api.OidcIssuerURL:  "",
+	})
+
+	tests := []struct {
+		tool     string
+		expected map[string]string
+	}{
+		{"cosign", map[string]string{
+			"COSIGN_YES":                "true",
+			"COSIGN_MIRROR":             "https://tuf.example.com",
+			"COSIGN_ROOT":               "https://tuf.example.com/root.json",
+			"COSIGN_REKOR_URL":          "https://rekor.example.com",
+			"COSIGN_OIDC_CLIENT_ID":     "trusted-artifact-signer",
+			"SIGSTORE_REKOR_PUBLIC_KEY": "/tmp/rekor.pub",
+		}},
+		{"gitsign", map[string]string{
+			"GITSIGN_REKOR_URL":         "https://rekor.example.com",
+			"GITSIGN_OIDC_CLIENT_ID":    "trusted-artifact-signer",
+			"SIGSTORE_REKOR_PUBLIC_KEY": "/tmp/rekor.pub",
+		}},
+		{"ec", map[string]string{
+			"SIGSTORE_REKOR_PUBLIC_KEY": "/tmp/rekor.pub",
+		}},
+		{"rekor-cli", map[string]string{}},
+	}
+	for _, tt := range tests {
+		vars := DefaultEnvironmentVars(tt.tool)
+		for key, value := range tt.expected {
+			if vars[key] != value {
+				t.Errorf("%s: %s = %q, want %q", tt.tool, key, vars[key], value)
+			}
+		}
+		for key := range vars {
+			if _, ok := tt.expected[key]; !ok {
+				t.Errorf("%s: unexpected variable %s", tt.tool, key)
+			}
+		}
+	}
+}
+
+func TestCommandEnvironment(t *testing.T) {
+	t.Setenv("E2E_UNRELATED_VAR", "leaked")
+	t.Setenv("HTTPS_PROXY", "http://proxy:3128")
+	t.Setenv("REGISTRY_AUTH_FILE", "/run/user/1000/containers/auth.json")
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+
+	c := newTestCli("cosign")
+	c.Env().Set("COSIGN_EXPERIMENTAL", "1")
+	if err := c.Setup(context.Background()); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	home := c.Env().Home
+
+	env := c.Command(context.Background(), "version").Env
+	if !slices.IsSorted(env) {
+		t.Error("expected sorted environment")
+	}
+	for key, want := range map[string]string{
+		"HOME":                home,
+		"TUF_ROOT":            filepath.Join(home, ".sigstore", "root"),
+		"XDG_CONFIG_HOME":     filepath.Join(home, ".config"),
+		"HTTPS_PROXY":         "http://proxy:3128",
+		"REGISTRY_AUTH_FILE":  "/run/user/1000/containers/auth.json",
+		"XDG_RUNTIME_DIR":     "/run/user/1000",
+		"COSIGN_EXPERIMENTAL": "1",
+	} {
+		if got, _ := envValue(env, key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if _, ok := envValue(env, "E2E_UNRELATED_VAR"); ok {
+		t.Error("host variable leaked into the command environment")
+	}
+
+	if err := c.Destroy(context.Background()); err != nil {
+		t.Fatalf("destroy failed: %v", err)
+	}
+	if _, err := os.Stat(home); !os.IsNotExist(err) {
+		t.Errorf("expected home %s to be removed, got %v", home, err)
+	}
+}
+
+func TestClientsHaveSeparateHomes(t *testing.T) {
+	first, second := newTestCli("cosign"), newTestCli("gitsign")
+	for _, c := range []*cli{first, second} {
+		if err := c.Setup(context.Background()); err != nil {
+			t.Fatalf("setup failed: %v", err)
+		}
+		t.Cleanup(func() { _ = c.Destroy(context.Background()) })
+	}
+	if first.Env().Home == second.Env().Home {
+		t.Fatalf("clients share home %s", first.Env().Home)
+	}
+}
+
+func TestEnvironOverrides(t *testing.T) {
+	e := &Environment{Vars: map[string]string{"SIGSTORE_REKOR_URL": "https://a"}}
+	env := e.Environ("SIGSTORE_REKOR_URL=https://b", "SIGSTORE_ID_TOKEN=token")
+	if got, _ := envValue(env, "SIGSTORE_REKOR_URL"); got != "https://b" {
+		t.Errorf("override not applied, got %q", got)
+	}
+	if _, ok := envValue(env, "HOME"); ok {
+		t.Error("HOME must not be set before Prepare")
+	}
+}
@@ -0,0 +1,72 @@
+package clients
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/securesign/sigstore-e2e/pkg/redact"
+)
+
+// CommandError describes a CLI invocation that did not finish successfully.
+// Only the subcommand is kept and stderr is redacted, so that flag values
+// (e.g. identity tokens) never end up in error messages.
+type CommandError struct {
+	Tool       string
+	Subcommand string
+	ExitCode   int
+	Stderr     string
+	Err        error
+}
+
+func newCommandError(tool string, args []string, stderr []byte, err error) *CommandError {
+	exitCode := -1
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	}
+	return &CommandError{
+		Tool:       tool,
+		Subcommand: subcommand(args),
+		ExitCode:   exitCode,
+		Stderr:     redact.String(string(stderr)),
+		Err:        err,
+	}
+}
+
+func (e *CommandError) Error() string {
+	msg := fmt.Sprintf("%s %s failed (exit code %d)", e.Tool, e.Subcommand, e.ExitCode)
+	if last := lastLine(e.Stderr); last != "" {
+		msg += ": " + last
+	}
+	return msg
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// subcommand returns the leading non-flag arguments, e.g. "download signature".
+// Positional values such as image references or file names are not included.
+func subcommand(args []string) string {
+	var parts []string
+	for i, a := range args {
+		if strings.HasPrefix(a, "-") || (i > 0 && !subcommandRegexp.MatchString(a)) {
+			break
+		}
+		parts = append(parts, a)
+		if len(parts) == 2 { //nolint:mnd
+			break
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+var subcommandRegexp = regexp.MustCompile(`^[a-z][a-z-]*$`)
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
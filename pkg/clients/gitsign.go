@@ -1,11 +1,14 @@
 package clients
 
 import (
+	"bytes"
 	"context"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"strconv"
 
 	"github.com/sirupsen/logrus"
 )
@@ -20,14 +23,267 @@ func NewGitsign() *Gitsign {
 			Name:           "gitsign",
 			setupStrategy:  PreferredSetupStrategy(),
 			versionCommand: "--version",
+			retryPolicies: map[string]*RetryPolicy{
+				"initialize": TUFRetryPolicy,
+				"verify":     NetworkRetryPolicy,
+				"verify-tag": NetworkRetryPolicy,
+				// git runs gitsign to sign commits and tags
+				"commit": SubmissionRetryPolicy,
+				"tag":    SubmissionRetryPolicy,
+				"attest": SubmissionRetryPolicy,
+			},
 		}}
 }
 
+// GitsignRepoConfig is the signing configuration written into a repository by SetupRepository.
+type GitsignRepoConfig struct {
+	UserName  string
+	UserEmail string
+	Fulcio    string
+	Rekor     string
+	Issuer    string
+	ClientID  string
+}
+
+// GitsignVerifyOptions holds the identity constraints for 'gitsign verify' and 'gitsign verify-tag'.
+type GitsignVerifyOptions struct {
+	CertificateIdentity         string
+	CertificateIdentityRegexp   string
+	CertificateOIDCIssuer       string
+	CertificateOIDCIssuerRegexp string
+}
+
+func (o GitsignVerifyOptions) flags() []string {
+	var args []string
+	if o.CertificateIdentity != "" {
+		args = append(args, "--certificate-identity", o.CertificateIdentity)
+	}
+	if o.CertificateIdentityRegexp != "" {
+		args = append(args, "--certificate-identity-regexp", o.CertificateIdentityRegexp)
+	}
+	if o.CertificateOIDCIssuer != "" {
+		args = append(args, "--certificate-oidc-issuer", o.CertificateOIDCIssuer)
+	}
+	if o.CertificateOIDCIssuerRegexp != "" {
+		args = append(args, "--certificate-oidc-issuer-regexp", o.CertificateOIDCIssuerRegexp)
+	}
+	return args
+}
+
+// GitsignVerifyResult is the parsed summary printed by 'gitsign verify'.
+type GitsignVerifyResult struct {
+	CertificateIdentity string
+	Issuer              string
+	// LogIndex is the Rekor log index of the signature, or -1 when not reported.
+	LogIndex int64
+	// UUID is the Rekor entry UUID, empty when the gitsign version does not print it.
+	UUID                   string
+	GitSignatureValid      bool
+	RekorEntryValid        bool
+	CertificateClaimsValid bool
+}
+
+// GitsignSignResult is the transparency log entry created for a signed commit or tag.
+type GitsignSignResult struct {
+	// LogIndex is the Rekor log index of the signature, or -1 when not reported.
+	LogIndex int64
+	// UUID is the Rekor entry UUID, empty when the gitsign version does not print it.
+	UUID string
+}
+
+// GitsignAttestOptions configures 'gitsign attest'.
+type GitsignAttestOptions struct {
+	// Path is the file with the attestation predicate.
+	Path string
+	// Type is the predicate type, e.g. "custom" or a predicate type URI.
+	Type string
+	// ObjectType is "commit" (default) or "tree".
+	ObjectType string
+}
+
+var (
+	// gitsign prints "tlog entry created with index: N" when signing and
+	// "tlog index: N" when verifying.
+	gitsignTlogIndexRegexp = regexp.MustCompile(`tlog (?:entry created with )?index: (\d+)`)
+	gitsignTlogUUIDRegexp  = regexp.MustCompile(`tlog (?:uuid|entry uuid): ([0-9a-f]+)`)
+	gitsignGoodSigRegexp   = regexp.MustCompile(`Good signature from \[([^\]]*)\]\(([^)]*)\)`)
+	gitsignValidatedRegexp = regexp.MustCompile(`Validated (Git signature|Rekor entry|Certificate claims): (true|false)`)
+)
+
+// SetupRepository configures the git repository in dir to sign commits and tags with this gitsign binary.
+func (c *Gitsign) SetupRepository(ctx context.Context, dir string, cfg GitsignRepoConfig) error {
+	options := [][2]string{
+		{"user.name", cfg.UserName},
+		{"user.email", cfg.UserEmail},
+		{"commit.gpgsign", "true"},
+		{"tag.gpgsign", "true"},
+		{"gpg.x509.program", c.pathToCLI},
+		{"gpg.format", "x509"},
+		{"gitsign.fulcio", cfg.Fulcio},
+		{"gitsign.rekor", cfg.Rekor},
+		{"gitsign.issuer", cfg.Issuer},
+		{"gitsign.clientID", cfg.ClientID},
+	}
+	for _, o := range options {
+		if o[1] == "" {
+			continue
+		}
+		if _, _, err := c.git(ctx, dir, "", "config", "--local", o[0], o[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Gitsign) Initialize(ctx context.Context, opts InitializeOptions) error {
+	_, _, err := c.run(ctx, opts.Args()...)
+	return err
+}
+
+// Commit creates a signed commit of the staged changes in dir.
+func (c *Gitsign) Commit(ctx context.Context, dir string, signToken string, message string) (*GitsignSignResult, error) {
+	return c.sign(ctx, dir, signToken, "commit", "-S", "-m", message)
+}
+
+// Tag creates a signed annotated tag of HEAD in dir.
+func (c *Gitsign) Tag(ctx context.Context, dir string, signToken string, name string, message string) (*GitsignSignResult, error) {
+	return c.sign(ctx, dir, signToken, "tag", "-s", name, "-m", message)
+}
+
+// sign runs git with args and parses the tlog entry gitsign reports. git
+// swallows the stderr of a signing program that succeeds, so gitsign also
+// writes it to the file named by GITSIGN_LOG.
+func (c *Gitsign) sign(ctx context.Context, dir string, signToken string, args ...string) (*GitsignSignResult, error) {
+	log, err := os.CreateTemp("", "gitsign-log")
+	if err != nil {
+		return nil, err
+	}
+	_ = log.Close()
+	defer os.Remove(log.Name())
+
+	_, stderr, err := c.gitWith(ctx, dir, signToken, []string{"GITSIGN_LOG=" + log.Name()}, args...)
+	if err != nil {
+		return nil, err
+	}
+	output, err := os.ReadFile(log.Name())
+	if err != nil {
+		return nil, err
+	}
+	return ParseGitsignSignOutput(append(output, stderr...)), nil
+}
+
+// Verify verifies the commit signature of revision in dir.
+func (c *Gitsign) Verify(ctx context.Context, dir string, revision string, opts GitsignVerifyOptions) (*GitsignVerifyResult, error) {
+	return c.verify(ctx, dir, "verify", revision, opts)
+}
+
+// VerifyTag verifies the signature of tag in dir.
+func (c *Gitsign) VerifyTag(ctx context.Context, dir string, tag string, opts GitsignVerifyOptions) (*GitsignVerifyResult, error) {
+	return c.verify(ctx, dir, "verify-tag", tag, opts)
+}
+
+// Attest signs the predicate at opts.Path and stores it as an attestation of HEAD in dir.
+func (c *Gitsign) Attest(ctx context.Context, dir string, signToken string, opts GitsignAttestOptions) error {
+	env, err := c.gitEnv(signToken, nil)
+	if err != nil {
+		return err
+	}
+	args := opts.args()
+	_, _, err = c.runCmd(ctx, c.Name, args, func() *Cmd {
+		cmd := c.Command(ctx, args...)
+		cmd.Dir = dir
+		cmd.Env = env
+		return cmd
+	})
+	return err
+}
+
+func (o GitsignAttestOptions) args() []string {
+	args := []string{"attest", "-f", o.Path}
+	if o.Type != "" {
+		args = append(args, "--type", o.Type)
+	}
+	if o.ObjectType != "" {
+		args = append(args, "--objtype", o.ObjectType)
+	}
+	return args
+}
+
+func (c *Gitsign) verify(ctx context.Context, dir string, command string, target string, opts GitsignVerifyOptions) (*GitsignVerifyResult, error) {
+	args := append(append([]string{command}, opts.flags()...), target)
+	stdout, stderr, err := c.runIn(ctx, dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGitsignVerifyOutput(append(stdout, stderr...)), nil
+}
+
+// ParseGitsignSignOutput returns the tlog entry printed by gitsign when
+// signing or verifying. The log index is -1 when there is none.
+func ParseGitsignSignOutput(output []byte) *GitsignSignResult {
+	result := &GitsignSignResult{LogIndex: -1}
+	if m := gitsignTlogIndexRegexp.FindSubmatch(output); m != nil {
+		if idx, err := strconv.ParseInt(string(m[1]), 10, 64); err == nil {
+			result.LogIndex = idx
+		}
+	}
+	if m := gitsignTlogUUIDRegexp.FindSubmatch(output); m != nil {
+		result.UUID = string(m[1])
+	}
+	return result
+}
+
+// ParseGitsignVerifyOutput extracts the verification summary printed by gitsign.
+func ParseGitsignVerifyOutput(output []byte) *GitsignVerifyResult {
+	entry := ParseGitsignSignOutput(output)
+	result := &GitsignVerifyResult{LogIndex: entry.LogIndex, UUID: entry.UUID}
+	if m := gitsignGoodSigRegexp.FindSubmatch(output); m != nil {
+		result.CertificateIdentity = string(m[1])
+		result.Issuer = string(m[2])
+	}
+	for _, m := range gitsignValidatedRegexp.FindAllSubmatch(output, -1) {
+		valid := bytes.Equal(m[2], []byte("true"))
+		switch string(m[1]) {
+		case "Git signature":
+			result.GitSignatureValid = valid
+		case "Rekor entry":
+			result.RekorEntryValid = valid
+		case "Certificate claims":
+			result.CertificateClaimsValid = valid
+		}
+	}
+	return result
+}
+
 func (c *Gitsign) GitWithGitSign(ctx context.Context, workdir string, signToken string, args ...string) error {
-	cmd := exec.CommandContext(ctx, "git", args...)
+	_, _, err := c.git(ctx, workdir, signToken, args...)
+	return err
+}
+
+// git runs git in workdir with this gitsign binary on PATH and returns its
+// stdout and stderr. A failed invocation is returned as a *CommandError.
+func (c *Gitsign) git(ctx context.Context, workdir string, signToken string, args ...string) ([]byte, []byte, error) {
+	return c.gitWith(ctx, workdir, signToken, nil, args...)
+}
+
+// gitWith is like git, with overrides added to the environment.
+func (c *Gitsign) gitWith(ctx context.Context, workdir string, signToken string, overrides []string, args ...string) ([]byte, []byte, error) {
+	env, err := c.gitEnv(signToken, overrides)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.runCmd(ctx, "git", args, func() *Cmd {
+		cmd := command(ctx, "git", "git", args...)
+		cmd.Env = env
+		cmd.Dir = workdir
+		return cmd
+	})
+}
+
+func (c *Gitsign) gitEnv(signToken string, extra []string) ([]string, error) {
 	gitPath, err := exec.LookPath("git")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var pathSeparator string
@@ -40,10 +296,9 @@ func (c *Gitsign) GitWithGitSign(ctx context.Context, workdir string, signToken
 		logrus.Fatal("Unsupported OS: " + runtime.GOOS)
 	}
 
-	cmd.Env = append(os.Environ(), "SIGSTORE_ID_TOKEN="+signToken, "PATH="+filepath.Dir(c.pathToCLI)+pathSeparator+filepath.Dir(gitPath)+pathSeparator+os.Getenv("PATH"))
-	cmd.Dir = workdir
-	cmd.Stdout = logrus.NewEntry(logrus.StandardLogger()).WithField("app", "git").WriterLevel(logrus.InfoLevel)
-	cmd.Stderr = logrus.NewEntry(logrus.StandardLogger()).WithField("app", "git").WriterLevel(logrus.ErrorLevel)
-
-	return cmd.Run()
+	overrides := []string{"PATH=" + filepath.Dir(c.pathToCLI) + pathSeparator + filepath.Dir(gitPath) + pathSeparator + os.Getenv("PATH")}
+	if signToken != "" {
+		overrides = append(overrides, "SIGSTORE_ID_TOKEN="+signToken)
+	}
+	return c.Env().Environ(append(overrides, extra...)...), nil
 }
@@ -0,0 +1,178 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const gitsignVerifyOutput = `tlog index: 1234567
+gitsign: Signature made using certificate ID 0x3f8a1c | CN=sigstore-intermediate,O=sigstore.dev
+gitsign: Good signature from [jdoe@redhat.com](https://keycloak.example.com/realms/trusted-artifact-signer)
+Validated Git signature: true
+Validated Rekor entry: true
+Validated Certificate claims: false
+WARNING: git verify-commit does not verify cert claims. Prefer using ` + "`gitsign verify`" + ` instead.
+`
+
+func TestParseGitsignVerifyOutput(t *testing.T) {
+	result := ParseGitsignVerifyOutput([]byte(gitsignVerifyOutput))
+
+	if result.LogIndex != 1234567 {
+		t.Errorf("log index = %d, want 1234567", result.LogIndex)
+	}
+	if result.CertificateIdentity != "jdoe@redhat.com" {
+		t.Errorf("identity = %q, want jdoe@redhat.com", result.CertificateIdentity)
+	}
+	if result.Issuer != "https://keycloak.example.com/realms/trusted-artifact-signer" {
+		t.Errorf("unexpected issuer %q", result.Issuer)
+	}
+	if !result.GitSignatureValid || !result.RekorEntryValid || result.CertificateClaimsValid {
+		t.Errorf("unexpected validation flags: %+v", result)
+	}
+}
+
+func TestParseGitsignVerifyOutputEmpty(t *testing.T) {
+	result := ParseGitsignVerifyOutput(nil)
+	if result.LogIndex != -1 || result.GitSignatureValid {
+		t.Fatalf("unexpected result for empty output: %+v", result)
+	}
+}
+
+func TestParseGitsignSignOutput(t *testing.T) {
+	tests := []struct {
+		file  string
+		index int64
+		uuid  string
+	}{
+		{file: "commit.txt", index: 168742391, uuid: "24296fb24b8ad77a8f3c1e5b0d7e9a2c4b6f8d0e1a3c5e7f9b2d4f6a8c0e2b4d6f8a1c3e5b7d9f0a"},
+		{file: "verify.txt", index: 168742391},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "gitsign", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ParseGitsignSignOutput(data)
+			if got.LogIndex != tt.index || got.UUID != tt.uuid {
+				t.Errorf("ParseGitsignSignOutput() = %+v, want index %d and UUID %q", got, tt.index, tt.uuid)
+			}
+		})
+	}
+}
+
+// fakeGitsign is a gitsign stand-in: called by git it reports a tlog entry to
+// GITSIGN_LOG and returns a dummy signature, called as 'gitsign attest' it
+// records its arguments and fails without a predicate file.
+const fakeGitsign = `#!/bin/sh
+if [ "$1" = attest ]; then
+	echo "$@" > attest-args
+	[ -f "$3" ] || { echo "error: open $3: no such file or directory" >&2; exit 1; }
+	exit 0
+fi
+cat > /dev/null
+echo "tlog entry created with index: 42" >> "$GITSIGN_LOG"
+echo "tlog entry uuid: 24296fb24b8ad77a0123456789abcdef" >> "$GITSIGN_LOG"
+printf '\n[GNUPG:] SIG_CREATED D 1 8 00 0 0\n' >&2
+echo "-----BEGIN SIGNED MESSAGE-----"
+echo "c2lnbmF0dXJl"
+echo "-----END SIGNED MESSAGE-----"
+`
+
+func newTestGitsign(t *testing.T) (*Gitsign, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	bin := filepath.Join(t.TempDir(), "gitsign")
+	if err := os.WriteFile(bin, []byte(fakeGitsign), 0700); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+	c := &Gitsign{&cli{
+		Name: "gitsign",
+		setupStrategy: func(_ context.Context, _ string) (string, error) {
+			return bin, nil
+		},
+	}}
+	if err := c.Setup(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Destroy(context.Background()) })
+
+	dir := t.TempDir()
+	if err := c.GitWithGitSign(context.Background(), dir, "", "init", "-q"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetupRepository(context.Background(), dir, GitsignRepoConfig{UserName: "CI", UserEmail: "ci@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	return c, dir
+}
+
+func TestGitsignCommitAndTag(t *testing.T) {
+	c, dir := newTestGitsign(t)
+	ctx := context.Background()
+	if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("content"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.GitWithGitSign(ctx, dir, "", "add", "file.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	commit, err := c.Commit(ctx, dir, "token", "signed commit")
+	if err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	tag, err := c.Tag(ctx, dir, "token", "v0.0.1", "signed tag")
+	if err != nil {
+		t.Fatalf("tag failed: %v", err)
+	}
+	for _, result := range []*GitsignSignResult{commit, tag} {
+		if result.LogIndex != 42 || result.UUID != "24296fb24b8ad77a0123456789abcdef" {
+			t.Errorf("unexpected sign result %+v", result)
+		}
+	}
+}
+
+func TestGitsignCommitFailure(t *testing.T) {
+	c, dir := newTestGitsign(t)
+	_, err := c.Commit(context.Background(), dir, "token", "nothing staged")
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("expected a *CommandError, got %v", err)
+	}
+	if cmdErr.Tool != "git" || cmdErr.Subcommand != "commit" || cmdErr.ExitCode != 1 {
+		t.Errorf("unexpected error %+v", cmdErr)
+	}
+}
+
+func TestGitsignAttest(t *testing.T) {
+	c, dir := newTestGitsign(t)
+	ctx := context.Background()
+	opts := GitsignAttestOptions{Path: "predicate.json", Type: "custom"}
+
+	err := c.Attest(ctx, dir, "token", opts)
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.Subcommand != "attest" || !strings.Contains(cmdErr.Stderr, "no such file") {
+		t.Fatalf("expected a *CommandError of attest, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, opts.Path), []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Attest(ctx, dir, "token", opts); err != nil {
+		t.Fatalf("attest failed: %v", err)
+	}
+	args, err := os.ReadFile(filepath.Join(dir, "attest-args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(args)); got != "attest -f predicate.json --type custom" {
+		t.Errorf("attest called with %q", got)
+	}
+}
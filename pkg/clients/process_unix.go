@@ -0,0 +1,33 @@
+//go:build !windows
+
+package clients
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// configureProcessGroup starts cmd in its own process group, so that on
+// cancellation helpers spawned by the CLI (e.g. gitsign run by git) are
+// terminated together with it: SIGTERM first, SIGKILL after TerminationGracePeriod.
+func configureProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		pgid := -cmd.Process.Pid
+		if err := syscall.Kill(pgid, syscall.SIGTERM); err != nil {
+			if errors.Is(err, syscall.ESRCH) {
+				return nil
+			}
+			return err
+		}
+		time.AfterFunc(TerminationGracePeriod, func() {
+			_ = syscall.Kill(pgid, syscall.SIGKILL)
+		})
+		return nil
+	}
+	// Stop waiting for output of processes that survived SIGKILL of the group,
+	// e.g. because they started their own session.
+	cmd.WaitDelay = TerminationGracePeriod + time.Second
+}
@@ -0,0 +1,84 @@
+//go:build !windows
+
+package clients
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func processGone(pid int) bool {
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return errors.Is(syscall.Kill(pid, 0), syscall.ESRCH)
+	}
+	// a zombie waiting to be reaped by init is gone as well
+	fields := strings.Fields(string(stat))
+	return len(fields) > 2 && fields[2] == "Z"
+}
+
+func TestCancelTerminatesProcessGroup(t *testing.T) {
+	grace := TerminationGracePeriod
+	TerminationGracePeriod = 200 * time.Millisecond
+	t.Cleanup(func() { TerminationGracePeriod = grace })
+
+	tests := []struct {
+		name   string
+		script string
+		stdout string
+	}{
+		// the shell exits on SIGTERM and leaves the background child behind
+		{"graceful", "trap 'echo terminated; exit 0' TERM; sleep 30 & echo $! > \"$PID_FILE\"; wait", "terminated"},
+		// SIGTERM is ignored by the shell and its child, so SIGKILL is needed
+		{"ignoring SIGTERM", "trap '' TERM; sleep 30 & echo $! > \"$PID_FILE\"; wait", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			pidFile := t.TempDir() + "/pid"
+			cmd := command(ctx, "sh", "/bin/sh", "-c", tt.script)
+			cmd.Stdout = nil
+			cmd.Env = append(os.Environ(), "PID_FILE="+pidFile)
+			if err := cmd.Start(); err != nil {
+				t.Fatal(err)
+			}
+
+			var child int
+			deadline := time.Now().Add(5 * time.Second)
+			for child == 0 && time.Now().Before(deadline) {
+				if data, err := os.ReadFile(pidFile); err == nil {
+					child, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if child == 0 {
+				t.Fatal("background child was not started")
+			}
+
+			start := time.Now()
+			cancel()
+			_ = cmd.Wait()
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Fatalf("command took %v to stop", elapsed)
+			}
+			if !strings.Contains(string(cmd.StdoutBytes()), tt.stdout) {
+				t.Errorf("unexpected output %q", cmd.StdoutBytes())
+			}
+
+			deadline = time.Now().Add(2 * time.Second)
+			for !processGone(child) && time.Now().Before(deadline) {
+				time.Sleep(20 * time.Millisecond)
+			}
+			if !processGone(child) {
+				_ = syscall.Kill(child, syscall.SIGKILL)
+				t.Fatalf("grandchild %d survived the cancellation", child)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+//go:build windows
+
+package clients
+
+import (
+	"os/exec"
+)
+
+// configureProcessGroup only bounds the wait for output on Windows; the
+// process itself is killed on cancellation.
+func configureProcessGroup(cmd *exec.Cmd) {
+	cmd.WaitDelay = TerminationGracePeriod
+}
@@ -1,14 +1,231 @@
 package clients
 
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/securesign/sigstore-e2e/pkg/api"
+)
+
 type RekorCli struct {
 	*cli
+	// Server is the Rekor URL passed as --rekor_server. REKOR_URL is used when empty.
+	Server string
 }
 
 func NewRekorCli() *RekorCli {
 	return &RekorCli{
-		&cli{
+		cli: &cli{
 			Name:           "rekor-cli",
 			setupStrategy:  PreferredSetupStrategy(),
 			versionCommand: "version",
+			retryPolicies: map[string]*RetryPolicy{
+				"upload":   SubmissionRetryPolicy,
+				"get":      NetworkRetryPolicy,
+				"verify":   NetworkRetryPolicy,
+				"search":   NetworkRetryPolicy,
+				"loginfo":  NetworkRetryPolicy,
+				"logproof": NetworkRetryPolicy,
+			},
 		}}
 }
+
+// RekorArtifactOptions describes the artifact and signature material shared by upload, verify and search.
+type RekorArtifactOptions struct {
+	Artifact     string
+	ArtifactHash string
+	Signature    string
+	PublicKey    string
+	PKIFormat    string
+	Type         string
+}
+
+func (o RekorArtifactOptions) flags() []string {
+	var args []string
+	if o.Artifact != "" {
+		args = append(args, "--artifact", o.Artifact)
+	}
+	if o.ArtifactHash != "" {
+		args = append(args, "--artifact-hash", o.ArtifactHash)
+	}
+	if o.Signature != "" {
+		args = append(args, "--signature", o.Signature)
+	}
+	if o.PublicKey != "" {
+		args = append(args, "--public-key", o.PublicKey)
+	}
+	if o.PKIFormat != "" {
+		args = append(args, "--pki-format", o.PKIFormat)
+	}
+	if o.Type != "" {
+		args = append(args, "--type", o.Type)
+	}
+	return args
+}
+
+// RekorEntryRef selects a log entry by UUID or, when UUID is empty, by log index.
+type RekorEntryRef struct {
+	UUID     string
+	LogIndex int64
+}
+
+func (r RekorEntryRef) flags() []string {
+	if r.UUID != "" {
+		return []string{"--uuid", r.UUID}
+	}
+	return []string{"--log-index", strconv.FormatInt(r.LogIndex, 10)}
+}
+
+type RekorSearchOptions struct {
+	Artifact  string
+	SHA       string
+	PublicKey string
+	PKIFormat string
+	Email     string
+}
+
+func (o RekorSearchOptions) flags() []string {
+	var args []string
+	if o.Artifact != "" {
+		args = append(args, "--artifact", o.Artifact)
+	}
+	if o.SHA != "" {
+		args = append(args, "--sha", o.SHA)
+	}
+	if o.PublicKey != "" {
+		args = append(args, "--public-key", o.PublicKey)
+	}
+	if o.PKIFormat != "" {
+		args = append(args, "--pki-format", o.PKIFormat)
+	}
+	if o.Email != "" {
+		args = append(args, "--email", o.Email)
+	}
+	return args
+}
+
+func (c *RekorCli) Upload(ctx context.Context, opts RekorArtifactOptions) (*RekorUploadResult, error) {
+	return runRekor[RekorUploadResult](ctx, c, "upload", opts.flags()...)
+}
+
+// Get retrieves a log entry. The entry body is decoded into RekorEntry.Body.
+func (c *RekorCli) Get(ctx context.Context, ref RekorEntryRef) (*RekorEntry, error) {
+	return runRekor[RekorEntry](ctx, c, "get", ref.flags()...)
+}
+
+// Verify checks the inclusion of an entry, identified either by its artifact
+// material or by ref when opts is empty.
+func (c *RekorCli) Verify(ctx context.Context, opts RekorArtifactOptions, ref *RekorEntryRef) (*RekorVerifyResult, error) {
+	args := opts.flags()
+	if ref != nil {
+		args = append(args, ref.flags()...)
+	}
+	return runRekor[RekorVerifyResult](ctx, c, "verify", args...)
+}
+
+// Search returns the UUIDs of entries matching opts.
+func (c *RekorCli) Search(ctx context.Context, opts RekorSearchOptions) ([]string, error) {
+	result, err := runRekor[struct{ UUIDs []string }](ctx, c, "search", opts.flags()...)
+	if err != nil {
+		return nil, err
+	}
+	return result.UUIDs, nil
+}
+
+func (c *RekorCli) LogInfo(ctx context.Context) (*RekorLogInfo, error) {
+	return runRekor[RekorLogInfo](ctx, c, "loginfo")
+}
+
+// LogProof returns the consistency proof between two tree sizes.
+func (c *RekorCli) LogProof(ctx context.Context, firstSize, lastSize int64) (*RekorLogProof, error) {
+	return runRekor[RekorLogProof](ctx, c, "logproof",
+		"--first-size", strconv.FormatInt(firstSize, 10),
+		"--last-size", strconv.FormatInt(lastSize, 10))
+}
+
+func (c *RekorCli) server() string {
+	if c.Server != "" {
+		return c.Server
+	}
+	return api.GetValueFor(api.RekorURL)
+}
+
+func runRekor[T any](ctx context.Context, c *RekorCli, command string, flags ...string) (*T, error) {
+	args := append([]string{command, "--rekor_server", c.server(), "--format", "json"}, flags...)
+	stdout, _, err := c.run(ctx, args...)
+	if err != nil {
+		var cmdErr *CommandError
+		if errors.As(err, &cmdErr) {
+			return nil, parseRekorError(cmdErr)
+		}
+		return nil, err
+	}
+	return parseRekorOutput[T](stdout)
+}
+
+// parseRekorOutput decodes the JSON document printed by rekor-cli with --format json.
+// Any text before the document (e.g. warnings) is ignored.
+func parseRekorOutput[T any](data []byte) (*T, error) {
+	start := bytes.IndexByte(data, '{')
+	if start < 0 {
+		return nil, errors.New("no JSON object in rekor-cli output")
+	}
+	var out T
+	if err := json.NewDecoder(bytes.NewReader(data[start:])).Decode(&out); err != nil {
+		return nil, fmt.Errorf("cannot parse rekor-cli output: %w", err)
+	}
+	return &out, nil
+}
+
+// RekorError is a failed rekor-cli invocation caused by an error response of the Rekor API.
+type RekorError struct {
+	*CommandError
+	Method     string
+	Path       string
+	StatusCode int
+	Operation  string
+	Message    string
+}
+
+func (e *RekorError) Error() string {
+	msg := fmt.Sprintf("rekor %s %s returned %d (%s)", e.Method, e.Path, e.StatusCode, e.Operation)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+func (e *RekorError) Unwrap() error {
+	return e.CommandError
+}
+
+var (
+	rekorErrorRegexp   = regexp.MustCompile(`\[(\w+) ([^\]]+)\]\[(\d{3})\] (\w+)`)
+	rekorMessageRegexp = regexp.MustCompile(`Message:\s*([^}]*)`)
+)
+
+// parseRekorError turns the go-swagger error printed by rekor-cli into a
+// *RekorError. The original error is returned when stderr has no API error.
+func parseRekorError(err *CommandError) error {
+	m := rekorErrorRegexp.FindStringSubmatch(err.Stderr)
+	if m == nil {
+		return err
+	}
+	status, _ := strconv.Atoi(m[3])
+	rekorErr := &RekorError{
+		CommandError: err,
+		Method:       m[1],
+		Path:         m[2],
+		StatusCode:   status,
+		Operation:    m[4],
+	}
+	if msg := rekorMessageRegexp.FindStringSubmatch(err.Stderr); msg != nil {
+		rekorErr.Message = msg[1]
+	}
+	return rekorErr
+}
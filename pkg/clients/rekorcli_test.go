@@ -0,0 +1,129 @@
+package clients
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func readRekorTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "rekor-cli", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseRekorUpload(t *testing.T) {
+	out, err := parseRekorOutput[RekorUploadResult](readRekorTestdata(t, "upload.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.Index != 12345 || out.AlreadyExists {
+		t.Fatalf("unexpected upload result: %+v", out)
+	}
+}
+
+func TestParseRekorVerify(t *testing.T) {
+	out, err := parseRekorOutput[RekorVerifyResult](readRekorTestdata(t, "verify.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.Index != 12345 || out.Size != 12400 || len(out.Hashes) != 2 {
+		t.Fatalf("unexpected verify result: %+v", out)
+	}
+	if out.EntryUUID != "24296fb24b8ad77a1ad7e9a9b9b8e2e4b9b0d0e0f6a9f1d5c1e7c3b4a9d8e7f60" {
+		t.Fatalf("unexpected entry UUID %q", out.EntryUUID)
+	}
+}
+
+func TestParseRekorGet(t *testing.T) {
+	tests := []struct {
+		file      string
+		entryType string
+		hash      string
+		signature string
+		logIndex  int64
+	}{
+		{"get_hashedrekord.json", RekorTypeHashedRekord, "8f434346648f6b96df89dda901c5176b10a6d83961dd3c1ac88b59b2dc327aa4", "MEUCIQDx", 12345},
+		{"get_dsse.json", RekorTypeDSSE, "bbbb", "MEQCIA==", 42},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			out, err := parseRekorOutput[RekorEntry](readRekorTestdata(t, tt.file))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.LogIndex != tt.logIndex {
+				t.Errorf("log index = %d, want %d", out.LogIndex, tt.logIndex)
+			}
+			if got := out.Body.Type(); got != tt.entryType {
+				t.Errorf("type = %q, want %q", got, tt.entryType)
+			}
+			if got := out.Body.Hash().Value; got != tt.hash {
+				t.Errorf("hash = %q, want %q", got, tt.hash)
+			}
+			if sig, key := out.Body.Signature(); sig != tt.signature || key == "" {
+				t.Errorf("signature = %q (key %q), want %q", sig, key, tt.signature)
+			}
+		})
+	}
+}
+
+func TestParseRekorSearch(t *testing.T) {
+	out, err := parseRekorOutput[struct{ UUIDs []string }](readRekorTestdata(t, "search.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Contains(out.UUIDs, "108e9186e8c5677a") || len(out.UUIDs) != 2 {
+		t.Fatalf("unexpected UUIDs: %v", out.UUIDs)
+	}
+}
+
+func TestParseRekorLogInfoAndProof(t *testing.T) {
+	info, err := parseRekorOutput[RekorLogInfo](readRekorTestdata(t, "loginfo.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.TreeID != "1193050959916656506" || info.ActiveTreeSize != 12400 {
+		t.Fatalf("unexpected loginfo: %+v", info)
+	}
+
+	proof, err := parseRekorOutput[RekorLogProof](readRekorTestdata(t, "logproof.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if proof.RootHash != info.RootHash || len(proof.Hashes) != 2 {
+		t.Fatalf("unexpected logproof: %+v", proof)
+	}
+}
+
+func TestParseRekorOutputNoJSON(t *testing.T) {
+	if _, err := parseRekorOutput[RekorLogInfo]([]byte("Current Tree Size: 1\n")); err == nil {
+		t.Fatal("expected error for non-JSON output")
+	}
+}
+
+func TestParseRekorError(t *testing.T) {
+	cmdErr := &CommandError{Tool: "rekor-cli", Subcommand: "get", ExitCode: 1, Stderr: string(readRekorTestdata(t, "get_not_found.txt"))}
+	err := parseRekorError(cmdErr)
+
+	var rekorErr *RekorError
+	if !errors.As(err, &rekorErr) {
+		t.Fatalf("expected *RekorError, got %T: %v", err, err)
+	}
+	if rekorErr.StatusCode != 404 || rekorErr.Method != "GET" || rekorErr.Operation != "getLogEntryByUuidNotFound" || rekorErr.Message != "entry not found" {
+		t.Fatalf("unexpected rekor error: %+v", rekorErr)
+	}
+	if !errors.Is(err, cmdErr) {
+		t.Fatal("expected RekorError to wrap the command error")
+	}
+
+	plain := &CommandError{Tool: "rekor-cli", Subcommand: "get", ExitCode: 1, Stderr: "Error: connection refused"}
+	if err := parseRekorError(plain); !errors.Is(err, plain) || errors.As(err, &rekorErr) {
+		t.Fatalf("expected plain command error, got %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package clients
+
+// The types below mirror the JSON documents printed by rekor-cli with --format json.
+
+type RekorUploadResult struct {
+	AlreadyExists bool
+	Location      string
+	Index         int64
+}
+
+type RekorVerifyResult struct {
+	RootHash  string
+	EntryUUID string
+	Index     int64
+	Size      int64
+	Hashes    []string
+}
+
+type RekorLogInfo struct {
+	ActiveTreeSize int64
+	TotalTreeSize  int64
+	RootHash       string
+	TreeID         string
+}
+
+type RekorLogProof struct {
+	Hashes   []string
+	RootHash string
+	TreeSize int64
+}
+
+type RekorEntry struct {
+	Attestation     string
+	AttestationType string
+	Body            RekorEntryBody
+	LogIndex        int64
+	IntegratedTime  int64
+	UUID            string
+	LogID           string
+}
+
+type RekorHash struct {
+	Algorithm string `json:"algorithm"`
+	Value     string `json:"value"`
+}
+
+type rekorSignedData struct {
+	Data struct {
+		Hash RekorHash `json:"hash"`
+	} `json:"data"`
+	Signature struct {
+		Content   string `json:"content"`
+		PublicKey struct {
+			Content string `json:"content"`
+		} `json:"publicKey"`
+	} `json:"signature"`
+}
+
+// RekorEntryBody is the decoded body of a log entry. Exactly one of the
+// objects is populated, depending on the entry type.
+type RekorEntryBody struct {
+	HashedRekordObj rekorSignedData `json:"HashedRekordObj"`
+	RekordObj       rekorSignedData `json:"RekordObj"`
+	DSSEObj         struct {
+		EnvelopeHash RekorHash `json:"envelopeHash"`
+		PayloadHash  RekorHash `json:"payloadHash"`
+		Signatures   []struct {
+			Signature string `json:"signature"`
+			Verifier  string `json:"verifier"`
+		} `json:"signatures"`
+	} `json:"DSSEObj"`
+}
+
+const (
+	RekorTypeHashedRekord = "hashedrekord:0.0.1"
+	RekorTypeRekord       = "rekord:0.0.1"
+	RekorTypeDSSE         = "dsse:0.0.1"
+)
+
+// Type returns the entry type in the form accepted by 'rekor-cli --type', or "" if unknown.
+func (b *RekorEntryBody) Type() string {
+	switch {
+	case len(b.DSSEObj.Signatures) > 0:
+		return RekorTypeDSSE
+	case b.HashedRekordObj.Signature.Content != "":
+		return RekorTypeHashedRekord
+	case b.RekordObj.Signature.Content != "":
+		return RekorTypeRekord
+	}
+	return ""
+}
+
+// Signature returns the base64 encoded signature and public key (or certificate) of the entry.
+func (b *RekorEntryBody) Signature() (signature string, publicKey string) {
+	switch b.Type() {
+	case RekorTypeDSSE:
+		return b.DSSEObj.Signatures[0].Signature, b.DSSEObj.Signatures[0].Verifier
+	case RekorTypeHashedRekord:
+		return b.HashedRekordObj.Signature.Content, b.HashedRekordObj.Signature.PublicKey.Content
+	case RekorTypeRekord:
+		return b.RekordObj.Signature.Content, b.RekordObj.Signature.PublicKey.Content
+	}
+	return "", ""
+}
+
+// Hash returns the artifact hash recorded in the entry (the payload hash for DSSE entries).
+func (b *RekorEntryBody) Hash() RekorHash {
+	switch b.Type() {
+	case RekorTypeDSSE:
+		return b.DSSEObj.PayloadHash
+	case RekorTypeHashedRekord:
+		return b.HashedRekordObj.Data.Hash
+	case RekorTypeRekord:
+		return b.RekordObj.Data.Hash
+	}
+	return RekorHash{}
+}
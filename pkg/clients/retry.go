@@ -0,0 +1,147 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// connectErrors are failures to reach a service at all: the request was
+	// never processed, so even non-idempotent commands can be run again.
+	connectErrors = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)connection refused`),
+		regexp.MustCompile(`(?i)no such host`),
+		regexp.MustCompile(`(?i)\b429 Too Many Requests\b`),
+		regexp.MustCompile(`\]\[429\]`),
+		regexp.MustCompile(`(?i)status code:? 429\b`),
+	}
+	// networkErrors are failures of a service that is still starting or briefly unreachable.
+	networkErrors = append([]*regexp.Regexp{
+		regexp.MustCompile(`(?i)connection reset by peer`),
+		regexp.MustCompile(`(?i)i/o timeout`),
+		regexp.MustCompile(`(?i)TLS handshake timeout`),
+		regexp.MustCompile(`(?i)Client\.Timeout exceeded`),
+		regexp.MustCompile(`(?i)\b(502 Bad Gateway|503 Service Unavailable|504 Gateway Timeout)\b`),
+		regexp.MustCompile(`\]\[(502|503|504)\]`),
+		regexp.MustCompile(`(?i)status code:? (502|503|504)\b`),
+	}, connectErrors...)
+	// tufErrors are failures of a TUF client whose local cache is updated
+	// concurrently (the atomic rename of the metadata directory can race), or
+	// of a TUF repository that is not published yet: the route answers 404 for
+	// the top-level metadata or the router's HTML error page instead of metadata.
+	tufErrors = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)rename .*: (file exists|directory not empty)`),
+		regexp.MustCompile(`(?i)\b(root|timestamp)\.json\b.*(\b404 Not Found\b|status code:? 404\b)`),
+		regexp.MustCompile(`(?i)Application is not available`),
+		regexp.MustCompile(`invalid character '<' looking for beginning of value`),
+	}
+	// registryErrors are failures caused by an image or signature that the registry has not published yet.
+	registryErrors = []*regexp.Regexp{
+		regexp.MustCompile(`MANIFEST_UNKNOWN`),
+		regexp.MustCompile(`NAME_UNKNOWN`),
+		regexp.MustCompile(`(?i)no signatures found`),
+	}
+)
+
+// RetryPolicy decides whether a failed command is retried and how long to
+// wait in between. Only failures whose stderr matches one of the Transient
+// patterns are retried; every other failure is returned immediately.
+type RetryPolicy struct {
+	Name            string
+	MaxElapsed      time.Duration
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	Transient       []*regexp.Regexp
+}
+
+var (
+	// NetworkRetryPolicy retries commands while a service is unreachable.
+	NetworkRetryPolicy = &RetryPolicy{
+		Name:            "network",
+		MaxElapsed:      2 * time.Minute,
+		InitialInterval: 2 * time.Second,
+		MaxInterval:     15 * time.Second,
+		Multiplier:      2,
+		Transient:       networkErrors,
+	}
+	// SubmissionRetryPolicy retries commands that create log entries or
+	// certificates (sign, attest, upload) only while a service cannot be
+	// reached, so a retry never submits the same entry twice.
+	SubmissionRetryPolicy = &RetryPolicy{
+		Name:            "submission",
+		MaxElapsed:      2 * time.Minute,
+		InitialInterval: 2 * time.Second,
+		MaxInterval:     15 * time.Second,
+		Multiplier:      2,
+		Transient:       connectErrors,
+	}
+	// TUFRetryPolicy retries TUF initialization, e.g. while the TUF server returns 503.
+	TUFRetryPolicy = &RetryPolicy{
+		Name:            "tuf",
+		MaxElapsed:      2 * time.Minute,
+		InitialInterval: 3 * time.Second,
+		MaxInterval:     15 * time.Second,
+		Multiplier:      2,
+		Transient:       append(append([]*regexp.Regexp{}, networkErrors...), tufErrors...),
+	}
+	// RegistryRetryPolicy retries registry reads until pushed signatures are visible.
+	RegistryRetryPolicy = &RetryPolicy{
+		Name:            "registry",
+		MaxElapsed:      time.Minute,
+		InitialInterval: 2 * time.Second,
+		MaxInterval:     10 * time.Second,
+		Multiplier:      2,
+		Transient:       append(append([]*regexp.Regexp{}, networkErrors...), registryErrors...),
+	}
+)
+
+// IsTransient reports whether err is worth retrying under this policy.
+func (p *RetryPolicy) IsTransient(err error) bool {
+	if p == nil || err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		return false
+	}
+	for _, re := range p.Transient {
+		if re.MatchString(cmdErr.Stderr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Do runs op until it succeeds, fails with a non-transient error, or MaxElapsed
+// has passed. A nil policy runs op once.
+func (p *RetryPolicy) Do(ctx context.Context, op func() error) error {
+	start := time.Now()
+	err := op()
+	if p == nil {
+		return err
+	}
+	interval := p.InitialInterval
+	for attempt := 1; p.IsTransient(err); attempt++ {
+		if time.Since(start)+interval > p.MaxElapsed {
+			return fmt.Errorf("%w (gave up after %d attempts in %s)", err, attempt, time.Since(start).Round(time.Second))
+		}
+		logrus.Warnf("Transient failure, retrying in %s (%s policy, attempt %d): %v", interval, p.Name, attempt, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(interval):
+		}
+		interval = time.Duration(float64(interval) * p.Multiplier)
+		if interval > p.MaxInterval {
+			interval = p.MaxInterval
+		}
+		err = op()
+	}
+	return err
+}
@@ -0,0 +1,125 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyIsTransient(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    *RetryPolicy
+		stderr    string
+		transient bool
+	}{
+		{"tuf 503", TUFRetryPolicy, "Error: initializing tuf: failed to download timestamp.json: 503 Service Unavailable", true},
+		{"tuf rename race", TUFRetryPolicy, "rename /tmp/tuf-123 /home/.sigstore/root: file exists", true},
+		{"tuf not published", TUFRetryPolicy, "Error: initializing tuf: failed to download root.json: 404 Not Found", true},
+		{"tuf timestamp not published", TUFRetryPolicy, "failed to download https://tuf/timestamp.json, http status code: 404", true},
+		{"tuf other 404", TUFRetryPolicy, "[GET /api/v1/log/entries/abc][404] getLogEntryByUuidNotFound", false},
+		{"tuf wrong url", TUFRetryPolicy, "Get \"https://rekor/api/v1/log\": 404 Not Found", false},
+		{"tuf router page", TUFRetryPolicy, "Error: invalid character '<' looking for beginning of value", true},
+		{"sign connection refused", SubmissionRetryPolicy, "Post \"https://fulcio/api/v2/signingCert\": dial tcp: connect: connection refused", true},
+		{"sign rate limited", SubmissionRetryPolicy, "[POST /api/v1/log/entries][429] createLogEntry default", true},
+		{"sign timeout", SubmissionRetryPolicy, "Post \"https://rekor/api/v1/log/entries\": i/o timeout", false},
+		{"sign gateway error", SubmissionRetryPolicy, "[POST /api/v1/log/entries][502] createLogEntry default", false},
+		{"connection refused", NetworkRetryPolicy, "dial tcp 10.0.0.1:443: connect: connection refused", true},
+		{"rekor 502", NetworkRetryPolicy, "[GET /api/v1/log/entries][502] getLogEntryByIndex default", true},
+		{"registry propagation", RegistryRetryPolicy, "Error: no signatures found", true},
+		{"manifest unknown", RegistryRetryPolicy, "MANIFEST_UNKNOWN: manifest unknown", true},
+		{"registry error under network policy", NetworkRetryPolicy, "MANIFEST_UNKNOWN: manifest unknown", false},
+		{"identity mismatch", RegistryRetryPolicy, "Error: no matching signatures: expected identity not found", false},
+		{"rekor not found", NetworkRetryPolicy, "[GET /api/v1/log/entries][404] getLogEntryByIndexNotFound", false},
+		{"no policy", nil, "connection refused", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := &CommandError{Tool: "cosign", ExitCode: 1, Stderr: tt.stderr}
+			if got := tt.policy.IsTransient(err); got != tt.transient {
+				t.Errorf("IsTransient = %v, want %v", got, tt.transient)
+			}
+		})
+	}
+}
+
+func testPolicy(maxElapsed time.Duration) *RetryPolicy {
+	return &RetryPolicy{
+		Name:            "test",
+		MaxElapsed:      maxElapsed,
+		InitialInterval: time.Millisecond,
+		MaxInterval:     2 * time.Millisecond,
+		Multiplier:      2,
+		Transient:       networkErrors,
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	transient := &CommandError{Tool: "rekor-cli", ExitCode: 1, Stderr: "connection refused"}
+	fatal := &CommandError{Tool: "rekor-cli", ExitCode: 1, Stderr: "invalid signature"}
+
+	tests := []struct {
+		name     string
+		policy   *RetryPolicy
+		errs     []error
+		attempts int
+		fails    bool
+	}{
+		{"succeeds after transient failures", testPolicy(time.Second), []error{transient, transient, nil}, 3, false},
+		{"fatal failure is not retried", testPolicy(time.Second), []error{fatal, nil}, 1, true},
+		{"transient then fatal", testPolicy(time.Second), []error{transient, fatal, nil}, 2, true},
+		{"nil policy runs once", nil, []error{transient, nil}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := tt.policy.Do(context.Background(), func() error {
+				attempts++
+				return tt.errs[attempts-1]
+			})
+			if attempts != tt.attempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.attempts)
+			}
+			if (err != nil) != tt.fails {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyGivesUp(t *testing.T) {
+	transient := &CommandError{Tool: "cosign", ExitCode: 1, Stderr: "i/o timeout"}
+	attempts := 0
+	err := testPolicy(20*time.Millisecond).Do(context.Background(), func() error {
+		attempts++
+		return transient
+	})
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("expected the last command error, got %v", err)
+	}
+	if attempts < 2 {
+		t.Errorf("expected several attempts, got %d", attempts)
+	}
+}
+
+func TestRetryPolicyLookup(t *testing.T) {
+	c := NewCosign()
+	if c.RetryPolicy("download signature") != RegistryRetryPolicy {
+		t.Error("expected registry policy for 'download signature'")
+	}
+	if c.RetryPolicy("tree registry.local/image") != RegistryRetryPolicy {
+		t.Error("expected registry policy for 'tree'")
+	}
+	c.SetRetryPolicy("verify", nil)
+	if c.RetryPolicy("verify") != nil {
+		t.Error("expected retries of 'verify' to be disabled")
+	}
+	if c.RetryPolicy("clean") != nil {
+		t.Error("expected no policy for 'clean'")
+	}
+	if g := NewGitsign(); g.RetryPolicy("verify") == TUFRetryPolicy || g.RetryPolicy("verify-tag") == TUFRetryPolicy {
+		t.Error("expected the TUF policy to be limited to 'initialize'")
+	}
+}
@@ -9,4 +9,5 @@ import (
 	_ "github.com/securesign/sigstore-e2e/pkg/strategy/git"
 	_ "github.com/securesign/sigstore-e2e/pkg/strategy/local"
 	_ "github.com/securesign/sigstore-e2e/pkg/strategy/openshift"
+	_ "github.com/securesign/sigstore-e2e/pkg/strategy/replay"
 )
@@ -0,0 +1,244 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/securesign/sigstore-e2e/pkg/redact"
+	"github.com/sirupsen/logrus"
+)
+
+// Invocation is the transcript record of a single CLI command.
+type Invocation struct {
+	Tool       string `json:"tool"`
+	Subcommand string `json:"subcommand"`
+	// Args are the command arguments with secret values masked.
+	Args []string `json:"args"`
+	// Env holds the variables that differ from the test process environment.
+	// It is nil when the command inherited the environment unchanged.
+	Env      map[string]string `json:"env,omitempty"`
+	Dir      string            `json:"dir,omitempty"`
+	Start    time.Time         `json:"start"`
+	Duration time.Duration     `json:"duration"`
+	// ExitCode is -1 when the command could not be started or was killed.
+	ExitCode int    `json:"exitCode"`
+	Error    string `json:"error,omitempty"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	// Usage is nil when the command could not be started.
+	Usage *ResourceUsage `json:"usage,omitempty"`
+}
+
+// Command returns the tool and subcommand, e.g. "cosign verify".
+func (inv Invocation) Command() string {
+	return strings.TrimSpace(inv.Tool + " " + inv.Subcommand)
+}
+
+// InvocationObserver receives every finished CLI command.
+type InvocationObserver func(Invocation)
+
+type observer struct {
+	id int
+	fn InvocationObserver
+}
+
+var (
+	observersMu sync.RWMutex
+	observers   []observer
+	observerID  int
+)
+
+// ObserveInvocations registers o for all subsequent commands and returns a
+// function that unregisters it. Observers are called in registration order.
+func ObserveInvocations(o InvocationObserver) func() {
+	observersMu.Lock()
+	defer observersMu.Unlock()
+	observerID++
+	id := observerID
+	observers = append(observers, observer{id: id, fn: o})
+	return func() {
+		observersMu.Lock()
+		defer observersMu.Unlock()
+		observers = slices.DeleteFunc(observers, func(o observer) bool { return o.id == id })
+	}
+}
+
+// notifyObservers calls every observer, even if one of them panics (e.g. an
+// observer failing the running Ginkgo spec). The first panic is re-raised afterwards.
+func notifyObservers(inv Invocation) {
+	observersMu.RLock()
+	current := slices.Clone(observers)
+	observersMu.RUnlock()
+
+	var panicked any
+	for _, o := range current {
+		func() {
+			defer func() {
+				if r := recover(); r != nil && panicked == nil {
+					panicked = r
+				}
+			}()
+			o.fn(inv)
+		}()
+	}
+	if panicked != nil {
+		panic(panicked)
+	}
+}
+
+// TerminationGracePeriod is how long a cancelled command may take to exit
+// after SIGTERM before it is killed.
+var TerminationGracePeriod = 10 * time.Second
+
+// Cmd is an exec.Cmd that records its stdout and stderr and reports an
+// Invocation to the observers once it finishes. It is used like exec.Cmd.
+type Cmd struct {
+	*exec.Cmd
+	tool   string
+	args   []string
+	start  time.Time
+	stdout bytes.Buffer
+	stderr bytes.Buffer
+}
+
+// command prepares tool at path with its output streamed to the log.
+func command(ctx context.Context, tool string, path string, args ...string) *Cmd {
+	cmd := exec.CommandContext(ctx, path, args...) // #nosec G204 - we don't expect the code to be running on PROD ENV
+	configureProcessGroup(cmd)
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithField("app", tool)
+	cmd.Stdout = entry.WriterLevel(logrus.InfoLevel)
+	cmd.Stderr = entry.WithField("stream", "stderr").WriterLevel(logrus.InfoLevel)
+	return &Cmd{Cmd: cmd, tool: tool, args: args}
+}
+
+func (c *Cmd) Start() error {
+	c.stdout.Reset()
+	c.stderr.Reset()
+	c.Cmd.Stdout = tee(c.Cmd.Stdout, &c.stdout)
+	c.Cmd.Stderr = tee(c.Cmd.Stderr, &c.stderr)
+	c.start = time.Now()
+	if err := c.Cmd.Start(); err != nil {
+		c.record(err)
+		return err
+	}
+	return nil
+}
+
+func (c *Cmd) Wait() error {
+	err := c.Cmd.Wait()
+	c.record(err)
+	return err
+}
+
+func (c *Cmd) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+// Output runs the command and returns its standard output, which is not logged.
+func (c *Cmd) Output() ([]byte, error) {
+	var out bytes.Buffer
+	c.Cmd.Stdout = &out
+	err := c.Run()
+	return out.Bytes(), err
+}
+
+// CombinedOutput runs the command and returns stdout and stderr interleaved, without logging them.
+func (c *Cmd) CombinedOutput() ([]byte, error) {
+	var out bytes.Buffer
+	c.Cmd.Stdout = &out
+	c.Cmd.Stderr = &out
+	err := c.Run()
+	return out.Bytes(), err
+}
+
+// StdoutBytes returns what the command wrote to its standard output.
+func (c *Cmd) StdoutBytes() []byte {
+	return c.stdout.Bytes()
+}
+
+// StderrBytes returns what the command wrote to its standard error.
+func (c *Cmd) StderrBytes() []byte {
+	return c.stderr.Bytes()
+}
+
+func (c *Cmd) record(err error) {
+	wall := time.Since(c.start)
+	inv := Invocation{
+		Tool:       c.tool,
+		Subcommand: subcommand(c.args),
+		Args:       redact.Strings(redactArgs(c.args)),
+		Env:        envDelta(c.Cmd.Env),
+		Dir:        c.Cmd.Dir,
+		Start:      c.start,
+		Duration:   wall,
+		ExitCode:   -1,
+		Stdout:     redact.String(c.stdout.String()),
+		Stderr:     redact.String(c.stderr.String()),
+		Usage:      usageOf(c.Cmd.ProcessState, wall),
+	}
+	if c.Cmd.ProcessState != nil {
+		inv.ExitCode = c.Cmd.ProcessState.ExitCode()
+	}
+	if err != nil {
+		inv.Error = redact.String(err.Error())
+	}
+	notifyObservers(inv)
+}
+
+func tee(w io.Writer, buf *bytes.Buffer) io.Writer {
+	if w == nil {
+		return buf
+	}
+	return io.MultiWriter(w, buf)
+}
+
+// envDelta returns the variables of env that are not set to the same value in the test process.
+func envDelta(env []string) map[string]string {
+	if env == nil {
+		return nil
+	}
+	delta := map[string]string{}
+	for _, kv := range env {
+		key, value, _ := strings.Cut(kv, "=")
+		if host, ok := os.LookupEnv(key); ok && host == value {
+			continue
+		}
+		if redact.IsSecretKey(key) {
+			value = redact.Mask
+		}
+		delta[key] = redact.String(value)
+	}
+	return delta
+}
+
+// secretFlags are flags whose value must never be recorded.
+var secretFlags = []string{"--identity-token", "--password", "--token"}
+
+func redactArgs(args []string) []string {
+	out := make([]string, len(args))
+	for i := 0; i < len(args); i++ {
+		out[i] = args[i]
+		for _, flag := range secretFlags {
+			if args[i] == flag && i+1 < len(args) {
+				i++
+				out[i] = redact.Mask
+				break
+			}
+			if strings.HasPrefix(args[i], flag+"=") {
+				out[i] = flag + "=" + redact.Mask
+				break
+			}
+		}
+	}
+	return out
+}
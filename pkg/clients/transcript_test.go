@@ -0,0 +1,88 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/securesign/sigstore-e2e/pkg/redact"
+)
+
+func TestInvocationTranscript(t *testing.T) {
+	var recorded []Invocation
+	stop := ObserveInvocations(func(inv Invocation) { recorded = append(recorded, inv) })
+	defer stop()
+
+	c := &cli{Name: "sh", pathToCLI: "/bin/sh"}
+	c.Env().Set("SIGSTORE_ID_TOKEN", "secret-token")
+	_, _, err := c.run(context.Background(), "-c", "echo out; echo err >&2; exit 3", "--identity-token", "secret-token")
+	if err == nil {
+		t.Fatal("expected the command to fail")
+	}
+	stop()
+	_ = c.Command(context.Background(), "-c", "true").Run()
+
+	if len(recorded) != 1 {
+		t.Fatalf("expected 1 invocation, got %d", len(recorded))
+	}
+	inv := recorded[0]
+	if inv.Tool != "sh" || inv.ExitCode != 3 {
+		t.Errorf("unexpected invocation %+v", inv)
+	}
+	if inv.Stdout != "out\n" || inv.Stderr != "err\n" {
+		t.Errorf("streams not separated: stdout=%q stderr=%q", inv.Stdout, inv.Stderr)
+	}
+	if slices.Contains(inv.Args, "secret-token") {
+		t.Errorf("token not masked in args %v", inv.Args)
+	}
+	if inv.Env["SIGSTORE_ID_TOKEN"] != redact.Mask {
+		t.Errorf("token not masked in env delta: %q", inv.Env["SIGSTORE_ID_TOKEN"])
+	}
+	if inv.Duration <= 0 || inv.Start.IsZero() {
+		t.Errorf("missing timing in %+v", inv)
+	}
+}
+
+func TestRedactArgs(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"sign", "--identity-token=abc", "img"}, []string{"sign", "--identity-token=" + redact.Mask, "img"}},
+		{[]string{"login", "--password", "abc", "--username", "u"}, []string{"login", "--password", redact.Mask, "--username", "u"}},
+		{[]string{"verify", "--token"}, []string{"verify", "--token"}},
+	}
+	for _, tt := range tests {
+		if got := redactArgs(tt.args); !slices.Equal(got, tt.expected) {
+			t.Errorf("redactArgs(%v) = %v, want %v", tt.args, got, tt.expected)
+		}
+	}
+}
+
+func TestInvocationOutputIsRedacted(t *testing.T) {
+	const jwt = "eyJhbGciOiJSUzI1NiJ9.eyJzdWIiOiJqZG9lIn0.c2ln"
+	var recorded []Invocation
+	stop := ObserveInvocations(func(inv Invocation) { recorded = append(recorded, inv) })
+	defer stop()
+
+	c := &cli{Name: "sh", pathToCLI: "/bin/sh"}
+	_, _, err := c.run(context.Background(), "-c", "echo "+jwt+"; echo token="+jwt+" >&2; exit 1")
+	if err == nil {
+		t.Fatal("expected the command to fail")
+	}
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) || strings.Contains(cmdErr.Stderr, jwt) || strings.Contains(err.Error(), jwt) {
+		t.Errorf("token leaked into the error: %v", err)
+	}
+	if len(recorded) != 1 {
+		t.Fatalf("expected 1 invocation, got %d", len(recorded))
+	}
+	for _, s := range append(recorded[0].Args, recorded[0].Stdout, recorded[0].Stderr) {
+		if strings.Contains(s, jwt) {
+			t.Errorf("token leaked into the transcript: %q", s)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package clients
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ResourceUsage is what a single CLI invocation consumed.
+type ResourceUsage struct {
+	WallTime  time.Duration `json:"wallTime"`
+	UserCPU   time.Duration `json:"userCPU"`
+	SystemCPU time.Duration `json:"systemCPU"`
+	// MaxRSS is the peak resident set size in bytes, or 0 when the platform does not report it.
+	MaxRSS int64 `json:"maxRSS"`
+}
+
+func (u ResourceUsage) CPU() time.Duration {
+	return u.UserCPU + u.SystemCPU
+}
+
+func usageOf(state *os.ProcessState, wall time.Duration) *ResourceUsage {
+	if state == nil {
+		return nil
+	}
+	return &ResourceUsage{
+		WallTime:  wall,
+		UserCPU:   state.UserTime(),
+		SystemCPU: state.SystemTime(),
+		MaxRSS:    maxRSS(state),
+	}
+}
+
+// UsageThreshold limits the resources of a command. Zero fields are not checked.
+type UsageThreshold struct {
+	MaxWallTime time.Duration
+	MaxCPU      time.Duration
+	MaxRSS      int64
+}
+
+// Check returns an error describing every limit u exceeds.
+func (t UsageThreshold) Check(u ResourceUsage) error {
+	var errs []error
+	if t.MaxWallTime > 0 && u.WallTime > t.MaxWallTime {
+		errs = append(errs, fmt.Errorf("wall time %v exceeds %v", u.WallTime.Round(time.Millisecond), t.MaxWallTime))
+	}
+	if t.MaxCPU > 0 && u.CPU() > t.MaxCPU {
+		errs = append(errs, fmt.Errorf("CPU time %v exceeds %v", u.CPU().Round(time.Millisecond), t.MaxCPU))
+	}
+	if t.MaxRSS > 0 && u.MaxRSS > t.MaxRSS {
+		errs = append(errs, fmt.Errorf("max RSS %s exceeds %s", formatBytes(u.MaxRSS), formatBytes(t.MaxRSS)))
+	}
+	return errors.Join(errs...)
+}
+
+// ParseUsageThresholds parses thresholds in the form
+//
+//	cosign verify:rss=256Mi,wall=30s;rekor-cli get:cpu=2s
+//
+// keyed by tool and subcommand as reported in Invocation.
+func ParseUsageThresholds(s string) (map[string]UsageThreshold, error) {
+	thresholds := map[string]UsageThreshold{}
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		command, limits, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("threshold %q: expected '<tool> <subcommand>:<limits>'", entry)
+		}
+		var t UsageThreshold
+		for _, limit := range strings.Split(limits, ",") {
+			name, value, ok := strings.Cut(strings.TrimSpace(limit), "=")
+			if !ok {
+				return nil, fmt.Errorf("threshold %q: expected <metric>=<value>, got %q", entry, limit)
+			}
+			var err error
+			switch name {
+			case "wall":
+				t.MaxWallTime, err = time.ParseDuration(value)
+			case "cpu":
+				t.MaxCPU, err = time.ParseDuration(value)
+			case "rss":
+				var q resource.Quantity
+				if q, err = resource.ParseQuantity(value); err == nil {
+					t.MaxRSS = q.Value()
+				}
+			default:
+				err = fmt.Errorf("unknown metric %q (expected wall, cpu or rss)", name)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("threshold %q: %w", entry, err)
+			}
+		}
+		thresholds[strings.TrimSpace(command)] = t
+	}
+	return thresholds, nil
+}
+
+// UsageStats aggregates the usage of all invocations of one command.
+type UsageStats struct {
+	Invocations   int           `json:"invocations"`
+	TotalWallTime time.Duration `json:"totalWallTime"`
+	MaxWallTime   time.Duration `json:"maxWallTime"`
+	TotalCPU      time.Duration `json:"totalCPU"`
+	MaxRSS        int64         `json:"maxRSS"`
+}
+
+// UsageSummary collects per-command statistics. It is safe for concurrent use.
+type UsageSummary struct {
+	mu    sync.Mutex
+	stats map[string]*UsageStats
+}
+
+func (s *UsageSummary) Add(inv Invocation) {
+	if inv.Usage == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stats == nil {
+		s.stats = map[string]*UsageStats{}
+	}
+	key := inv.Command()
+	st, ok := s.stats[key]
+	if !ok {
+		st = &UsageStats{}
+		s.stats[key] = st
+	}
+	st.Invocations++
+	st.TotalWallTime += inv.Usage.WallTime
+	st.TotalCPU += inv.Usage.CPU()
+	st.MaxWallTime = max(st.MaxWallTime, inv.Usage.WallTime)
+	st.MaxRSS = max(st.MaxRSS, inv.Usage.MaxRSS)
+}
+
+// Merge adds statistics keyed by command, e.g. the ones collected by another
+// parallel process.
+func (s *UsageSummary) Merge(stats map[string]UsageStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stats == nil {
+		s.stats = map[string]*UsageStats{}
+	}
+	for key, other := range stats {
+		st, ok := s.stats[key]
+		if !ok {
+			st = &UsageStats{}
+			s.stats[key] = st
+		}
+		st.Invocations += other.Invocations
+		st.TotalWallTime += other.TotalWallTime
+		st.TotalCPU += other.TotalCPU
+		st.MaxWallTime = max(st.MaxWallTime, other.MaxWallTime)
+		st.MaxRSS = max(st.MaxRSS, other.MaxRSS)
+	}
+}
+
+// Stats returns a copy of the statistics keyed by command.
+func (s *UsageSummary) Stats() map[string]UsageStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]UsageStats, len(s.stats))
+	for k, v := range s.stats {
+		out[k] = *v
+	}
+	return out
+}
+
+// String formats the summary as a table sorted by command.
+func (s *UsageSummary) String() string {
+	stats := s.Stats()
+	commands := make([]string, 0, len(stats))
+	for k := range stats {
+		commands = append(commands, k)
+	}
+	sort.Strings(commands)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-32s %5s %12s %12s %12s %10s\n", "COMMAND", "RUNS", "TOTAL WALL", "MAX WALL", "TOTAL CPU", "MAX RSS")
+	for _, c := range commands {
+		st := stats[c]
+		fmt.Fprintf(&b, "%-32s %5d %12v %12v %12v %10s\n", c, st.Invocations,
+			st.TotalWallTime.Round(time.Millisecond), st.MaxWallTime.Round(time.Millisecond),
+			st.TotalCPU.Round(time.Millisecond), formatBytes(st.MaxRSS))
+	}
+	return b.String()
+}
+
+func formatBytes(n int64) string {
+	return resource.NewQuantity(n, resource.BinarySI).String()
+}
@@ -0,0 +1,13 @@
+package clients
+
+import (
+	"os"
+	"syscall"
+)
+
+func maxRSS(state *os.ProcessState) int64 {
+	if ru, ok := state.SysUsage().(*syscall.Rusage); ok {
+		return ru.Maxrss // reported in bytes
+	}
+	return 0
+}
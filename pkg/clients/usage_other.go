@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package clients
+
+import "os"
+
+func maxRSS(_ *os.ProcessState) int64 {
+	return 0
+}
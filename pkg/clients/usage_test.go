@@ -0,0 +1,117 @@
+package clients
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseUsageThresholds(t *testing.T) {
+	thresholds, err := ParseUsageThresholds("cosign verify:rss=256Mi,wall=30s; rekor-cli get:cpu=2s")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]UsageThreshold{
+		"cosign verify": {MaxWallTime: 30 * time.Second, MaxRSS: 256 << 20},
+		"rekor-cli get": {MaxCPU: 2 * time.Second},
+	}
+	if len(thresholds) != len(expected) {
+		t.Fatalf("got %v, want %v", thresholds, expected)
+	}
+	for k, v := range expected {
+		if thresholds[k] != v {
+			t.Errorf("%s: got %+v, want %+v", k, thresholds[k], v)
+		}
+	}
+
+	for _, invalid := range []string{"cosign verify", "cosign verify:rss", "cosign verify:mem=1Gi", "cosign verify:wall=fast"} {
+		if _, err := ParseUsageThresholds(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+func TestUsageThresholdCheck(t *testing.T) {
+	threshold := UsageThreshold{MaxRSS: 100 << 20, MaxCPU: time.Second}
+	if err := threshold.Check(ResourceUsage{MaxRSS: 50 << 20, UserCPU: 500 * time.Millisecond}); err != nil {
+		t.Errorf("unexpected violation: %v", err)
+	}
+	err := threshold.Check(ResourceUsage{MaxRSS: 200 << 20, UserCPU: time.Second, SystemCPU: time.Second, WallTime: time.Hour})
+	if err == nil || !strings.Contains(err.Error(), "max RSS 200Mi exceeds 100Mi") || !strings.Contains(err.Error(), "CPU time 2s exceeds 1s") {
+		t.Errorf("unexpected result %v", err)
+	}
+}
+
+func TestInvocationUsage(t *testing.T) {
+	var summary UsageSummary
+	var recorded []Invocation
+	stop := ObserveInvocations(func(inv Invocation) {
+		recorded = append(recorded, inv)
+		summary.Add(inv)
+	})
+	defer stop()
+
+	c := &cli{Name: "sh", pathToCLI: "/bin/sh"}
+	for i := 0; i < 2; i++ {
+		if _, _, err := c.run(context.Background(), "-c", "i=0; while [ $i -lt 2000 ]; do i=$((i+1)); done"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(recorded) != 2 || recorded[0].Usage == nil {
+		t.Fatalf("expected 2 invocations with usage, got %+v", recorded)
+	}
+	usage := recorded[0].Usage
+	if usage.WallTime <= 0 {
+		t.Errorf("missing wall time in %+v", usage)
+	}
+	if runtime.GOOS == "linux" && usage.MaxRSS <= 0 {
+		t.Errorf("missing max RSS in %+v", usage)
+	}
+
+	stats := summary.Stats()["sh"]
+	if stats.Invocations != 2 || stats.TotalWallTime < stats.MaxWallTime {
+		t.Errorf("unexpected summary %+v", stats)
+	}
+	if !strings.Contains(summary.String(), "sh ") {
+		t.Errorf("summary table misses the command:\n%s", summary.String())
+	}
+}
+
+func TestUsageSummaryMerge(t *testing.T) {
+	var summary UsageSummary
+	summary.Add(Invocation{Tool: "cosign", Subcommand: "verify", Usage: &ResourceUsage{WallTime: 2 * time.Second, MaxRSS: 100}})
+	summary.Merge(map[string]UsageStats{
+		"cosign verify": {Invocations: 2, TotalWallTime: 3 * time.Second, MaxWallTime: 2500 * time.Millisecond, MaxRSS: 300},
+		"cosign sign":   {Invocations: 1, TotalWallTime: time.Second, MaxWallTime: time.Second},
+	})
+
+	stats := summary.Stats()
+	want := UsageStats{Invocations: 3, TotalWallTime: 5 * time.Second, MaxWallTime: 2500 * time.Millisecond, MaxRSS: 300}
+	if stats["cosign verify"] != want {
+		t.Errorf("merged stats = %+v, want %+v", stats["cosign verify"], want)
+	}
+	if stats["cosign sign"].Invocations != 1 {
+		t.Errorf("missing merged command in %+v", stats)
+	}
+}
+
+func TestObserverPanicDoesNotSkipOthers(t *testing.T) {
+	called := false
+	stopFailing := ObserveInvocations(func(Invocation) { panic("spec failed") })
+	defer stopFailing()
+	stopRecording := ObserveInvocations(func(Invocation) { called = true })
+	defer stopRecording()
+
+	defer func() {
+		if r := recover(); r != "spec failed" {
+			t.Errorf("expected the observer panic to be re-raised, got %v", r)
+		}
+		if !called {
+			t.Error("second observer was not called")
+		}
+	}()
+	notifyObservers(Invocation{Tool: "cosign"})
+}
@@ -0,0 +1,198 @@
+// Package discovery finds the endpoints of a Trusted Artifact Signer
+// deployment from its custom resources and the Keycloak route.
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	controller "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// GroupVersion of the Securesign operator resources.
+var GroupVersion = schema.GroupVersion{Group: "rhtas.redhat.com", Version: "v1alpha1"}
+
+// RouteGroupVersion of OpenShift routes.
+var RouteGroupVersion = schema.GroupVersion{Group: "route.openshift.io", Version: "v1"}
+
+// Kinds read by Discover.
+const (
+	KindSecuresign         = "Securesign"
+	KindFulcio             = "Fulcio"
+	KindRekor              = "Rekor"
+	KindCTlog              = "CTlog"
+	KindTuf                = "Tuf"
+	KindTimestampAuthority = "TimestampAuthority"
+	KindRoute              = "Route"
+)
+
+// Options restricts where Discover looks.
+type Options struct {
+	// Namespace of the Securesign resources; all namespaces when empty.
+	Namespace string
+	// KeycloakNamespace and KeycloakSelector select the Keycloak route.
+	KeycloakNamespace string
+	KeycloakSelector  map[string]string
+	// Realm is appended to the Keycloak URL to form the OIDC issuer.
+	Realm string
+}
+
+// DefaultOptions match the layout installed by the Securesign operator.
+func DefaultOptions() Options {
+	return Options{
+		KeycloakNamespace: "keycloak-system",
+		KeycloakSelector:  map[string]string{"app": "keycloak"},
+		Realm:             api.GetValueFor(api.OidcRealm),
+	}
+}
+
+// Endpoints of a deployment. Endpoints that were not found are empty.
+type Endpoints struct {
+	TUF        string `json:"tufURL,omitempty"`
+	Fulcio     string `json:"fulcioURL,omitempty"`
+	Rekor      string `json:"rekorURL,omitempty"`
+	RekorUI    string `json:"rekorUIURL,omitempty"`
+	CTlog      string `json:"ctlogURL,omitempty"`
+	TSA        string `json:"tsaURL,omitempty"`
+	OIDCIssuer string `json:"oidcIssuerURL,omitempty"`
+}
+
+// Vars returns the configuration keys of the endpoints that were found.
+func (e *Endpoints) Vars() map[string]string {
+	vars := map[string]string{}
+	for key, value := range map[string]string{
+		api.TufURL:        e.TUF,
+		api.FulcioURL:     e.Fulcio,
+		api.RekorURL:      e.Rekor,
+		api.RekorUIURL:    e.RekorUI,
+		api.TsaURL:        e.TSA,
+		api.OidcIssuerURL: e.OIDCIssuer,
+	} {
+		if value != "" {
+			vars[key] = value
+		}
+	}
+	return vars
+}
+
+// Apply sets the discovered endpoints as defaults of api.Values, so values from
+// the environment or a profile take precedence. It returns the keys it filled.
+func (e *Endpoints) Apply() []string {
+	var filled []string
+	for key, value := range e.Vars() {
+		if api.GetValueFor(key) == "" {
+			api.Values.SetDefault(key, value)
+			filled = append(filled, key)
+		}
+	}
+	sort.Strings(filled)
+	return filled
+}
+
+// Discover reads the endpoints from the Securesign resources visible to c.
+// URLs in the status of the component resources take precedence over the
+// status of the Securesign resource.
+func Discover(ctx context.Context, c controller.Reader, opts Options) (*Endpoints, error) {
+	e := &Endpoints{}
+	fields := []struct {
+		kind   string
+		target *string
+		path   []string
+	}{
+		{KindTuf, &e.TUF, []string{"status", "url"}},
+		{KindFulcio, &e.Fulcio, []string{"status", "url"}},
+		{KindRekor, &e.Rekor, []string{"status", "url"}},
+		{KindRekor, &e.RekorUI, []string{"status", "rekorSearchUIUrl"}},
+		{KindCTlog, &e.CTlog, []string{"status", "url"}},
+		{KindTimestampAuthority, &e.TSA, []string{"status", "url"}},
+		{KindSecuresign, &e.TUF, []string{"status", "tuf", "url"}},
+		{KindSecuresign, &e.Fulcio, []string{"status", "fulcio", "url"}},
+		{KindSecuresign, &e.Rekor, []string{"status", "rekor", "url"}},
+		{KindSecuresign, &e.RekorUI, []string{"status", "rekor", "rekorSearchUIUrl"}},
+		{KindSecuresign, &e.TSA, []string{"status", "tsa", "url"}},
+	}
+
+	lists := map[string][]unstructured.Unstructured{}
+	for _, f := range fields {
+		items, ok := lists[f.kind]
+		if !ok {
+			var err error
+			if items, err = list(ctx, c, GroupVersion.WithKind(f.kind), opts.Namespace, nil); err != nil {
+				return nil, err
+			}
+			lists[f.kind] = items
+		}
+		if *f.target == "" {
+			*f.target = firstString(items, f.path...)
+		}
+	}
+
+	issuer, err := keycloakIssuer(ctx, c, opts)
+	if err != nil {
+		return nil, err
+	}
+	e.OIDCIssuer = issuer
+	return e, nil
+}
+
+func keycloakIssuer(ctx context.Context, c controller.Reader, opts Options) (string, error) {
+	if opts.KeycloakNamespace == "" {
+		return "", nil
+	}
+	routes, err := list(ctx, c, RouteGroupVersion.WithKind(KindRoute), opts.KeycloakNamespace, opts.KeycloakSelector)
+	if err != nil {
+		return "", err
+	}
+	host := firstString(routes, "spec", "host")
+	if host == "" {
+		return "", nil
+	}
+	issuer := "https://" + host
+	if opts.Realm != "" {
+		issuer += "/realms/" + opts.Realm
+	}
+	return issuer, nil
+}
+
+// list returns the objects of gvk sorted by namespace and name. A kind that is
+// not installed in the cluster yields no objects.
+func list(ctx context.Context, c controller.Reader, gvk schema.GroupVersionKind, namespace string, labels map[string]string) ([]unstructured.Unstructured, error) {
+	l := &unstructured.UnstructuredList{}
+	l.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+
+	opts := []controller.ListOption{controller.InNamespace(namespace)}
+	if len(labels) > 0 {
+		opts = append(opts, controller.MatchingLabels(labels))
+	}
+	if err := c.List(ctx, l, opts...); err != nil {
+		if isNotInstalled(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot list %s: %w", gvk.Kind, err)
+	}
+	sort.Slice(l.Items, func(i, j int) bool {
+		a, b := l.Items[i], l.Items[j]
+		return a.GetNamespace()+"/"+a.GetName() < b.GetNamespace()+"/"+b.GetName()
+	})
+	return l.Items, nil
+}
+
+func isNotInstalled(err error) bool {
+	return meta.IsNoMatchError(err) || runtime.IsNotRegisteredError(err) || apierrors.IsNotFound(err)
+}
+
+func firstString(items []unstructured.Unstructured, path ...string) string {
+	for _, item := range items {
+		if v, found, err := unstructured.NestedString(item.Object, path...); err == nil && found && v != "" {
+			return v
+		}
+	}
+	return ""
+}
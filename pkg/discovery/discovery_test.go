@@ -0,0 +1,209 @@
+package discovery
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	controller "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func object(gv schema.GroupVersion, kind, namespace, name string, fields map[string]any) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: fields}
+	u.SetGroupVersionKind(gv.WithKind(kind))
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return u
+}
+
+func newFakeClient(t *testing.T, kinds []string, objects ...controller.Object) controller.Client {
+	t.Helper()
+	scheme := k8sruntime.NewScheme()
+	register := func(gvk schema.GroupVersionKind) {
+		scheme.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+		scheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind+"List"), &unstructured.UnstructuredList{})
+	}
+	for _, kind := range kinds {
+		register(GroupVersion.WithKind(kind))
+	}
+	register(RouteGroupVersion.WithKind(KindRoute))
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+}
+
+var allKinds = []string{KindSecuresign, KindFulcio, KindRekor, KindCTlog, KindTuf, KindTimestampAuthority}
+
+func keycloakRoute() *unstructured.Unstructured {
+	route := object(RouteGroupVersion, KindRoute, "keycloak-system", "keycloak", map[string]any{
+		"spec": map[string]any{"host": "keycloak.apps.example.com"},
+	})
+	route.SetLabels(map[string]string{"app": "keycloak"})
+	return route
+}
+
+func TestDiscoverComponents(t *testing.T) {
+	c := newFakeClient(t, allKinds,
+		object(GroupVersion, KindTuf, "tas", "tuf", map[string]any{"status": map[string]any{"url": "https://tuf.example.com"}}),
+		object(GroupVersion, KindFulcio, "tas", "fulcio", map[string]any{"status": map[string]any{"url": "https://fulcio.example.com"}}),
+		object(GroupVersion, KindRekor, "tas", "rekor", map[string]any{"status": map[string]any{
+			"url": "https://rekor.example.com", "rekorSearchUIUrl": "https://rekor-ui.example.com",
+		}}),
+		object(GroupVersion, KindTimestampAuthority, "tas", "tsa", map[string]any{"status": map[string]any{"url": "https://tsa.example.com"}}),
+		object(GroupVersion, KindCTlog, "tas", "ctlog", map[string]any{"status": map[string]any{}}),
+		// component resources take precedence over the Securesign status
+		object(GroupVersion, KindSecuresign, "tas", "securesign", map[string]any{"status": map[string]any{
+			"tuf": map[string]any{"url": "https://stale.example.com"},
+		}}),
+		keycloakRoute(),
+	)
+
+	e, err := Discover(t.Context(), c, Options{
+		KeycloakNamespace: "keycloak-system",
+		KeycloakSelector:  map[string]string{"app": "keycloak"},
+		Realm:             "trusted-artifact-signer",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Endpoints{
+		TUF:        "https://tuf.example.com",
+		Fulcio:     "https://fulcio.example.com",
+		Rekor:      "https://rekor.example.com",
+		RekorUI:    "https://rekor-ui.example.com",
+		TSA:        "https://tsa.example.com",
+		OIDCIssuer: "https://keycloak.apps.example.com/realms/trusted-artifact-signer",
+	}
+	if !reflect.DeepEqual(e, want) {
+		t.Fatalf("Discover() = %+v, want %+v", e, want)
+	}
+}
+
+func TestDiscoverSecuresignStatus(t *testing.T) {
+	c := newFakeClient(t, allKinds,
+		object(GroupVersion, KindSecuresign, "tas", "securesign", map[string]any{"status": map[string]any{
+			"tuf":    map[string]any{"url": "https://tuf.example.com"},
+			"fulcio": map[string]any{"url": "https://fulcio.example.com"},
+			"rekor":  map[string]any{"url": "https://rekor.example.com", "rekorSearchUIUrl": "https://rekor-ui.example.com"},
+		}}),
+		object(GroupVersion, KindSecuresign, "other", "securesign", map[string]any{"status": map[string]any{
+			"tuf": map[string]any{"url": "https://tuf.other.example.com"},
+		}}),
+	)
+
+	e, err := Discover(t.Context(), c, Options{Namespace: "tas"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.TUF != "https://tuf.example.com" || e.Fulcio != "https://fulcio.example.com" || e.RekorUI != "https://rekor-ui.example.com" {
+		t.Fatalf("unexpected endpoints: %+v", e)
+	}
+	if e.OIDCIssuer != "" {
+		t.Fatalf("expected no OIDC issuer without Keycloak namespace, got %q", e.OIDCIssuer)
+	}
+}
+
+func TestDiscoverWithoutOperator(t *testing.T) {
+	// none of the Securesign kinds are installed
+	c := newFakeClient(t, nil, keycloakRoute())
+	e, err := Discover(t.Context(), c, Options{KeycloakNamespace: "keycloak-system"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.TUF != "" || e.OIDCIssuer != "https://keycloak.apps.example.com" {
+		t.Fatalf("unexpected endpoints: %+v", e)
+	}
+}
+
+func TestApply(t *testing.T) {
+	t.Setenv(api.TufURL, "https://tuf.env.example.com")
+	t.Setenv(api.FulcioURL, "")
+	t.Cleanup(func() { api.Values.SetDefault(api.FulcioURL, nil) })
+
+	e := &Endpoints{TUF: "https://tuf.example.com", Fulcio: "https://fulcio.example.com"}
+	filled := e.Apply()
+	if !reflect.DeepEqual(filled, []string{api.FulcioURL}) {
+		t.Fatalf("Apply() = %v", filled)
+	}
+	if got := api.GetValueFor(api.TufURL); got != "https://tuf.env.example.com" {
+		t.Errorf("environment value was overridden: %s", got)
+	}
+	if got := api.GetValueFor(api.FulcioURL); got != "https://fulcio.example.com" {
+		t.Errorf("%s = %q", api.FulcioURL, got)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	vars := map[string]string{"TUF_URL": "https://tuf.example.com", "OIDC_CLIENT_ID": "it's"}
+	tests := map[string]string{
+		FormatDotenv:     "OIDC_CLIENT_ID=it's\nTUF_URL=https://tuf.example.com\n",
+		FormatShell:      "export OIDC_CLIENT_ID='it'\\''s'\nexport TUF_URL='https://tuf.example.com'\n",
+		FormatPowerShell: "$env:OIDC_CLIENT_ID = 'it''s'\n$env:TUF_URL = 'https://tuf.example.com'\n",
+	}
+	for format, want := range tests {
+		var out bytes.Buffer
+		if err := Write(&out, vars, format); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != want {
+			t.Errorf("Write(%s) = %q, want %q", format, out.String(), want)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := Write(&out, vars, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]string
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil || !reflect.DeepEqual(decoded, vars) {
+		t.Errorf("Write(json) = %s, %v", out.String(), err)
+	}
+
+	if err := Write(&out, vars, "yaml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestWithCLIVars(t *testing.T) {
+	tests := []struct {
+		name string
+		vars map[string]string
+		want map[string]string
+	}{
+		{
+			name: "all services",
+			vars: map[string]string{
+				"TUF_URL":        "https://tuf.example.com/",
+				"REKOR_URL":      "https://rekor.example.com",
+				"OIDC_CLIENT_ID": "trusted-artifact-signer",
+			},
+			want: map[string]string{
+				"TUF_URL":                 "https://tuf.example.com/",
+				"REKOR_URL":               "https://rekor.example.com",
+				"OIDC_CLIENT_ID":          "trusted-artifact-signer",
+				"COSIGN_YES":              "true",
+				"COSIGN_MIRROR":           "https://tuf.example.com/",
+				"COSIGN_ROOT":             "https://tuf.example.com/root.json",
+				"REKOR_REKOR_SERVER":      "https://rekor.example.com",
+				"COSIGN_OIDC_CLIENT_ID":   "trusted-artifact-signer",
+				"SIGSTORE_OIDC_CLIENT_ID": "trusted-artifact-signer",
+			},
+		},
+		{
+			name: "nothing discovered",
+			vars: map[string]string{},
+			want: map[string]string{"COSIGN_YES": "true"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WithCLIVars(tt.vars); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WithCLIVars() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/securesign/sigstore-e2e/pkg/api"
+)
+
+// Output formats of Write.
+const (
+	FormatDotenv     = "dotenv"
+	FormatShell      = "shell"
+	FormatPowerShell = "powershell"
+	FormatJSON       = "json"
+)
+
+// Formats lists the formats supported by Write.
+var Formats = []string{FormatDotenv, FormatShell, FormatPowerShell, FormatJSON}
+
+// WithCLIVars adds to vars the variables read by cosign and rekor-cli, derived
+// from the TUF and Rekor URLs and the OIDC client ID in vars, as the former
+// tas-env-variables scripts exported them.
+func WithCLIVars(vars map[string]string) map[string]string {
+	out := make(map[string]string, len(vars)+6) //nolint:mnd
+	for k, v := range vars {
+		out[k] = v
+	}
+	out["COSIGN_YES"] = "true"
+	if tuf := vars[api.TufURL]; tuf != "" {
+		out["COSIGN_MIRROR"] = tuf
+		out["COSIGN_ROOT"] = strings.TrimRight(tuf, "/") + "/root.json"
+	}
+	if rekor := vars[api.RekorURL]; rekor != "" {
+		out["REKOR_REKOR_SERVER"] = rekor
+	}
+	if clientID := vars[api.OidcClientID]; clientID != "" {
+		out["COSIGN_OIDC_CLIENT_ID"] = clientID
+		out["SIGSTORE_OIDC_CLIENT_ID"] = clientID
+	}
+	return out
+}
+
+// Write prints vars to w in format, sorted by key.
+func Write(w io.Writer, vars map[string]string, format string) error {
+	if format == FormatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(vars)
+	}
+
+	var line func(k, v string) string
+	switch format {
+	case FormatDotenv:
+		line = func(k, v string) string { return k + "=" + v }
+	case FormatShell:
+		line = func(k, v string) string { return "export " + k + "='" + strings.ReplaceAll(v, "'", `'\''`) + "'" }
+	case FormatPowerShell:
+		line = func(k, v string) string { return "$env:" + k + " = '" + strings.ReplaceAll(v, "'", "''") + "'" }
+	default:
+		return fmt.Errorf("unknown format %q (expected one of %s)", format, strings.Join(Formats, ", "))
+	}
+
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, err := fmt.Fprintln(w, line(k, vars[k])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
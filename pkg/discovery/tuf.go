@@ -0,0 +1,273 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/theupdateframework/go-tuf/v2/metadata/config"
+	"github.com/theupdateframework/go-tuf/v2/metadata/updater"
+)
+
+// Targets of a Sigstore TUF repository describing the deployment.
+const (
+	TrustedRootTarget   = "trusted_root.json"
+	SigningConfigTarget = "signing_config.json"
+)
+
+// TimeRange is the validity of a key or URL. An empty End means it is still valid.
+type TimeRange struct {
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+func (r *TimeRange) validAt(t time.Time) bool {
+	if r == nil {
+		return true
+	}
+	return !t.Before(r.Start) && (r.End == nil || t.Before(*r.End))
+}
+
+// PublicKey of a transparency log.
+type PublicKey struct {
+	// RawBytes is the DER encoded key, base64 encoded in JSON.
+	RawBytes   []byte     `json:"rawBytes"`
+	KeyDetails string     `json:"keyDetails"`
+	ValidFor   *TimeRange `json:"validFor,omitempty"`
+}
+
+// TransparencyLog is a Rekor or CT log in trusted_root.json.
+type TransparencyLog struct {
+	BaseURL       string    `json:"baseUrl"`
+	HashAlgorithm string    `json:"hashAlgorithm"`
+	PublicKey     PublicKey `json:"publicKey"`
+	LogID         struct {
+		KeyID []byte `json:"keyId"`
+	} `json:"logId"`
+}
+
+// CertificateAuthority is a Fulcio instance or timestamp authority in trusted_root.json.
+type CertificateAuthority struct {
+	URI       string `json:"uri"`
+	CertChain struct {
+		Certificates []struct {
+			// RawBytes is the DER encoded certificate, base64 encoded in JSON.
+			RawBytes []byte `json:"rawBytes"`
+		} `json:"certificates"`
+	} `json:"certChain"`
+	ValidFor *TimeRange `json:"validFor,omitempty"`
+}
+
+// TrustedRoot is the subset of the Sigstore trusted root used by the tests.
+type TrustedRoot struct {
+	MediaType              string                 `json:"mediaType"`
+	Tlogs                  []TransparencyLog      `json:"tlogs"`
+	CertificateAuthorities []CertificateAuthority `json:"certificateAuthorities"`
+	Ctlogs                 []TransparencyLog      `json:"ctlogs"`
+	TimestampAuthorities   []CertificateAuthority `json:"timestampAuthorities"`
+}
+
+// Service is a URL in signing_config.json v0.2.
+type Service struct {
+	URL             string     `json:"url"`
+	MajorAPIVersion int        `json:"majorApiVersion"`
+	ValidFor        *TimeRange `json:"validFor,omitempty"`
+}
+
+// SigningConfig holds the service URLs of signing_config.json. Both the v0.1
+// (single URLs) and v0.2 (lists of services) media types are read.
+type SigningConfig struct {
+	MediaType string `json:"mediaType"`
+	// v0.1
+	CAURL    string   `json:"caUrl,omitempty"`
+	OIDCURL  string   `json:"oidcUrl,omitempty"`
+	TlogURLs []string `json:"tlogUrls,omitempty"`
+	TSAURLs  []string `json:"tsaUrls,omitempty"`
+	// v0.2
+	CAURLs        []Service `json:"caUrls,omitempty"`
+	OIDCURLs      []Service `json:"oidcUrls,omitempty"`
+	RekorTlogURLs []Service `json:"rekorTlogUrls,omitempty"`
+	TSAServices   []Service `json:"-"`
+}
+
+func (c *SigningConfig) UnmarshalJSON(data []byte) error {
+	type plain SigningConfig
+	var raw struct {
+		plain
+		TSAURLs json.RawMessage `json:"tsaUrls,omitempty"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*c = SigningConfig(raw.plain)
+	if len(raw.TSAURLs) == 0 {
+		return nil
+	}
+	// tsaUrls is a list of strings in v0.1 and a list of services in v0.2
+	if err := json.Unmarshal(raw.TSAURLs, &c.TSAURLs); err == nil {
+		return nil
+	}
+	return json.Unmarshal(raw.TSAURLs, &c.TSAServices)
+}
+
+// TUFConfig is the deployment configuration distributed by a TUF repository.
+type TUFConfig struct {
+	Mirror      string
+	TrustedRoot *TrustedRoot
+	// SigningConfig is nil when the repository does not provide signing_config.json.
+	SigningConfig *SigningConfig
+}
+
+// FetchTUFConfig downloads and verifies trusted_root.json and signing_config.json
+// from the TUF repository at mirror. Without root, the root.json of the mirror
+// is trusted on first use, like 'cosign initialize --root <mirror>/root.json'.
+func FetchTUFConfig(ctx context.Context, mirror string, root []byte) (*TUFConfig, error) {
+	mirror = strings.TrimRight(mirror, "/")
+	if root == nil {
+		var err error
+		if root, err = fetch(ctx, mirror+"/root.json"); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg, err := config.New(mirror, root)
+	if err != nil {
+		return nil, err
+	}
+	cfg.DisableLocalCache = true
+	cfg.PrefixTargetsWithHash = true
+	up, err := updater.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := up.Refresh(); err != nil {
+		return nil, fmt.Errorf("cannot update TUF metadata from %s: %w", mirror, err)
+	}
+
+	tc := &TUFConfig{Mirror: mirror}
+	data, err := downloadTarget(up, TrustedRootTarget)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &tc.TrustedRoot); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", TrustedRootTarget, err)
+	}
+	data, err = downloadTarget(up, SigningConfigTarget)
+	switch {
+	case errors.Is(err, errTargetNotFound):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(data, &tc.SigningConfig); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", SigningConfigTarget, err)
+		}
+	}
+	return tc, nil
+}
+
+var errTargetNotFound = errors.New("target not found")
+
+func downloadTarget(up *updater.Updater, name string) ([]byte, error) {
+	info, err := up.GetTargetInfo(name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", name, errTargetNotFound, err)
+	}
+	_, data, err := up.DownloadTarget(info, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("cannot download %s: %w", name, err)
+	}
+	return data, nil
+}
+
+func fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// ReadRoot reads a pinned TUF root from path, or returns nil when path is empty.
+func ReadRoot(path string) ([]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return os.ReadFile(path)
+}
+
+// Endpoints returns the service URLs valid now. The signing config takes
+// precedence over the URLs in the trusted root.
+func (c *TUFConfig) Endpoints() *Endpoints {
+	now := time.Now()
+	e := &Endpoints{TUF: c.Mirror}
+	if sc := c.SigningConfig; sc != nil {
+		e.Fulcio = firstNonEmpty(sc.CAURL, currentService(sc.CAURLs, now))
+		e.OIDCIssuer = firstNonEmpty(sc.OIDCURL, currentService(sc.OIDCURLs, now))
+		e.Rekor = firstNonEmpty(first(sc.TlogURLs), currentService(sc.RekorTlogURLs, now))
+		e.TSA = firstNonEmpty(first(sc.TSAURLs), currentService(sc.TSAServices, now))
+	}
+	if tr := c.TrustedRoot; tr != nil {
+		e.Fulcio = firstNonEmpty(e.Fulcio, currentAuthority(tr.CertificateAuthorities, now))
+		e.Rekor = firstNonEmpty(e.Rekor, currentLog(tr.Tlogs, now))
+		e.CTlog = currentLog(tr.Ctlogs, now)
+		e.TSA = firstNonEmpty(e.TSA, currentAuthority(tr.TimestampAuthorities, now))
+	}
+	return e
+}
+
+func currentService(services []Service, now time.Time) string {
+	for _, s := range services {
+		if s.ValidFor.validAt(now) {
+			return s.URL
+		}
+	}
+	return ""
+}
+
+func currentAuthority(authorities []CertificateAuthority, now time.Time) string {
+	for i := len(authorities) - 1; i >= 0; i-- {
+		if authorities[i].ValidFor.validAt(now) {
+			return authorities[i].URI
+		}
+	}
+	return ""
+}
+
+func currentLog(logs []TransparencyLog, now time.Time) string {
+	for i := len(logs) - 1; i >= 0; i-- {
+		if logs[i].PublicKey.ValidFor.validAt(now) {
+			return logs[i].BaseURL
+		}
+	}
+	return ""
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,134 @@
+package discovery
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/securesign/sigstore-e2e/pkg/tuf/tuftest"
+)
+
+const trustedRootJSON = `{
+  "mediaType": "application/vnd.dev.sigstore.trustedroot+json;version=0.1",
+  "tlogs": [
+    {"baseUrl": "https://rekor.old.example.com", "publicKey": {"validFor": {"start": "2020-01-01T00:00:00Z", "end": "2021-01-01T00:00:00Z"}}},
+    {"baseUrl": "https://rekor.example.com", "publicKey": {"rawBytes": "AQID", "validFor": {"start": "2021-01-01T00:00:00Z"}}}
+  ],
+  "certificateAuthorities": [{"uri": "https://fulcio.example.com", "validFor": {"start": "2021-01-01T00:00:00Z"}}],
+  "ctlogs": [{"baseUrl": "https://ctlog.example.com", "publicKey": {"validFor": {"start": "2021-01-01T00:00:00Z"}}}],
+  "timestampAuthorities": [{"uri": "https://tsa.example.com/api/v1/timestamp"}]
+}`
+
+// tufRepository serves a TUF repository with the given targets. It returns
+// the mirror URL and root.json.
+func tufRepository(t *testing.T, targets map[string]string) (string, []byte) {
+	t.Helper()
+	files := map[string][]byte{}
+	for name, content := range targets {
+		files[name] = []byte(content)
+	}
+	server, err := tuftest.NewServer(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Close)
+	return server.URL, server.RootJSON()
+}
+
+func TestFetchTUFConfig(t *testing.T) {
+	mirror, root := tufRepository(t, map[string]string{
+		TrustedRootTarget: trustedRootJSON,
+		SigningConfigTarget: `{
+  "mediaType": "application/vnd.dev.sigstore.signingconfig.v0.2+json",
+  "caUrls": [{"url": "https://fulcio.signing.example.com", "majorApiVersion": 1}],
+  "oidcUrls": [{"url": "https://oidc.example.com", "majorApiVersion": 1}],
+  "rekorTlogUrls": [{"url": "https://rekor.signing.example.com", "majorApiVersion": 1}],
+  "tsaUrls": [{"url": "https://tsa.signing.example.com/api/v1/timestamp", "majorApiVersion": 1}]
+}`,
+	})
+
+	for name, pinned := range map[string][]byte{"pinned root": root, "trust on first use": nil} {
+		t.Run(name, func(t *testing.T) {
+			tc, err := FetchTUFConfig(t.Context(), mirror+"/", pinned)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tc.TrustedRoot.Tlogs) != 2 || string(tc.TrustedRoot.Tlogs[1].PublicKey.RawBytes) != "\x01\x02\x03" {
+				t.Fatalf("unexpected tlogs: %+v", tc.TrustedRoot.Tlogs)
+			}
+			want := &Endpoints{
+				TUF:        mirror,
+				Fulcio:     "https://fulcio.signing.example.com",
+				Rekor:      "https://rekor.signing.example.com",
+				CTlog:      "https://ctlog.example.com",
+				TSA:        "https://tsa.signing.example.com/api/v1/timestamp",
+				OIDCIssuer: "https://oidc.example.com",
+			}
+			if got := tc.Endpoints(); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Endpoints() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestFetchTUFConfigWithoutSigningConfig(t *testing.T) {
+	mirror, root := tufRepository(t, map[string]string{TrustedRootTarget: trustedRootJSON})
+	tc, err := FetchTUFConfig(t.Context(), mirror, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc.SigningConfig != nil {
+		t.Fatalf("unexpected signing config: %+v", tc.SigningConfig)
+	}
+	want := &Endpoints{
+		TUF:    mirror,
+		Fulcio: "https://fulcio.example.com",
+		Rekor:  "https://rekor.example.com",
+		CTlog:  "https://ctlog.example.com",
+		TSA:    "https://tsa.example.com/api/v1/timestamp",
+	}
+	if got := tc.Endpoints(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Endpoints() = %+v, want %+v", got, want)
+	}
+}
+
+func TestFetchTUFConfigUntrustedRoot(t *testing.T) {
+	mirror, _ := tufRepository(t, map[string]string{TrustedRootTarget: trustedRootJSON})
+	_, other := tufRepository(t, map[string]string{TrustedRootTarget: trustedRootJSON})
+	if _, err := FetchTUFConfig(t.Context(), mirror, other); err == nil {
+		t.Fatal("expected error for metadata not signed by the pinned root")
+	}
+}
+
+func TestSigningConfig(t *testing.T) {
+	tests := map[string]struct {
+		json string
+		want Endpoints
+	}{
+		"v0.1": {
+			json: `{"caUrl": "https://fulcio.example.com", "oidcUrl": "https://oidc.example.com",
+				"tlogUrls": ["https://rekor.example.com"], "tsaUrls": ["https://tsa.example.com"]}`,
+			want: Endpoints{Fulcio: "https://fulcio.example.com", OIDCIssuer: "https://oidc.example.com",
+				Rekor: "https://rekor.example.com", TSA: "https://tsa.example.com"},
+		},
+		"v0.2 validity": {
+			json: `{"caUrls": [
+					{"url": "https://fulcio.expired.example.com", "validFor": {"start": "2020-01-01T00:00:00Z", "end": "2021-01-01T00:00:00Z"}},
+					{"url": "https://fulcio.example.com", "validFor": {"start": "2021-01-01T00:00:00Z"}}],
+				"rekorTlogUrls": [{"url": "https://rekor.future.example.com", "validFor": {"start": "2999-01-01T00:00:00Z"}}],
+				"tsaUrls": [{"url": "https://tsa.example.com"}]}`,
+			want: Endpoints{Fulcio: "https://fulcio.example.com", TSA: "https://tsa.example.com"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var sc SigningConfig
+			if err := json.Unmarshal([]byte(tt.json), &sc); err != nil {
+				t.Fatal(err)
+			}
+			if got := (&TUFConfig{SigningConfig: &sc}).Endpoints(); !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Endpoints() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
@@ -20,6 +20,7 @@ import (
 )
 
 var k8sClient Client
+var k8sClientErr error
 var once sync.Once
 
 // Client is an abstraction for a k8s client.
@@ -51,15 +52,23 @@ func (c *defaultClient) GetConfig() *rest.Config {
 }
 
 func GetClient() Client {
+	c, err := TryGetClient()
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// TryGetClient is GetClient returning an error when no cluster is configured.
+func TryGetClient() (Client, error) {
 	once.Do(func() {
-		var err error
-		if k8sClient, err = newClient(); err != nil {
-			panic(err)
+		if k8sClient, k8sClientErr = newClient(); k8sClientErr != nil {
+			return
 		}
 		_ = projectv1.AddToScheme(k8sClient.GetScheme())
 		_ = consoleCli.AddToScheme(k8sClient.GetScheme())
 	})
-	return k8sClient
+	return k8sClient, k8sClientErr
 }
 
 // NewClient creates a new k8s client that can be used from outside or in the cluster.
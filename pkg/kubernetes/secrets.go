@@ -0,0 +1,41 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+func init() {
+	api.RegisterSecretSource(api.SecretSchemeK8s, func(ctx context.Context, ref *url.URL) (string, error) {
+		c, err := TryGetClient()
+		if err != nil {
+			return "", err
+		}
+		return SecretValue(ctx, c, ref)
+	})
+}
+
+// SecretValue reads the key of the secret referenced as k8s://namespace/secret#key.
+func SecretValue(ctx context.Context, c kubernetes.Interface, ref *url.URL) (string, error) {
+	namespace, name, key := ref.Host, strings.Trim(ref.Path, "/"), ref.Fragment
+	if namespace == "" || name == "" || key == "" {
+		return "", fmt.Errorf("expected k8s://namespace/secret#key, got %s", ref.Redacted())
+	}
+	secret, err := c.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	if value, ok := secret.Data[key]; ok {
+		return string(value), nil
+	}
+	if value, ok := secret.StringData[key]; ok {
+		return value, nil
+	}
+	return "", fmt.Errorf("secret %s/%s has no key %s", namespace, name, key)
+}
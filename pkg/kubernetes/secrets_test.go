@@ -0,0 +1,37 @@
+package kubernetes
+
+import (
+	"net/url"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSecretValue(t *testing.T) {
+	c := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "tas", Name: "oidc"},
+		Data:       map[string][]byte{"password": []byte("s3cr3t")},
+	})
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{"k8s://tas/oidc#password", "s3cr3t", false},
+		{"k8s://tas/oidc#token", "", true},
+		{"k8s://tas/missing#password", "", true},
+		{"k8s://tas/oidc", "", true},
+	}
+	for _, tt := range tests {
+		ref, err := url.Parse(tt.ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := SecretValue(t.Context(), c, ref)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("SecretValue(%s) = %q, %v, want %q", tt.ref, got, err, tt.want)
+		}
+	}
+}
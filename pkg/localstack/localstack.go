@@ -0,0 +1,81 @@
+// Package localstack describes a Sigstore deployment of upstream components
+// running on the local machine: their images, the key material they are
+// started with and the trust root distributed to the clients.
+package localstack
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Components of the stack.
+const (
+	MySQL             = "mysql"
+	TrillianLogServer = "trillian-log-server"
+	TrillianLogSigner = "trillian-log-signer"
+	Rekor             = "rekor"
+	CTLog             = "ctlog"
+	Fulcio            = "fulcio"
+	TSA               = "tsa"
+)
+
+// DefaultImages are the known-good upstream images of the components.
+var DefaultImages = map[string]string{
+	MySQL:             "gcr.io/trillian-opensource-ci/db_server:v1.6.1",
+	TrillianLogServer: "gcr.io/trillian-opensource-ci/log_server:v1.6.1",
+	TrillianLogSigner: "gcr.io/trillian-opensource-ci/log_signer:v1.6.1",
+	Rekor:             "ghcr.io/sigstore/rekor/rekor-server:v1.3.9",
+	CTLog:             "ghcr.io/sigstore/scaffolding/ct_server:v0.7.22",
+	Fulcio:            "ghcr.io/sigstore/fulcio:v1.6.6",
+	TSA:               "ghcr.io/sigstore/timestamp-server:v1.2.5",
+}
+
+// Images returns DefaultImages with the overrides applied. Overrides are
+// comma-separated component=image pairs, e.g. "rekor=localhost/rekor-server:dev".
+func Images(overrides string) (map[string]string, error) {
+	images := maps.Clone(DefaultImages)
+	for _, override := range strings.Split(overrides, ",") {
+		override = strings.TrimSpace(override)
+		if override == "" {
+			continue
+		}
+		component, image, ok := strings.Cut(override, "=")
+		component, image = strings.TrimSpace(component), strings.TrimSpace(image)
+		if !ok || image == "" {
+			return nil, fmt.Errorf("invalid image override %q, expected component=image", override)
+		}
+		if _, known := DefaultImages[component]; !known {
+			return nil, fmt.Errorf("unknown component %q in image override, expected one of %s",
+				component, strings.Join(slices.Sorted(maps.Keys(DefaultImages)), ", "))
+		}
+		images[component] = image
+	}
+	return images, nil
+}
+
+// CTLogConfig returns the text protobuf configuration of a CT log serving the
+// Trillian tree treeID at /test, accepting certificates issued by the roots
+// in rootsPath and signing with the encrypted key at keyPath.
+func CTLogConfig(treeID int64, rootsPath string, keyPath string, password string) string {
+	return fmt.Sprintf(`config {
+  log_id: %d
+  prefix: "test"
+  roots_pem_file: %q
+  private_key: {
+    [type.googleapis.com/keyspb.PEMKeyFile] {
+      path: %q
+      password: %q
+    }
+  }
+  ext_key_usages: "CodeSigning"
+}
+`, treeID, rootsPath, keyPath, password)
+}
+
+// FulcioConfig returns the Fulcio configuration trusting the email
+// identities of a single OIDC issuer.
+func FulcioConfig(issuerURL string, clientID string) string {
+	return fmt.Sprintf(`{"OIDCIssuers": {%q: {"IssuerURL": %q, "ClientID": %q, "Type": "email"}}}`, issuerURL, issuerURL, clientID)
+}
@@ -0,0 +1,170 @@
+package localstack
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/securesign/sigstore-e2e/pkg/discovery"
+)
+
+func TestImages(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides string
+		want      map[string]string
+		wantErr   bool
+	}{
+		{name: "defaults", want: DefaultImages},
+		{name: "override", overrides: " rekor=localhost/rekor-server:dev , tsa=localhost/tsa@sha256:abc",
+			want: map[string]string{Rekor: "localhost/rekor-server:dev", TSA: "localhost/tsa@sha256:abc"}},
+		{name: "unknown component", overrides: "redis=redis:7", wantErr: true},
+		{name: "missing image", overrides: "rekor=", wantErr: true},
+		{name: "missing component", overrides: "localhost/rekor-server:dev", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Images(tt.overrides)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Images() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for component, image := range tt.want {
+				if got[component] != image {
+					t.Errorf("image of %s = %q, want %q", component, got[component], image)
+				}
+			}
+			if !tt.wantErr && len(got) != len(DefaultImages) {
+				t.Errorf("Images() = %v, want all components", got)
+			}
+		})
+	}
+	if DefaultImages[Rekor] == "localhost/rekor-server:dev" {
+		t.Error("Images() modified the default images")
+	}
+}
+
+func TestEncryptedKeyPEM(t *testing.T) {
+	ca, err := NewCA("fulcio", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ca.Certificate.IsCA || ca.Certificate.Subject.CommonName != "fulcio" {
+		t.Errorf("unexpected CA certificate %v", ca.Certificate.Subject)
+	}
+	data, err := EncryptedKeyPEM(ca.Key, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(data)
+	//nolint:staticcheck // reading the legacy format written by EncryptedKeyPEM
+	der, err := x509.DecryptPEMBlock(block, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := x509.ParseECPrivateKey(der)
+	if err != nil || !key.Equal(ca.Key) {
+		t.Errorf("decrypted key does not match (%v)", err)
+	}
+}
+
+func publicKeyPEM(t *testing.T) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestTrustTargets(t *testing.T) {
+	fulcio, err := NewCA("fulcio", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tsaRoot, err := NewCA("tsa", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trust := &Trust{
+		Since:         time.Now().Add(-time.Minute),
+		RekorURL:      "http://127.0.0.1:3000",
+		RekorKey:      publicKeyPEM(t),
+		CTLogURL:      "http://127.0.0.1:6962/test",
+		CTLogKey:      publicKeyPEM(t),
+		FulcioURL:     "http://127.0.0.1:5555",
+		FulcioChain:   fulcio.CertificatePEM(),
+		TSAURL:        "http://127.0.0.1:3001/api/v1/timestamp",
+		TSAChain:      append(tsaRoot.CertificatePEM(), fulcio.CertificatePEM()...),
+		OIDCIssuerURL: "http://127.0.0.1:8080",
+	}
+	targets, err := trust.Targets()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var root discovery.TrustedRoot
+	if err := json.Unmarshal(targets[discovery.TrustedRootTarget], &root); err != nil {
+		t.Fatal(err)
+	}
+	if len(root.Tlogs) != 1 || root.Tlogs[0].PublicKey.KeyDetails != "PKIX_ECDSA_P384_SHA_384" || len(root.Tlogs[0].LogID.KeyID) != 32 {
+		t.Errorf("unexpected tlogs %+v", root.Tlogs)
+	}
+	if len(root.TimestampAuthorities) != 1 || len(root.TimestampAuthorities[0].CertChain.Certificates) != 2 {
+		t.Errorf("unexpected timestamp authorities %+v", root.TimestampAuthorities)
+	}
+	var signingConfig discovery.SigningConfig
+	if err := json.Unmarshal(targets[discovery.SigningConfigTarget], &signingConfig); err != nil {
+		t.Fatal(err)
+	}
+	want := &discovery.Endpoints{
+		TUF:        "http://127.0.0.1:4000",
+		Fulcio:     trust.FulcioURL,
+		Rekor:      trust.RekorURL,
+		CTlog:      trust.CTLogURL,
+		TSA:        trust.TSAURL,
+		OIDCIssuer: trust.OIDCIssuerURL,
+	}
+	cfg := &discovery.TUFConfig{Mirror: "http://127.0.0.1:4000", TrustedRoot: &root, SigningConfig: &signingConfig}
+	if got := cfg.Endpoints(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Endpoints() = %+v, want %+v", got, want)
+	}
+	if string(targets["rekor.pub"]) != string(trust.RekorKey) || string(targets["fulcio_v1.crt.pem"]) != string(trust.FulcioChain) {
+		t.Error("legacy targets do not match the trust material")
+	}
+
+	trust.CTLogKey = []byte("not a key")
+	if _, err := trust.Targets(); err == nil || !strings.HasPrefix(err.Error(), "ctlog") {
+		t.Errorf("expected ctlog error, got %v", err)
+	}
+}
+
+func TestConfigs(t *testing.T) {
+	ct := CTLogConfig(42, "/etc/ctfe/roots.pem", "/etc/ctfe/key.pem", "pass")
+	for _, want := range []string{"log_id: 42", `roots_pem_file: "/etc/ctfe/roots.pem"`, `path: "/etc/ctfe/key.pem"`, `password: "pass"`} {
+		if !strings.Contains(ct, want) {
+			t.Errorf("CT log config does not contain %s:\n%s", want, ct)
+		}
+	}
+
+	var fulcio struct {
+		OIDCIssuers map[string]struct{ IssuerURL, ClientID, Type string }
+	}
+	if err := json.Unmarshal([]byte(FulcioConfig("http://127.0.0.1:8080", "sigstore")), &fulcio); err != nil {
+		t.Fatal(err)
+	}
+	issuer := fulcio.OIDCIssuers["http://127.0.0.1:8080"]
+	if issuer.IssuerURL != "http://127.0.0.1:8080" || issuer.ClientID != "sigstore" || issuer.Type != "email" {
+		t.Errorf("unexpected Fulcio issuer %+v", issuer)
+	}
+}
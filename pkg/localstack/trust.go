@@ -0,0 +1,233 @@
+package localstack
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/securesign/sigstore-e2e/pkg/discovery"
+	"github.com/securesign/sigstore-e2e/pkg/tuf/tuftest"
+)
+
+// CA is a self-signed code signing certificate authority, e.g. the root of
+// Fulcio started with --ca=fileca.
+type CA struct {
+	Certificate *x509.Certificate
+	Key         *ecdsa.PrivateKey
+}
+
+// NewCA generates a CA valid for validity.
+func NewCA(commonName string, validity time.Duration) (*CA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName, Organization: []string{"sigstore-e2e"}},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &CA{Certificate: cert, Key: key}, nil
+}
+
+// CertificatePEM returns the PEM encoded certificate.
+func (c *CA) CertificatePEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Certificate.Raw})
+}
+
+// EncryptedKeyPEM returns key as a password protected PEM block, the format
+// read by Fulcio's file CA and the CT log.
+func EncryptedKeyPEM(key *ecdsa.PrivateKey, password string) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:staticcheck // legacy PEM encryption is what both components can read
+	block, err := x509.EncryptPEMBlock(rand.Reader, "EC PRIVATE KEY", der, []byte(password), x509.PEMCipherAES256)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+// Trust holds the URLs and keys of the running components, from which the
+// targets of the TUF repository are built.
+type Trust struct {
+	Since time.Time
+
+	// PEM encoded public keys of the logs
+	RekorURL string
+	RekorKey []byte
+	CTLogURL string
+	CTLogKey []byte
+	// PEM encoded certificate chains, leaf first
+	FulcioURL   string
+	FulcioChain []byte
+	TSAURL      string
+	TSAChain    []byte
+
+	OIDCIssuerURL string
+}
+
+// Targets returns the legacy targets (rekor.pub, ctfe.pub, fulcio_v1.crt.pem,
+// tsa.certchain.pem) together with trusted_root.json and signing_config.json.
+func (t *Trust) Targets() (map[string][]byte, error) {
+	trustedRoot, err := t.TrustedRoot()
+	if err != nil {
+		return nil, err
+	}
+	signingConfig, err := t.SigningConfig()
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{
+		tuftest.RekorTarget:           t.RekorKey,
+		tuftest.CTFETarget:            t.CTLogKey,
+		tuftest.FulcioTarget:          t.FulcioChain,
+		tuftest.TSATarget:             t.TSAChain,
+		discovery.TrustedRootTarget:   trustedRoot,
+		discovery.SigningConfigTarget: signingConfig,
+	}, nil
+}
+
+// TrustedRoot returns the trusted_root.json of the stack.
+func (t *Trust) TrustedRoot() ([]byte, error) {
+	validFor := &discovery.TimeRange{Start: t.Since.UTC()}
+	rekor, err := transparencyLog(t.RekorURL, t.RekorKey, validFor)
+	if err != nil {
+		return nil, fmt.Errorf("rekor: %w", err)
+	}
+	ctlog, err := transparencyLog(t.CTLogURL, t.CTLogKey, validFor)
+	if err != nil {
+		return nil, fmt.Errorf("ctlog: %w", err)
+	}
+	fulcio, err := certificateAuthority(t.FulcioURL, t.FulcioChain, validFor)
+	if err != nil {
+		return nil, fmt.Errorf("fulcio: %w", err)
+	}
+	tsa, err := certificateAuthority(t.TSAURL, t.TSAChain, validFor)
+	if err != nil {
+		return nil, fmt.Errorf("tsa: %w", err)
+	}
+	return json.MarshalIndent(discovery.TrustedRoot{
+		MediaType:              "application/vnd.dev.sigstore.trustedroot+json;version=0.1",
+		Tlogs:                  []discovery.TransparencyLog{*rekor},
+		CertificateAuthorities: []discovery.CertificateAuthority{*fulcio},
+		Ctlogs:                 []discovery.TransparencyLog{*ctlog},
+		TimestampAuthorities:   []discovery.CertificateAuthority{*tsa},
+	}, "", "  ")
+}
+
+// SigningConfig returns the signing_config.json v0.2 of the stack.
+func (t *Trust) SigningConfig() ([]byte, error) {
+	service := func(url string) []map[string]any {
+		return []map[string]any{{
+			"url":             url,
+			"majorApiVersion": 1,
+			"validFor":        map[string]any{"start": t.Since.UTC()},
+			"operator":        "sigstore-e2e",
+		}}
+	}
+	return json.MarshalIndent(map[string]any{
+		"mediaType":       "application/vnd.dev.sigstore.signingconfig.v0.2+json",
+		"caUrls":          service(t.FulcioURL),
+		"oidcUrls":        service(t.OIDCIssuerURL),
+		"rekorTlogUrls":   service(t.RekorURL),
+		"rekorTlogConfig": map[string]any{"selector": "ANY"},
+		"tsaUrls":         service(t.TSAURL),
+		"tsaConfig":       map[string]any{"selector": "ANY"},
+	}, "", "  ")
+}
+
+func transparencyLog(url string, keyPEM []byte, validFor *discovery.TimeRange) (*discovery.TransparencyLog, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("no PEM encoded public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	details, err := keyDetails(key)
+	if err != nil {
+		return nil, err
+	}
+	logID := sha256.Sum256(block.Bytes)
+	tlog := &discovery.TransparencyLog{
+		BaseURL:       url,
+		HashAlgorithm: "SHA2_256",
+		PublicKey:     discovery.PublicKey{RawBytes: block.Bytes, KeyDetails: details, ValidFor: validFor},
+	}
+	tlog.LogID.KeyID = logID[:]
+	return tlog, nil
+}
+
+// keyDetails returns the PublicKeyDetails enum value of the Sigstore protobuf specs for key.
+func keyDetails(key crypto.PublicKey) (string, error) {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return "PKIX_ECDSA_P256_SHA_256", nil
+		case elliptic.P384():
+			return "PKIX_ECDSA_P384_SHA_384", nil
+		case elliptic.P521():
+			return "PKIX_ECDSA_P521_SHA_512", nil
+		}
+	case ed25519.PublicKey:
+		return "PKIX_ED25519", nil
+	case *rsa.PublicKey:
+		return fmt.Sprintf("PKIX_RSA_PKCS1V15_%d_SHA256", k.N.BitLen()), nil
+	}
+	return "", fmt.Errorf("unsupported key type %T", key)
+}
+
+func certificateAuthority(url string, chainPEM []byte, validFor *discovery.TimeRange) (*discovery.CertificateAuthority, error) {
+	ca := &discovery.CertificateAuthority{URI: url, ValidFor: validFor}
+	for rest := chainPEM; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		ca.CertChain.Certificates = append(ca.CertChain.Certificates, struct {
+			RawBytes []byte `json:"rawBytes"`
+		}{block.Bytes})
+	}
+	if len(ca.CertChain.Certificates) == 0 {
+		return nil, errors.New("no PEM encoded certificate")
+	}
+	return ca, nil
+}
@@ -0,0 +1,76 @@
+package oidc
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultLeeway is how long before its expiry a cached token is renewed, so a
+// token handed to a CLI does not expire while the command runs.
+const DefaultLeeway = 30 * time.Second
+
+// FetchFunc obtains a new token. previous is the token being replaced, or nil,
+// so that its refresh token can be used.
+type FetchFunc func(ctx context.Context, previous *Token) (*Token, error)
+
+// Cache holds a token and fetches a new one shortly before it expires.
+// It is safe for concurrent use.
+type Cache struct {
+	fetch  FetchFunc
+	leeway time.Duration
+	now    func() time.Time
+
+	mu     sync.Mutex
+	token  *Token
+	expiry time.Time
+}
+
+// NewCache returns a cache renewing tokens DefaultLeeway before they expire.
+func NewCache(fetch FetchFunc) *Cache {
+	return &Cache{fetch: fetch, leeway: DefaultLeeway, now: time.Now}
+}
+
+// Token returns the cached token, or a new one if there is none or it expires
+// within the leeway.
+func (c *Cache) Token(ctx context.Context) (*Token, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if c.token != nil && (c.expiry.IsZero() || now.Add(c.leeway).Before(c.expiry)) {
+		return c.token, nil
+	}
+	token, err := c.fetch(ctx, c.token)
+	if err != nil {
+		return nil, err
+	}
+	c.token, c.expiry = token, Expiry(token, now)
+	return token, nil
+}
+
+// Expiry returns when the cached token must be renewed.
+func (c *Cache) Expiry() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.expiry
+}
+
+// Invalidate drops the cached token, e.g. after it was rejected.
+func (c *Cache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token, c.expiry = nil, time.Time{}
+}
+
+// Expiry returns when token expires: the exp claim of its access token if it
+// is a JWT, else expires_in counted from issued. The zero time means the
+// expiry is unknown and the token is kept.
+func Expiry(token *Token, issued time.Time) time.Time {
+	if claims, err := ParseClaims(token.AccessToken); err == nil && claims.Expiry != 0 {
+		return claims.ExpiresAt()
+	}
+	if token.ExpiresIn > 0 {
+		return issued.Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return time.Time{}
+}
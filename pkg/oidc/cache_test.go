@@ -0,0 +1,88 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	var previous []*Token
+	fetches := 0
+	c := NewCache(func(_ context.Context, prev *Token) (*Token, error) {
+		fetches++
+		previous = append(previous, prev)
+		exp := now.Add(5 * time.Minute).Unix()
+		return &Token{AccessToken: jwt(t, map[string]any{"exp": exp}), RefreshToken: "refresh"}, nil
+	})
+	c.now = func() time.Time { return now }
+
+	first, err := c.Token(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Expiry().Equal(now.Add(5 * time.Minute)) {
+		t.Errorf("Expiry() = %s", c.Expiry())
+	}
+
+	now = now.Add(4 * time.Minute)
+	if token, _ := c.Token(t.Context()); token != first || fetches != 1 {
+		t.Fatalf("token was renewed %d times before the leeway", fetches-1)
+	}
+
+	now = now.Add(time.Minute - DefaultLeeway)
+	if _, err := c.Token(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if fetches != 2 || previous[1] != first {
+		t.Fatalf("expected renewal with the previous token, got %d fetches, previous %v", fetches, previous)
+	}
+
+	c.Invalidate()
+	if _, err := c.Token(t.Context()); err != nil || fetches != 3 || previous[2] != nil {
+		t.Fatalf("expected a fresh fetch after Invalidate, got %d fetches, previous %v", fetches, previous[2])
+	}
+}
+
+func TestCacheError(t *testing.T) {
+	fail := true
+	c := NewCache(func(context.Context, *Token) (*Token, error) {
+		if fail {
+			return nil, errors.New("unavailable")
+		}
+		return &Token{AccessToken: "opaque"}, nil
+	})
+	if _, err := c.Token(t.Context()); err == nil {
+		t.Fatal("expected error")
+	}
+	fail = false
+	token, err := c.Token(t.Context())
+	if err != nil || token.AccessToken != "opaque" {
+		t.Fatalf("Token() = %v, %v", token, err)
+	}
+	// without exp claim and expires_in the token is kept
+	fail = true
+	if _, err := c.Token(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	issued := time.Unix(1700000000, 0)
+	tests := []struct {
+		name  string
+		token *Token
+		want  time.Time
+	}{
+		{"exp claim", &Token{AccessToken: jwt(t, map[string]any{"exp": issued.Add(time.Hour).Unix()}), ExpiresIn: 60}, issued.Add(time.Hour)},
+		{"expires_in", &Token{AccessToken: "opaque", ExpiresIn: 60}, issued.Add(time.Minute)},
+		{"unknown", &Token{AccessToken: "opaque"}, time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := Expiry(tt.token, issued); !got.Equal(tt.want) {
+			t.Errorf("%s: Expiry() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package oidc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Audience is the aud claim, which is either a string or a list of strings.
+type Audience []string
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// Claims of a JWT that Fulcio and the suites depend on.
+type Claims struct {
+	Issuer          string   `json:"iss"`
+	Subject         string   `json:"sub"`
+	Audience        Audience `json:"aud"`
+	AuthorizedParty string   `json:"azp,omitempty"`
+	Email           string   `json:"email,omitempty"`
+	EmailVerified   *bool    `json:"email_verified,omitempty"`
+	Expiry          int64    `json:"exp"`
+}
+
+// ExpiresAt returns the exp claim as time.
+func (c *Claims) ExpiresAt() time.Time {
+	return time.Unix(c.Expiry, 0)
+}
+
+// ParseClaims decodes the payload of a JWT. The signature is not verified;
+// Fulcio does that, this only catches misconfiguration early.
+func ParseClaims(jwt string) (*Claims, error) {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT payload: %w", err)
+	}
+	var c Claims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, fmt.Errorf("invalid JWT claims: %w", err)
+	}
+	return &c, nil
+}
+
+// Expectation lists the claim values the suites assert against. Empty fields
+// are not checked.
+type Expectation struct {
+	Issuer string
+	// Audience must be in aud or be the authorized party.
+	Audience string
+	// Email must match exactly; EmailDomain only the part after '@'.
+	Email       string
+	EmailDomain string
+}
+
+// Check returns an error listing every claim of c not matching e.
+func (c *Claims) Check(e Expectation, now time.Time) error {
+	var errs []error
+	if c.Expiry != 0 && !now.Before(c.ExpiresAt()) {
+		errs = append(errs, fmt.Errorf("token expired at %s", c.ExpiresAt().UTC().Format(time.RFC3339)))
+	}
+	if e.Issuer != "" && strings.TrimRight(c.Issuer, "/") != strings.TrimRight(e.Issuer, "/") {
+		errs = append(errs, fmt.Errorf("iss is %q, expected %q", c.Issuer, e.Issuer))
+	}
+	if e.Audience != "" && !slices.Contains(c.Audience, e.Audience) && c.AuthorizedParty != e.Audience {
+		errs = append(errs, fmt.Errorf("aud is %q, expected it to contain %q", []string(c.Audience), e.Audience))
+	}
+	if (e.Email != "" || e.EmailDomain != "") && c.Email == "" {
+		errs = append(errs, errors.New("token has no email claim"))
+	} else {
+		if e.Email != "" && !strings.EqualFold(c.Email, e.Email) {
+			errs = append(errs, fmt.Errorf("email is %q, expected %q", c.Email, e.Email))
+		}
+		if e.EmailDomain != "" && !strings.HasSuffix(strings.ToLower(c.Email), "@"+strings.ToLower(e.EmailDomain)) {
+			errs = append(errs, fmt.Errorf("email %q is not in domain %q", c.Email, e.EmailDomain))
+		}
+	}
+	if c.EmailVerified != nil && !*c.EmailVerified && c.Email != "" {
+		errs = append(errs, fmt.Errorf("email %q is not verified", c.Email))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,180 @@
+// Package oidc requests identity tokens from an OpenID Connect provider and
+// checks their claims before the tokens are handed to the CLIs.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Grant types supported by RequestToken.
+const (
+	GrantPassword          = "password"
+	GrantClientCredentials = "client_credentials"
+	GrantRefreshToken      = "refresh_token"
+)
+
+// Grants lists the supported grant types.
+var Grants = []string{GrantPassword, GrantClientCredentials, GrantRefreshToken}
+
+// ProviderMetadata is the subset of the OpenID provider configuration used here.
+type ProviderMetadata struct {
+	Issuer              string   `json:"issuer"`
+	TokenEndpoint       string   `json:"token_endpoint"`
+	JWKSURI             string   `json:"jwks_uri"`
+	GrantTypesSupported []string `json:"grant_types_supported"`
+}
+
+// Discover reads the provider configuration of issuer from
+// <issuer>/.well-known/openid-configuration.
+func Discover(ctx context.Context, client *http.Client, issuer string) (*ProviderMetadata, error) {
+	issuer = strings.TrimRight(issuer, "/")
+	configURL := issuer + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, configURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := httpClient(client).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot discover OIDC provider %s: %w", issuer, err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot discover OIDC provider %s: GET %s: %s", issuer, configURL, resp.Status)
+	}
+	var md ProviderMetadata
+	if err := json.Unmarshal(body, &md); err != nil {
+		return nil, fmt.Errorf("invalid OIDC provider configuration at %s: %w", configURL, err)
+	}
+	if md.TokenEndpoint == "" {
+		return nil, fmt.Errorf("OIDC provider configuration at %s has no token_endpoint", configURL)
+	}
+	if strings.TrimRight(md.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("OIDC provider at %s identifies as issuer %q", issuer, md.Issuer)
+	}
+	return &md, nil
+}
+
+// SupportsGrant reports whether the provider advertises grant. Providers that
+// do not advertise grant types are assumed to support all of them.
+func (md *ProviderMetadata) SupportsGrant(grant string) bool {
+	return len(md.GrantTypesSupported) == 0 || slices.Contains(md.GrantTypesSupported, grant)
+}
+
+// TokenRequest holds the parameters of a token request.
+type TokenRequest struct {
+	Grant        string
+	ClientID     string
+	ClientSecret string
+	// Username and Password are sent with the password grant.
+	Username string
+	Password string
+	// RefreshToken is sent with the refresh_token grant.
+	RefreshToken string
+	Scopes       []string
+}
+
+// Token is a successful token response.
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	IDToken      string `json:"id_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	TokenType    string `json:"token_type,omitempty"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
+}
+
+// Error is an OAuth 2.0 error response (RFC 6749, section 5.2).
+type Error struct {
+	StatusCode  int    `json:"-"`
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	URI         string `json:"error_uri,omitempty"`
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("token request failed with HTTP %d: %s", e.StatusCode, e.Code)
+	if e.Description != "" {
+		msg += ": " + e.Description
+	}
+	return msg
+}
+
+// RequestToken requests a token from endpoint. Error responses of the
+// provider are returned as *Error.
+func RequestToken(ctx context.Context, client *http.Client, endpoint string, tr TokenRequest) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", tr.Grant)
+	switch tr.Grant {
+	case GrantPassword:
+		form.Set("username", tr.Username)
+		form.Set("password", tr.Password)
+	case GrantClientCredentials:
+		if tr.ClientSecret == "" {
+			return nil, fmt.Errorf("the %s grant requires a client secret", tr.Grant)
+		}
+	case GrantRefreshToken:
+		if tr.RefreshToken == "" {
+			return nil, fmt.Errorf("the %s grant requires a refresh token", tr.Grant)
+		}
+		form.Set("refresh_token", tr.RefreshToken)
+	default:
+		return nil, fmt.Errorf("unsupported grant type %q (expected one of %s)", tr.Grant, strings.Join(Grants, ", "))
+	}
+	if len(tr.Scopes) > 0 {
+		form.Set("scope", strings.Join(tr.Scopes, " "))
+	}
+	form.Set("client_id", tr.ClientID)
+	if tr.ClientSecret != "" {
+		form.Set("client_secret", tr.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := httpClient(client).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		oauthErr := &Error{StatusCode: resp.StatusCode}
+		if json.Unmarshal(body, oauthErr) != nil || oauthErr.Code == "" {
+			oauthErr.Code = http.StatusText(resp.StatusCode)
+		}
+		return nil, oauthErr
+	}
+	var token Token
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("invalid token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("token response has no access_token")
+	}
+	return &token, nil
+}
+
+func httpClient(client *http.Client) *http.Client {
+	if client == nil {
+		return http.DefaultClient
+	}
+	return client
+}
@@ -0,0 +1,189 @@
+package oidc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func jwt(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString(payload) + ".sig"
+}
+
+// provider serves discovery and a token endpoint accepting jdoe/secure,
+// the client secret "client-secret" and the refresh token "refresh".
+func provider(t *testing.T, grants []string) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/realms/test/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(ProviderMetadata{
+			Issuer:              server.URL + "/realms/test",
+			TokenEndpoint:       server.URL + "/realms/test/token",
+			GrantTypesSupported: grants,
+		})
+	})
+	mux.HandleFunc("/realms/test/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("client_id") != "sigstore" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		ok := false
+		switch r.Form.Get("grant_type") {
+		case GrantPassword:
+			ok = r.Form.Get("username") == "jdoe" && r.Form.Get("password") == "secure"
+		case GrantClientCredentials:
+			ok = r.Form.Get("client_secret") == "client-secret"
+		case GrantRefreshToken:
+			ok = r.Form.Get("refresh_token") == "refresh"
+		}
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant","error_description":"Invalid user credentials"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(Token{AccessToken: "access-" + r.Form.Get("grant_type"), ExpiresIn: 300})
+	})
+	mux.HandleFunc("/broken/token", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"token_type":"bearer"}`))
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDiscover(t *testing.T) {
+	server := provider(t, []string{GrantPassword})
+	md, err := Discover(t.Context(), server.Client(), server.URL+"/realms/test/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.TokenEndpoint != server.URL+"/realms/test/token" {
+		t.Errorf("TokenEndpoint = %s", md.TokenEndpoint)
+	}
+	if !md.SupportsGrant(GrantPassword) || md.SupportsGrant(GrantClientCredentials) {
+		t.Errorf("unexpected supported grants %v", md.GrantTypesSupported)
+	}
+	if !(&ProviderMetadata{}).SupportsGrant(GrantRefreshToken) {
+		t.Error("providers not advertising grants should support all")
+	}
+
+	if _, err := Discover(t.Context(), server.Client(), server.URL+"/realms/missing"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected 404 error, got %v", err)
+	}
+}
+
+func TestRequestToken(t *testing.T) {
+	server := provider(t, nil)
+	endpoint := server.URL + "/realms/test/token"
+	tests := []struct {
+		name    string
+		request TokenRequest
+		want    string
+		wantErr string
+	}{
+		{"password", TokenRequest{Grant: GrantPassword, ClientID: "sigstore", Username: "jdoe", Password: "secure"}, "access-password", ""},
+		{"client credentials", TokenRequest{Grant: GrantClientCredentials, ClientID: "sigstore", ClientSecret: "client-secret"}, "access-client_credentials", ""},
+		{"refresh token", TokenRequest{Grant: GrantRefreshToken, ClientID: "sigstore", RefreshToken: "refresh"}, "access-refresh_token", ""},
+		{"wrong password", TokenRequest{Grant: GrantPassword, ClientID: "sigstore", Username: "jdoe", Password: "wrong"}, "",
+			"HTTP 401: invalid_grant: Invalid user credentials"},
+		{"wrong client", TokenRequest{Grant: GrantPassword, ClientID: "other"}, "", "HTTP 400: invalid_client"},
+		{"missing secret", TokenRequest{Grant: GrantClientCredentials, ClientID: "sigstore"}, "", "requires a client secret"},
+		{"missing refresh token", TokenRequest{Grant: GrantRefreshToken, ClientID: "sigstore"}, "", "requires a refresh token"},
+		{"unknown grant", TokenRequest{Grant: "implicit"}, "", "unsupported grant type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := RequestToken(t.Context(), server.Client(), endpoint, tt.request)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("RequestToken() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token.AccessToken != tt.want {
+				t.Errorf("AccessToken = %q, want %q", token.AccessToken, tt.want)
+			}
+		})
+	}
+
+	_, err := RequestToken(t.Context(), server.Client(), endpoint, TokenRequest{Grant: GrantPassword, ClientID: "sigstore"})
+	var oauthErr *Error
+	if !errors.As(err, &oauthErr) || oauthErr.Code != "invalid_grant" || oauthErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected *Error, got %#v", err)
+	}
+	if _, err := RequestToken(t.Context(), server.Client(), server.URL+"/broken/token", TokenRequest{Grant: GrantPassword}); err == nil ||
+		!strings.Contains(err.Error(), "no access_token") {
+		t.Errorf("expected missing access_token error, got %v", err)
+	}
+}
+
+func TestClaimsCheck(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	valid := map[string]any{
+		"iss":   "https://keycloak.example.com/realms/tas",
+		"aud":   []string{"account", "trusted-artifact-signer"},
+		"azp":   "trusted-artifact-signer",
+		"email": "jdoe@redhat.com",
+		"exp":   now.Add(time.Minute).Unix(),
+	}
+	expected := Expectation{
+		Issuer:      "https://keycloak.example.com/realms/tas/",
+		Audience:    "trusted-artifact-signer",
+		Email:       "jdoe@redhat.com",
+		EmailDomain: "redhat.com",
+	}
+	tests := []struct {
+		name    string
+		change  map[string]any
+		wantErr string
+	}{
+		{"valid", nil, ""},
+		{"audience as string", map[string]any{"aud": "trusted-artifact-signer"}, ""},
+		{"authorized party", map[string]any{"aud": "account"}, ""},
+		{"wrong issuer", map[string]any{"iss": "https://other.example.com"}, "iss is"},
+		{"wrong audience", map[string]any{"aud": "account", "azp": "other"}, "aud is"},
+		{"wrong email", map[string]any{"email": "other@example.com"}, "not in domain"},
+		{"no email", map[string]any{"email": ""}, "no email claim"},
+		{"unverified email", map[string]any{"email_verified": false}, "not verified"},
+		{"expired", map[string]any{"exp": now.Add(-time.Minute).Unix()}, "token expired"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := map[string]any{}
+			for k, v := range valid {
+				claims[k] = v
+			}
+			for k, v := range tt.change {
+				claims[k] = v
+			}
+			c, err := ParseClaims(jwt(t, claims))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = c.Check(expected, now)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Check() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := ParseClaims("opaque-token"); err == nil {
+		t.Error("expected error for a token that is not a JWT")
+	}
+}
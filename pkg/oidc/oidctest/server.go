@@ -0,0 +1,301 @@
+// Package oidctest provides an in-process OpenID Connect provider minting
+// tokens for arbitrary identities, including invalid ones.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/securesign/sigstore-e2e/pkg/oidc"
+)
+
+// DefaultTokenLifetime is the lifetime of tokens of identities without ExpiresIn.
+const DefaultTokenLifetime = 5 * time.Minute
+
+// Identity holds the claims of the tokens minted for a subject.
+type Identity struct {
+	Subject string
+	Email   string
+	// UnverifiedEmail sets email_verified to false.
+	UnverifiedEmail bool
+	Groups          []string
+	// FederatedClaims is the Dex federated_claims claim, e.g. connector_id and user_id.
+	FederatedClaims map[string]string
+	// Audience overrides the client ID the token was requested for.
+	Audience string
+	// Issuer overrides the URL of the server.
+	Issuer string
+	// ExpiresIn is the token lifetime; a negative value mints expired tokens.
+	ExpiresIn time.Duration
+	// Claims are added to the token, e.g. the claims of GitHubActions.
+	Claims map[string]any
+}
+
+// Expired returns a copy of i whose tokens expired a minute ago.
+func (i Identity) Expired() Identity {
+	i.ExpiresIn = -time.Minute
+	return i
+}
+
+// WithAudience returns a copy of i whose tokens are issued for audience.
+func (i Identity) WithAudience(audience string) Identity {
+	i.Audience = audience
+	return i
+}
+
+// WithUnverifiedEmail returns a copy of i whose email is not verified.
+func (i Identity) WithUnverifiedEmail() Identity {
+	i.UnverifiedEmail = true
+	return i
+}
+
+// Email returns a user identity with the email as subject.
+func Email(email string) Identity {
+	return Identity{Subject: email, Email: email}
+}
+
+// GitHubActions returns the identity of a workflow run of repository at ref,
+// with the claims GitHub Actions adds to its tokens.
+func GitHubActions(repository string, workflow string, ref string) Identity {
+	owner, _, _ := strings.Cut(repository, "/")
+	workflowRef := repository + "/.github/workflows/" + workflow + "@" + ref
+	return Identity{
+		Subject: "repo:" + repository + ":ref:" + ref,
+		Claims: map[string]any{
+			"repository":         repository,
+			"repository_owner":   owner,
+			"ref":                ref,
+			"sha":                strings.Repeat("0", 40),
+			"workflow":           workflow,
+			"workflow_ref":       workflowRef,
+			"job_workflow_ref":   workflowRef,
+			"event_name":         "push",
+			"runner_environment": "github-hosted",
+		},
+	}
+}
+
+// Server is an OIDC provider with discovery, JWKS and a token endpoint
+// supporting the password, client_credentials and refresh_token grants.
+type Server struct {
+	URL string
+
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	keyID  string
+
+	mu      sync.Mutex
+	users   map[string]credential
+	clients map[string]credential
+	refresh map[string]credential
+}
+
+type credential struct {
+	secret   string
+	identity Identity
+}
+
+// NewServer starts a provider. Close it when done.
+func NewServer() (*Server, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(key.N.Bytes())
+	s := &Server{
+		key:     key,
+		keyID:   hex.EncodeToString(sum[:8]),
+		users:   map[string]credential{},
+		clients: map[string]credential{},
+		refresh: map[string]credential{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/keys", s.jwks)
+	mux.HandleFunc("/token", s.token)
+	s.server = httptest.NewServer(mux)
+	s.URL = s.server.URL
+	return s, nil
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// AddUser lets username obtain tokens of identity with the password grant.
+func (s *Server) AddUser(username string, password string, identity Identity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[username] = credential{password, identity}
+}
+
+// AddClient lets clientID obtain tokens of identity with the client_credentials grant.
+func (s *Server) AddClient(clientID string, secret string, identity Identity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients[clientID] = credential{secret, identity}
+}
+
+// Mint returns a token of identity issued for audience.
+func (s *Server) Mint(identity Identity, audience string) (string, error) {
+	now := time.Now()
+	lifetime := identity.ExpiresIn
+	if lifetime == 0 {
+		lifetime = DefaultTokenLifetime
+	}
+	claims := map[string]any{}
+	maps.Copy(claims, identity.Claims)
+	claims["iss"] = firstNonEmpty(identity.Issuer, s.URL)
+	claims["sub"] = identity.Subject
+	claims["aud"] = firstNonEmpty(identity.Audience, audience)
+	claims["iat"] = now.Unix()
+	claims["nbf"] = now.Add(min(lifetime, 0) - time.Minute).Unix()
+	claims["exp"] = now.Add(lifetime).Unix()
+	if identity.Email != "" {
+		claims["email"] = identity.Email
+		claims["email_verified"] = !identity.UnverifiedEmail
+	}
+	if len(identity.Groups) > 0 {
+		claims["groups"] = identity.Groups
+	}
+	if len(identity.FederatedClaims) > 0 {
+		claims["federated_claims"] = identity.FederatedClaims
+	}
+	return s.sign(claims)
+}
+
+func (s *Server) sign(claims map[string]any) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": s.keyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func (s *Server) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                s.URL,
+		"token_endpoint":                        s.URL + "/token",
+		"jwks_uri":                              s.URL + "/keys",
+		"grant_types_supported":                 oidc.Grants,
+		"response_types_supported":              []string{"code", "id_token"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"scopes_supported":                      []string{"openid", "email", "profile"},
+		"claims_supported":                      []string{"iss", "sub", "aud", "exp", "iat", "email", "email_verified", "groups", "federated_claims"},
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"alg": "RS256",
+		"use": "sig",
+		"kid": s.keyID,
+		"n":   base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+	}}})
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, &oidc.Error{Code: "invalid_request"})
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, &oidc.Error{Code: "invalid_request", Description: err.Error()})
+		return
+	}
+	clientID := r.Form.Get("client_id")
+	if clientID == "" {
+		writeJSON(w, http.StatusBadRequest, &oidc.Error{Code: "invalid_client", Description: "missing client_id"})
+		return
+	}
+
+	s.mu.Lock()
+	var c credential
+	var ok bool
+	switch grant := r.Form.Get("grant_type"); grant {
+	case oidc.GrantPassword:
+		c, ok = s.users[r.Form.Get("username")]
+		ok = ok && c.secret == r.Form.Get("password")
+	case oidc.GrantClientCredentials:
+		c, ok = s.clients[clientID]
+		ok = ok && c.secret == r.Form.Get("client_secret")
+	case oidc.GrantRefreshToken:
+		c, ok = s.refresh[r.Form.Get("refresh_token")]
+		delete(s.refresh, r.Form.Get("refresh_token"))
+	default:
+		s.mu.Unlock()
+		writeJSON(w, http.StatusBadRequest, &oidc.Error{Code: "unsupported_grant_type", Description: fmt.Sprintf("grant %q is not supported", grant)})
+		return
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, &oidc.Error{Code: "invalid_grant", Description: "Invalid user credentials"})
+		return
+	}
+
+	token, err := s.Mint(c.identity, clientID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, &oidc.Error{Code: "server_error", Description: err.Error()})
+		return
+	}
+	refresh := make([]byte, 16)
+	_, _ = rand.Read(refresh)
+	refreshToken := hex.EncodeToString(refresh)
+	s.mu.Lock()
+	s.refresh[refreshToken] = c
+	s.mu.Unlock()
+
+	lifetime := c.identity.ExpiresIn
+	if lifetime == 0 {
+		lifetime = DefaultTokenLifetime
+	}
+	writeJSON(w, http.StatusOK, &oidc.Token{
+		AccessToken:  token,
+		IDToken:      token,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(max(lifetime, 0) / time.Second),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
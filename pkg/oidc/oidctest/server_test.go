@@ -0,0 +1,147 @@
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/securesign/sigstore-e2e/pkg/oidc"
+)
+
+func newServer(t *testing.T) *Server {
+	t.Helper()
+	s, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+// verify checks the signature of token with the key served at the JWKS URI.
+func verify(t *testing.T, jwksURI string, token string) {
+	t.Helper()
+	resp, err := http.Get(jwksURI) //nolint:noctx
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var jwks struct {
+		Keys []struct{ N, E string }
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil || len(jwks.Keys) != 1 {
+		t.Fatalf("invalid JWKS: %v", err)
+	}
+	n, _ := base64.RawURLEncoding.DecodeString(jwks.Keys[0].N)
+	e, _ := base64.RawURLEncoding.DecodeString(jwks.Keys[0].E)
+	key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+
+	parts := strings.Split(token, ".")
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		t.Fatalf("token signature does not verify: %v", err)
+	}
+}
+
+func TestPasswordGrant(t *testing.T) {
+	s := newServer(t)
+	s.AddUser("jdoe", "secure", Identity{Subject: "jdoe", Email: "jdoe@example.com", Groups: []string{"signers"}})
+
+	provider, err := oidc.Discover(t.Context(), nil, s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := oidc.TokenRequest{Grant: oidc.GrantPassword, ClientID: "sigstore", Username: "jdoe", Password: "secure"}
+	token, err := oidc.RequestToken(t.Context(), nil, provider.TokenEndpoint, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verify(t, provider.JWKSURI, token.AccessToken)
+	claims, err := oidc.ParseClaims(token.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := oidc.Expectation{Issuer: s.URL, Audience: "sigstore", Email: "jdoe@example.com"}
+	if err := claims.Check(expected, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	refreshed, err := oidc.RequestToken(t.Context(), nil, provider.TokenEndpoint,
+		oidc.TokenRequest{Grant: oidc.GrantRefreshToken, ClientID: "sigstore", RefreshToken: token.RefreshToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.RefreshToken == token.RefreshToken {
+		t.Error("refresh token was not rotated")
+	}
+
+	request.Password = "wrong"
+	_, err = oidc.RequestToken(t.Context(), nil, provider.TokenEndpoint, request)
+	var oauthErr *oidc.Error
+	if !errors.As(err, &oauthErr) || oauthErr.Code != "invalid_grant" {
+		t.Fatalf("expected invalid_grant, got %v", err)
+	}
+}
+
+func TestClientCredentialsGrant(t *testing.T) {
+	s := newServer(t)
+	s.AddClient("ci", "ci-secret", GitHubActions("securesign/sigstore-e2e", "e2e.yml", "refs/heads/main"))
+
+	token, err := oidc.RequestToken(t.Context(), nil, s.URL+"/token",
+		oidc.TokenRequest{Grant: oidc.GrantClientCredentials, ClientID: "ci", ClientSecret: "ci-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, _ := base64.RawURLEncoding.DecodeString(strings.Split(token.AccessToken, ".")[1])
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims["sub"] != "repo:securesign/sigstore-e2e:ref:refs/heads/main" ||
+		claims["job_workflow_ref"] != "securesign/sigstore-e2e/.github/workflows/e2e.yml@refs/heads/main" ||
+		claims["aud"] != "ci" {
+		t.Errorf("unexpected claims %v", claims)
+	}
+}
+
+func TestNegativeIdentities(t *testing.T) {
+	s := newServer(t)
+	base := Email("jdoe@example.com")
+	base.FederatedClaims = map[string]string{"connector_id": "https://github.com/login/oauth", "user_id": "1"}
+	expected := oidc.Expectation{Issuer: s.URL, Audience: "sigstore", Email: "jdoe@example.com"}
+	tests := map[string]struct {
+		identity Identity
+		wantErr  string
+	}{
+		"valid":          {base, ""},
+		"expired":        {base.Expired(), "token expired"},
+		"wrong audience": {base.WithAudience("other"), "aud is"},
+		"unverified":     {base.WithUnverifiedEmail(), "not verified"},
+		"other issuer":   {Identity{Email: "jdoe@example.com", Issuer: "https://other.example.com"}, "iss is"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			token, err := s.Mint(tt.identity, "sigstore")
+			if err != nil {
+				t.Fatal(err)
+			}
+			claims, err := oidc.ParseClaims(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = claims.Check(expected, time.Now())
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Check() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,340 @@
+// Package preflight probes the services of a deployment before the suites run
+// and reports which of them are unusable, and why.
+package preflight
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/securesign/sigstore-e2e/pkg/api"
+)
+
+// Services probed by Run.
+const (
+	TUF     = "TUF"
+	Fulcio  = "Fulcio"
+	Rekor   = "Rekor"
+	TSA     = "TSA"
+	OIDC    = "OIDC"
+	RekorUI = "Rekor UI"
+)
+
+var (
+	// ProbeTimeout bounds a single probe.
+	ProbeTimeout = 10 * time.Second
+	// ExpiryWarning is how long before its expiry a TLS certificate or the
+	// TUF root is reported.
+	ExpiryWarning = 14 * 24 * time.Hour
+)
+
+// Service is a service of the deployment configured by the URL in Key.
+type Service struct {
+	Name string
+	Key  string
+	// probe checks the service at base and returns the URL it requested
+	// with the warnings about it.
+	probe func(ctx context.Context, client *http.Client, base string) (string, []string, error)
+}
+
+// Services lists the services in the order they are reported.
+var Services = []Service{
+	{Name: TUF, Key: api.TufURL, probe: probeTUF},
+	{Name: Fulcio, Key: api.FulcioURL, probe: probeFulcio},
+	{Name: Rekor, Key: api.RekorURL, probe: probeRekor},
+	{Name: TSA, Key: api.TsaURL, probe: probeTSA},
+	{Name: OIDC, Key: api.OidcIssuerURL, probe: probeOIDC},
+	{Name: RekorUI, Key: api.RekorUIURL, probe: probeRekorUI},
+}
+
+// Lookup returns the service called name.
+func Lookup(name string) (Service, bool) {
+	for _, service := range Services {
+		if service.Name == name {
+			return service, true
+		}
+	}
+	return Service{}, false
+}
+
+// Status of a probed service.
+type Status string
+
+const (
+	StatusOK      Status = "ok"
+	StatusWarning Status = "warning"
+	StatusFailed  Status = "failed"
+	// StatusSkipped is the status of services that are not configured.
+	StatusSkipped Status = "skipped"
+)
+
+// Result is the outcome of probing a service.
+type Result struct {
+	Service string
+	URL     string
+	// Probe is the URL that was requested.
+	Probe  string
+	Status Status
+	// Reason explains a failure, warnings or why the service was skipped.
+	Reason string
+	// NotAfter is the expiry of the TLS certificate of the service, zero
+	// without TLS.
+	NotAfter time.Time
+	Duration time.Duration
+}
+
+func (r Result) String() string {
+	if r.Reason == "" {
+		return fmt.Sprintf("%s at %s is %s", r.Service, r.URL, r.Status)
+	}
+	if r.URL == "" {
+		return fmt.Sprintf("%s is %s: %s", r.Service, r.Status, r.Reason)
+	}
+	return fmt.Sprintf("%s at %s is %s: %s", r.Service, r.URL, r.Status, r.Reason)
+}
+
+// Check probes service at rawURL. A service answering with certificates or
+// metadata that expire within ExpiryWarning is reported with StatusWarning.
+func Check(ctx context.Context, client *http.Client, service Service, rawURL string) Result {
+	result := Result{Service: service.Name, URL: rawURL}
+	if rawURL == "" {
+		result.Status = StatusSkipped
+		result.Reason = service.Key + " is not set"
+		return result
+	}
+	ctx, cancel := context.WithTimeout(ctx, ProbeTimeout)
+	defer cancel()
+	start := time.Now()
+	tracked := &tlsTracker{client: client}
+	probe, warnings, err := service.probe(ctx, tracked.Client(), strings.TrimRight(rawURL, "/"))
+	result.Duration = time.Since(start)
+	result.Probe = probe
+	result.NotAfter = tracked.notAfter
+	if !result.NotAfter.IsZero() && time.Until(result.NotAfter) < ExpiryWarning {
+		warnings = append(warnings, fmt.Sprintf("TLS certificate expires %s", result.NotAfter.UTC().Format(time.RFC3339)))
+	}
+	switch {
+	case err != nil:
+		result.Status = StatusFailed
+		result.Reason = describe(err)
+	case len(warnings) > 0:
+		result.Status = StatusWarning
+		result.Reason = strings.Join(warnings, ", ")
+	default:
+		result.Status = StatusOK
+	}
+	return result
+}
+
+// Run probes the configured services concurrently.
+func Run(ctx context.Context, client *http.Client) Report {
+	report := make(Report, len(Services))
+	var wg sync.WaitGroup
+	for i, service := range Services {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report[i] = Check(ctx, client, service, api.GetValueFor(service.Key))
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+// describe points out TLS failures, which are otherwise buried in the error
+// of the HTTP client.
+func describe(err error) string {
+	var verification *tls.CertificateVerificationError
+	if errors.As(err, &verification) {
+		return "invalid TLS certificate: " + err.Error()
+	}
+	return err.Error()
+}
+
+// tlsTracker records the earliest expiry of the leaf certificates the client
+// was served.
+type tlsTracker struct {
+	client   *http.Client
+	mu       sync.Mutex
+	notAfter time.Time
+}
+
+func (t *tlsTracker) Client() *http.Client {
+	client := *t.client
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	client.Transport = roundTripper(func(req *http.Request) (*http.Response, error) {
+		resp, err := transport.RoundTrip(req)
+		if err == nil && resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+			t.mu.Lock()
+			if notAfter := resp.TLS.PeerCertificates[0].NotAfter; t.notAfter.IsZero() || notAfter.Before(t.notAfter) {
+				t.notAfter = notAfter
+			}
+			t.mu.Unlock()
+		}
+		return resp, err
+	})
+	return &client
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// get requests url and returns the body of a 200 response.
+func get(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return body, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	body, err := get(ctx, client, url)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("GET %s: invalid JSON: %w", url, err)
+	}
+	return nil
+}
+
+// getCertificates requires a PEM encoded certificate chain at url and
+// returns warnings for certificates expiring within ExpiryWarning.
+func getCertificates(ctx context.Context, client *http.Client, url string) ([]string, error) {
+	body, err := get(ctx, client, url)
+	if err != nil {
+		return nil, err
+	}
+	var warnings []string
+	found := false
+	for rest := body; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("GET %s: %w", url, err)
+		}
+		found = true
+		switch {
+		case time.Now().After(cert.NotAfter):
+			return nil, fmt.Errorf("GET %s: certificate %q expired %s", url, cert.Subject.CommonName, cert.NotAfter.UTC().Format(time.RFC3339))
+		case time.Until(cert.NotAfter) < ExpiryWarning:
+			warnings = append(warnings, fmt.Sprintf("certificate %q expires %s", cert.Subject.CommonName, cert.NotAfter.UTC().Format(time.RFC3339)))
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("GET %s: no PEM encoded certificate", url)
+	}
+	return warnings, nil
+}
+
+func probeTUF(ctx context.Context, client *http.Client, base string) (string, []string, error) {
+	url := base + "/root.json"
+	var root struct {
+		Signed struct {
+			Type    string    `json:"_type"`
+			Version int       `json:"version"`
+			Expires time.Time `json:"expires"`
+		} `json:"signed"`
+	}
+	if err := getJSON(ctx, client, url, &root); err != nil {
+		return url, nil, err
+	}
+	switch expires := root.Signed.Expires; {
+	case root.Signed.Type != "root":
+		return url, nil, fmt.Errorf("GET %s: not TUF root metadata", url)
+	case time.Now().After(expires):
+		return url, nil, fmt.Errorf("root.json version %d expired %s", root.Signed.Version, expires.UTC().Format(time.RFC3339))
+	case time.Until(expires) < ExpiryWarning:
+		return url, []string{fmt.Sprintf("root.json version %d expires %s", root.Signed.Version, expires.UTC().Format(time.RFC3339))}, nil
+	}
+	return url, nil, nil
+}
+
+func probeFulcio(ctx context.Context, client *http.Client, base string) (string, []string, error) {
+	url := base + "/api/v1/rootCert"
+	warnings, err := getCertificates(ctx, client, url)
+	return url, warnings, err
+}
+
+func probeRekor(ctx context.Context, client *http.Client, base string) (string, []string, error) {
+	url := base + "/api/v1/log"
+	var info struct {
+		RootHash string `json:"rootHash"`
+	}
+	if err := getJSON(ctx, client, url, &info); err != nil {
+		return url, nil, err
+	}
+	if info.RootHash == "" {
+		return url, nil, fmt.Errorf("GET %s: no root hash", url)
+	}
+	return url, nil, nil
+}
+
+// probeTSA fetches the certificate chain, TSA_URL being either the server or
+// its timestamp endpoint.
+func probeTSA(ctx context.Context, client *http.Client, base string) (string, []string, error) {
+	if !strings.HasSuffix(base, "/api/v1/timestamp") {
+		base += "/api/v1/timestamp"
+	}
+	url := base + "/certchain"
+	warnings, err := getCertificates(ctx, client, url)
+	return url, warnings, err
+}
+
+func probeOIDC(ctx context.Context, client *http.Client, base string) (string, []string, error) {
+	url := base + "/.well-known/openid-configuration"
+	var discovery struct {
+		Issuer        string `json:"issuer"`
+		TokenEndpoint string `json:"token_endpoint"`
+	}
+	if err := getJSON(ctx, client, url, &discovery); err != nil {
+		return url, nil, err
+	}
+	if strings.TrimRight(discovery.Issuer, "/") != base {
+		return url, nil, fmt.Errorf("issuer %q does not match the configured URL", discovery.Issuer)
+	}
+	if discovery.TokenEndpoint == "" {
+		return url, nil, fmt.Errorf("GET %s: no token endpoint", url)
+	}
+	return url, nil, nil
+}
+
+func probeRekorUI(ctx context.Context, client *http.Client, base string) (string, []string, error) {
+	url := base + "/"
+	_, err := get(ctx, client, url)
+	return url, nil, err
+}
@@ -0,0 +1,175 @@
+package preflight
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/securesign/sigstore-e2e/pkg/localstack"
+	"github.com/securesign/sigstore-e2e/pkg/oidc/oidctest"
+	"github.com/securesign/sigstore-e2e/pkg/rekor/rekortest"
+	"github.com/securesign/sigstore-e2e/pkg/tuf/tuftest"
+)
+
+// serve starts a server answering path with status and body.
+func serve(t *testing.T, path string, status int, body string) string {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func certificate(t *testing.T, validity time.Duration) string {
+	t.Helper()
+	ca, err := localstack.NewCA("preflight", validity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(ca.CertificatePEM())
+}
+
+func mustLookup(t *testing.T, name string) Service {
+	t.Helper()
+	service, ok := Lookup(name)
+	if !ok {
+		t.Fatalf("unknown service %s", name)
+	}
+	return service
+}
+
+func TestCheck(t *testing.T) {
+	defer func(expiry time.Duration) { ExpiryWarning = expiry }(ExpiryWarning)
+	ExpiryWarning = time.Hour
+
+	tuf, err := tuftest.NewServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tuf.Close)
+	expiredTUF, err := tuftest.NewServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(expiredTUF.Close)
+	expiredTUF.SetFault(tuftest.FaultExpired)
+	tlsTUF, err := tuftest.NewTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tlsTUF.Close)
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(tlsTUF.Certificate())
+	trusting := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}}}
+
+	rekor, err := rekortest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(rekor.Close)
+	issuer, err := oidctest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(issuer.Close)
+
+	chain := certificate(t, 24*time.Hour)
+	tsa := serve(t, "/api/v1/timestamp/certchain", http.StatusOK, chain)
+
+	tests := []struct {
+		name        string
+		service     string
+		url         string
+		client      *http.Client
+		expiry      time.Duration
+		wantStatus  Status
+		wantReason  string
+		wantProbe   string
+		wantTLSTime bool
+	}{
+		{name: "not configured", service: Fulcio, wantStatus: StatusSkipped, wantReason: "FULCIO_URL is not set"},
+		{name: "tuf", service: TUF, url: tuf.URL + "/", wantStatus: StatusOK, wantProbe: tuf.URL + "/root.json"},
+		{name: "expired tuf root", service: TUF, url: expiredTUF.URL, wantStatus: StatusFailed, wantReason: "root.json version 1 expired"},
+		{name: "tuf root about to expire", service: TUF, url: tuf.URL, expiry: 48 * time.Hour, wantStatus: StatusWarning, wantReason: "root.json version 1 expires"},
+		{name: "trusted tls", service: TUF, url: tlsTUF.URL, client: trusting, wantStatus: StatusOK, wantTLSTime: true},
+		{name: "tls certificate about to expire", service: TUF, url: tlsTUF.URL, client: trusting, expiry: 100 * 365 * 24 * time.Hour,
+			wantStatus: StatusWarning, wantReason: "TLS certificate expires", wantTLSTime: true},
+		{name: "untrusted tls", service: TUF, url: tlsTUF.URL, wantStatus: StatusFailed, wantReason: "invalid TLS certificate"},
+		{name: "rekor", service: Rekor, url: rekor.URL, wantStatus: StatusOK, wantProbe: rekor.URL + "/api/v1/log"},
+		{name: "rekor down", service: Rekor, url: serve(t, "/api/v1/log", http.StatusServiceUnavailable, ""),
+			wantStatus: StatusFailed, wantReason: "503 Service Unavailable"},
+		{name: "rekor without root hash", service: Rekor, url: serve(t, "/api/v1/log", http.StatusOK, `{"treeSize": 0}`),
+			wantStatus: StatusFailed, wantReason: "no root hash"},
+		{name: "fulcio", service: Fulcio, url: serve(t, "/api/v1/rootCert", http.StatusOK, chain), wantStatus: StatusOK},
+		{name: "fulcio certificate about to expire", service: Fulcio, url: serve(t, "/api/v1/rootCert", http.StatusOK, certificate(t, 30*time.Minute)),
+			wantStatus: StatusWarning, wantReason: `certificate "preflight" expires`},
+		{name: "fulcio without certificate", service: Fulcio, url: serve(t, "/api/v1/rootCert", http.StatusOK, "{}"),
+			wantStatus: StatusFailed, wantReason: "no PEM encoded certificate"},
+		{name: "tsa server", service: TSA, url: tsa, wantStatus: StatusOK, wantProbe: tsa + "/api/v1/timestamp/certchain"},
+		{name: "tsa endpoint", service: TSA, url: tsa + "/api/v1/timestamp", wantStatus: StatusOK, wantProbe: tsa + "/api/v1/timestamp/certchain"},
+		{name: "oidc", service: OIDC, url: issuer.URL, wantStatus: StatusOK, wantProbe: issuer.URL + "/.well-known/openid-configuration"},
+		{name: "oidc issuer mismatch", service: OIDC,
+			url:        serve(t, "/.well-known/openid-configuration", http.StatusOK, `{"issuer": "https://keycloak.example.com/realms/other", "token_endpoint": "/token"}`),
+			wantStatus: StatusFailed, wantReason: `issuer "https://keycloak.example.com/realms/other" does not match`},
+		{name: "rekor ui unreachable", service: RekorUI, url: "http://127.0.0.1:1", wantStatus: StatusFailed, wantReason: "connection refused"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.expiry != 0 {
+				ExpiryWarning = tt.expiry
+				defer func() { ExpiryWarning = time.Hour }()
+			}
+			client := tt.client
+			if client == nil {
+				client = &http.Client{}
+			}
+			got := Check(t.Context(), client, mustLookup(t, tt.service), tt.url)
+			if got.Status != tt.wantStatus || !strings.Contains(got.Reason, tt.wantReason) {
+				t.Errorf("Check() = %s, want %s containing %q", got, tt.wantStatus, tt.wantReason)
+			}
+			if tt.wantProbe != "" && got.Probe != tt.wantProbe {
+				t.Errorf("probed %s, want %s", got.Probe, tt.wantProbe)
+			}
+			if got.NotAfter.IsZero() == tt.wantTLSTime {
+				t.Errorf("TLS expiry = %v, want set: %v", got.NotAfter, tt.wantTLSTime)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	report := Report{
+		{Service: TUF, URL: "https://tuf.example.com", Status: StatusOK, NotAfter: time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC), Duration: 12 * time.Millisecond},
+		{Service: Rekor, URL: "https://rekor.example.com", Status: StatusFailed, Reason: "GET https://rekor.example.com/api/v1/log: 503 Service Unavailable"},
+		{Service: TSA, Status: StatusSkipped, Reason: "TSA_URL is not set"},
+	}
+	lines := strings.Split(report.String(), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "SERVICE") {
+		t.Fatalf("unexpected report:\n%s", report)
+	}
+	for i, want := range [][]string{
+		{"TUF", "ok", "2030-01-02", "12ms"},
+		{"Rekor", "failed", "503 Service Unavailable"},
+		{"TSA", "skipped", "TSA_URL is not set"},
+	} {
+		for _, field := range want {
+			if !strings.Contains(lines[i+1], field) {
+				t.Errorf("line %q does not contain %q", lines[i+1], field)
+			}
+		}
+	}
+	if failed := report.Failed(); len(failed) != 1 || failed[0].Service != Rekor {
+		t.Errorf("Failed() = %v", failed)
+	}
+	if result, ok := report.Result(TSA); !ok || result.Status != StatusSkipped {
+		t.Errorf("Result(TSA) = %v, %v", result, ok)
+	}
+}
@@ -0,0 +1,58 @@
+package preflight
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Report holds the results of Run, one per service.
+type Report []Result
+
+// Result returns the result of the service called name.
+func (r Report) Result(name string) (Result, bool) {
+	for _, result := range r {
+		if result.Service == name {
+			return result, true
+		}
+	}
+	return Result{}, false
+}
+
+// Failed returns the results of the services that failed their probe.
+func (r Report) Failed() []Result {
+	var failed []Result
+	for _, result := range r {
+		if result.Status == StatusFailed {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// String renders the report as a table.
+func (r Report) String() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVICE\tSTATUS\tURL\tTLS EXPIRY\tTIME\tREASON")
+	for _, result := range r {
+		expiry, took := "-", "-"
+		if !result.NotAfter.IsZero() {
+			expiry = result.NotAfter.UTC().Format(time.DateOnly)
+		}
+		if result.Status != StatusSkipped {
+			took = result.Duration.Round(time.Millisecond).String()
+		}
+		url, reason := result.URL, result.Reason
+		if url == "" {
+			url = "-"
+		}
+		if reason == "" {
+			reason = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", result.Service, result.Status, url, expiry, took, reason)
+	}
+	_ = w.Flush()
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,170 @@
+// Package redact masks identity tokens, passwords and other secrets before
+// they reach logs, error messages or transcripts.
+package redact
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/sirupsen/logrus"
+)
+
+// Mask replaces every secret.
+const Mask = "[REDACTED]"
+
+// SecretKeys are the configuration keys whose values are secrets.
+var SecretKeys = api.SecretKeys()
+
+// minSecretLength avoids masking short values (e.g. a one-letter password or
+// a PIN) in every word they appear in.
+const minSecretLength = 6
+
+// publicDefaults are secret values shipped as defaults by this repository, such
+// as the OIDC_PASSWORD of the test user. They protect nothing, so masking them
+// would only mangle the logs.
+var publicDefaults = map[string]bool{"secure": true}
+
+var (
+	jwtRegexp    = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	bearerRegexp = regexp.MustCompile(`(?i)\b(bearer\s+)[A-Za-z0-9._~+/=-]+`)
+	// keyValueRegexp matches assignments such as OIDC_PASSWORD=x, --identity-token=x,
+	// password=x in form bodies and "access_token":"x" in JSON.
+	keyValueRegexp = regexp.MustCompile(`(?i)([A-Z0-9_-]*(?:password|token|secret)["']?\s*[=:]\s*["']?)([^\s"'&,}]+)`)
+
+	mu sync.Mutex
+	// extra are the patterns of the secrets registered with AddSecrets.
+	extra []*regexp.Regexp
+	// patterns caches the compiled pattern of every secret seen so far.
+	patterns = map[string]*regexp.Regexp{}
+)
+
+// AddSecrets registers values that are masked wherever they appear, e.g. a
+// token obtained at runtime that is not a JWT. The patterns are compiled once,
+// here, and not on every log line.
+func AddSecrets(values ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, v := range values {
+		if !isSecretValue(v) {
+			continue
+		}
+		if _, seen := patterns[v]; seen {
+			continue
+		}
+		extra = append(extra, compile(v))
+	}
+}
+
+// IsSecretKey reports whether the configuration key or variable name holds a secret.
+func IsSecretKey(key string) bool {
+	key = strings.ToUpper(key)
+	for _, k := range SecretKeys {
+		if key == k {
+			return true
+		}
+	}
+	return strings.Contains(key, "TOKEN") || strings.Contains(key, "PASSWORD") || strings.Contains(key, "SECRET")
+}
+
+// String returns s with all known secrets masked.
+func String(s string) string {
+	s = jwtRegexp.ReplaceAllString(s, Mask)
+	s = bearerRegexp.ReplaceAllString(s, "${1}"+Mask)
+	s = keyValueRegexp.ReplaceAllStringFunc(s, func(m string) string {
+		parts := keyValueRegexp.FindStringSubmatch(m)
+		if parts[2] == Mask {
+			return m
+		}
+		return parts[1] + Mask
+	})
+	for _, pattern := range secretPatterns() {
+		s = pattern.ReplaceAllString(s, Mask)
+	}
+	return s
+}
+
+// Bytes is String for byte slices.
+func Bytes(b []byte) []byte {
+	return []byte(String(string(b)))
+}
+
+// Strings masks every element of values and returns a new slice.
+func Strings(values []string) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = String(v)
+	}
+	return out
+}
+
+// isSecretValue reports whether v is worth masking.
+func isSecretValue(v string) bool {
+	return len(v) >= minSecretLength && !publicDefaults[v]
+}
+
+func secretPatterns() []*regexp.Regexp {
+	var values []string
+	// raw values only: resolving a reference here could block on the network
+	// while logging
+	for _, key := range SecretKeys {
+		if v := api.Values.GetString(key); isSecretValue(v) && !api.IsSecretReference(v) {
+			values = append(values, v)
+		}
+	}
+	// secrets read through references, whatever key they were configured for
+	for _, v := range api.ResolvedSecrets() {
+		if isSecretValue(v) {
+			values = append(values, v)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// configured values can change at any time; they are compiled the first
+	// time they are seen
+	out := make([]*regexp.Regexp, 0, len(values)+len(extra))
+	for _, v := range values {
+		pattern, ok := patterns[v]
+		if !ok {
+			pattern = compile(v)
+		}
+		out = append(out, pattern)
+	}
+	return append(out, extra...)
+}
+
+// compile returns the pattern replacing secret unless it is only part of a
+// longer word, so that e.g. a password "secret" does not mangle
+// "--client-secret-file". The caller must hold mu.
+func compile(secret string) *regexp.Regexp {
+	expr := regexp.QuoteMeta(secret)
+	if isWordChar(secret[0]) {
+		expr = `\b` + expr
+	}
+	if isWordChar(secret[len(secret)-1]) {
+		expr += `\b`
+	}
+	pattern := regexp.MustCompile(expr)
+	patterns[secret] = pattern
+	return pattern
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// Formatter masks secrets in the output of the wrapped formatter, including
+// message, fields and errors.
+type Formatter struct {
+	logrus.Formatter
+}
+
+func (f *Formatter) Format(entry *logrus.Entry) ([]byte, error) {
+	out, err := f.Formatter.Format(entry)
+	if err != nil {
+		return nil, err
+	}
+	return Bytes(out), nil
+}
@@ -0,0 +1,97 @@
+package rekortest
+
+import (
+	"crypto/sha256"
+	"math/bits"
+)
+
+// tree is an append-only RFC 6962 Merkle tree keeping all leaf hashes in memory.
+type tree struct {
+	leaves [][]byte
+}
+
+func leafHash(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func nodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+func (t *tree) append(data []byte) int64 {
+	t.leaves = append(t.leaves, leafHash(data))
+	return int64(len(t.leaves) - 1)
+}
+
+func (t *tree) size() int64 {
+	return int64(len(t.leaves))
+}
+
+// root returns the root hash of the first size leaves.
+func (t *tree) root(size int64) []byte {
+	if size == 0 {
+		empty := sha256.Sum256(nil)
+		return empty[:]
+	}
+	return t.hash(0, size)
+}
+
+// hash is MTH(D[begin:end]).
+func (t *tree) hash(begin, end int64) []byte {
+	if end-begin == 1 {
+		return t.leaves[begin]
+	}
+	k := split(end - begin)
+	return nodeHash(t.hash(begin, begin+k), t.hash(begin+k, end))
+}
+
+// inclusionProof is PATH(index, D[0:size]).
+func (t *tree) inclusionProof(index, size int64) [][]byte {
+	return t.path(index, 0, size)
+}
+
+func (t *tree) path(index, begin, end int64) [][]byte {
+	if end-begin <= 1 {
+		return nil
+	}
+	k := split(end - begin)
+	if index < k {
+		return append(t.path(index, begin, begin+k), t.hash(begin+k, end))
+	}
+	return append(t.path(index-k, begin+k, end), t.hash(begin, begin+k))
+}
+
+// consistencyProof is PROOF(first, D[0:size]).
+func (t *tree) consistencyProof(first, size int64) [][]byte {
+	if first == 0 || first == size {
+		return nil
+	}
+	return t.subproof(first, 0, size, true)
+}
+
+func (t *tree) subproof(m, begin, end int64, complete bool) [][]byte {
+	n := end - begin
+	if m == n {
+		if complete {
+			return nil
+		}
+		return [][]byte{t.hash(begin, end)}
+	}
+	k := split(n)
+	if m <= k {
+		return append(t.subproof(m, begin, begin+k, complete), t.hash(begin+k, end))
+	}
+	return append(t.subproof(m-k, begin+k, end, false), t.hash(begin, begin+k))
+}
+
+// split returns the largest power of two smaller than n.
+func split(n int64) int64 {
+	return 1 << (bits.Len64(uint64(n-1)) - 1)
+}
@@ -0,0 +1,91 @@
+package rekortest
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// verifyInclusion is the verification algorithm of RFC 9162, section 2.1.3.2.
+func verifyInclusion(index, size int64, leaf []byte, proof [][]byte, root []byte) bool {
+	if index >= size {
+		return false
+	}
+	fn, sn, r := index, size-1, leaf
+	for _, p := range proof {
+		if sn == 0 {
+			return false
+		}
+		if fn&1 == 1 || fn == sn {
+			r = nodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = nodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	return sn == 0 && bytes.Equal(r, root)
+}
+
+// verifyConsistency is the verification algorithm of RFC 9162, section 2.1.4.2.
+func verifyConsistency(first, second int64, firstRoot, secondRoot []byte, proof [][]byte) bool {
+	if first == second {
+		return len(proof) == 0 && bytes.Equal(firstRoot, secondRoot)
+	}
+	if first&(first-1) == 0 {
+		proof = append([][]byte{firstRoot}, proof...)
+	}
+	if len(proof) == 0 {
+		return false
+	}
+	fn, sn := first-1, second-1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+	fr, sr := proof[0], proof[0]
+	for _, c := range proof[1:] {
+		if sn == 0 {
+			return false
+		}
+		if fn&1 == 1 || fn == sn {
+			fr, sr = nodeHash(c, fr), nodeHash(c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			sr = nodeHash(sr, c)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	return bytes.Equal(fr, firstRoot) && bytes.Equal(sr, secondRoot) && sn == 0
+}
+
+func TestTree(t *testing.T) {
+	var tr tree
+	for i := range 17 {
+		tr.append(fmt.Appendf(nil, "entry %d", i))
+	}
+	for size := int64(1); size <= tr.size(); size++ {
+		root := tr.root(size)
+		for index := range size {
+			if !verifyInclusion(index, size, tr.leaves[index], tr.inclusionProof(index, size), root) {
+				t.Errorf("inclusion proof of %d in tree of size %d does not verify", index, size)
+			}
+		}
+		for first := int64(1); first <= size; first++ {
+			if !verifyConsistency(first, size, tr.root(first), root, tr.consistencyProof(first, size)) {
+				t.Errorf("consistency proof %d -> %d does not verify", first, size)
+			}
+		}
+	}
+	if verifyInclusion(1, 4, tr.leaves[2], tr.inclusionProof(1, 4), tr.root(4)) {
+		t.Error("inclusion proof verified for the wrong leaf")
+	}
+}
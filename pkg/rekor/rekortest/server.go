@@ -0,0 +1,430 @@
+// Package rekortest provides an in-process Rekor server implementing the
+// REST subset used by the suites, backed by an in-memory Merkle tree.
+//
+// Entries are stored as proposed: signatures are not verified and only the
+// hashedrekord, rekord and dsse types are canonicalized like Rekor does, so
+// the server is meant for developing suites and parsers, not for testing
+// Rekor itself.
+package rekortest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Origin is the name of the log in its checkpoints.
+const Origin = "rekor.local"
+
+// Server is an in-process Rekor log.
+type Server struct {
+	URL    string
+	TreeID int64
+
+	server *httptest.Server
+	key    *ecdsa.PrivateKey
+	pubDER []byte
+	logID  string
+	now    func() time.Time
+
+	mu      sync.Mutex
+	tree    tree
+	entries []*entry
+	byLeaf  map[string]int64
+	index   map[string][]string
+}
+
+type entry struct {
+	body           []byte
+	integratedTime int64
+	leaf           string
+}
+
+// NewServer starts an empty log. Close it when done.
+func NewServer() (*Server, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	logID := sha256.Sum256(der)
+	s := &Server{
+		TreeID: time.Now().UnixNano() & 0x7fffffffffffffff,
+		key:    key,
+		pubDER: der,
+		logID:  hex.EncodeToString(logID[:]),
+		now:    time.Now,
+		byLeaf: map[string]int64{},
+		index:  map[string][]string{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/log", s.logInfo)
+	mux.HandleFunc("GET /api/v1/log/publicKey", s.publicKey)
+	mux.HandleFunc("GET /api/v1/log/proof", s.proof)
+	mux.HandleFunc("GET /api/v1/log/entries", s.entryByIndex)
+	mux.HandleFunc("POST /api/v1/log/entries", s.createEntry)
+	mux.HandleFunc("GET /api/v1/log/entries/{uuid}", s.entryByUUID)
+	mux.HandleFunc("POST /api/v1/log/entries/retrieve", s.retrieveEntries)
+	mux.HandleFunc("POST /api/v1/index/retrieve", s.searchIndex)
+	s.server = httptest.NewServer(mux)
+	s.URL = s.server.URL
+	return s, nil
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// PublicKeyPEM returns the key signing checkpoints and entry timestamps.
+func (s *Server) PublicKeyPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: s.pubDER})
+}
+
+// LogID is the hex encoded SHA-256 of the public key.
+func (s *Server) LogID() string {
+	return s.logID
+}
+
+// Size returns the number of entries.
+func (s *Server) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tree.size()
+}
+
+// uuid returns the entry UUID with the tree ID prefix used by sharded logs.
+func (s *Server) uuid(leaf string) string {
+	return fmt.Sprintf("%016x%s", s.TreeID, leaf)
+}
+
+// Add appends a proposed entry, as POST /api/v1/log/entries does, and returns its UUID.
+func (s *Server) Add(proposed []byte) (string, error) {
+	body, keys, err := canonicalize(proposed)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	leaf := hex.EncodeToString(leafHash(body))
+	if _, ok := s.byLeaf[leaf]; ok {
+		return s.uuid(leaf), errExists
+	}
+	index := s.tree.append(body)
+	s.byLeaf[leaf] = index
+	s.entries = append(s.entries, &entry{body: body, integratedTime: s.now().Unix(), leaf: leaf})
+	for _, key := range keys {
+		s.index[key] = append(s.index[key], s.uuid(leaf))
+	}
+	return s.uuid(leaf), nil
+}
+
+var errExists = errors.New("an equivalent entry already exists in the transparency log")
+
+type apiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...any) {
+	writeJSON(w, status, apiError{Code: status, Message: fmt.Sprintf(format, args...)})
+}
+
+func (s *Server) logInfo(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	size := s.tree.size()
+	checkpoint, err := s.checkpoint(size)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"rootHash":       hex.EncodeToString(s.tree.root(size)),
+		"treeSize":       size,
+		"signedTreeHead": checkpoint,
+		"treeID":         strconv.FormatInt(s.TreeID, 10),
+		"inactiveShards": []any{},
+	})
+}
+
+func (s *Server) publicKey(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/x-pem-file")
+	_, _ = w.Write(s.PublicKeyPEM())
+}
+
+func (s *Server) proof(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	size := s.tree.size()
+	first, err1 := strconv.ParseInt(r.URL.Query().Get("firstSize"), 10, 64)
+	last, err2 := strconv.ParseInt(r.URL.Query().Get("lastSize"), 10, 64)
+	if r.URL.Query().Get("firstSize") == "" {
+		first, err1 = 1, nil
+	}
+	if err1 != nil || err2 != nil || first < 1 || first > last || last > size {
+		writeError(w, http.StatusBadRequest, "invalid tree sizes firstSize=%d lastSize=%d (tree size %d)", first, last, size)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"rootHash": hex.EncodeToString(s.tree.root(last)),
+		"hashes":   hexes(s.tree.consistencyProof(first, last)),
+	})
+}
+
+func (s *Server) entryByIndex(w http.ResponseWriter, r *http.Request) {
+	index, err := strconv.ParseInt(r.URL.Query().Get("logIndex"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid logIndex")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if index < 0 || index >= s.tree.size() {
+		writeError(w, http.StatusNotFound, "log entry with index %d not found", index)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.logEntry(index))
+}
+
+func (s *Server) entryByUUID(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	index, ok := s.lookup(r.PathValue("uuid"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "log entry %s not found", r.PathValue("uuid"))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.logEntry(index))
+}
+
+func (s *Server) createEntry(w http.ResponseWriter, r *http.Request) {
+	proposed, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	uuid, err := s.Add(proposed)
+	switch {
+	case errors.Is(err, errExists):
+		w.Header().Set("Location", "/api/v1/log/entries/"+uuid)
+		writeError(w, http.StatusConflict, "%v", err)
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	index, _ := s.lookup(uuid)
+	w.Header().Set("Location", "/api/v1/log/entries/"+uuid)
+	writeJSON(w, http.StatusCreated, s.logEntry(index))
+}
+
+func (s *Server) retrieveEntries(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		EntryUUIDs []string          `json:"entryUUIDs"`
+		LogIndexes []int64           `json:"logIndexes"`
+		Entries    []json.RawMessage `json:"entries"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	// Proposed entries, as sent by 'rekor-cli verify', are found by the leaf
+	// of their canonical body.
+	var leaves []string
+	for _, proposed := range req.Entries {
+		body, _, err := canonicalize(proposed)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid entry: %v", err)
+			return
+		}
+		leaves = append(leaves, hex.EncodeToString(leafHash(body)))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := []map[string]any{}
+	for _, uuid := range req.EntryUUIDs {
+		if index, ok := s.lookup(uuid); ok {
+			results = append(results, s.logEntry(index))
+		}
+	}
+	for _, leaf := range leaves {
+		if index, ok := s.byLeaf[leaf]; ok {
+			results = append(results, s.logEntry(index))
+		}
+	}
+	for _, index := range req.LogIndexes {
+		if index >= 0 && index < s.tree.size() {
+			results = append(results, s.logEntry(index))
+		}
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+func (s *Server) searchIndex(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Hash      string `json:"hash"`
+		Email     string `json:"email"`
+		PublicKey struct {
+			Content string `json:"content"`
+		} `json:"publicKey"`
+		Operator string `json:"operator"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	var keys []string
+	if req.Hash != "" {
+		hash := strings.ToLower(req.Hash)
+		if !strings.Contains(hash, ":") {
+			hash = "sha256:" + hash
+		}
+		keys = append(keys, hash)
+	}
+	if req.Email != "" {
+		keys = append(keys, "email:"+strings.ToLower(req.Email))
+	}
+	if req.PublicKey.Content != "" {
+		content, err := base64.StdEncoding.DecodeString(req.PublicKey.Content)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid public key content: %v", err)
+			return
+		}
+		keys = append(keys, keyIndex(content))
+	}
+	if len(keys) == 0 {
+		writeError(w, http.StatusBadRequest, "one of hash, email or publicKey is required")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := map[string]int{}
+	var order []string
+	for _, key := range keys {
+		for _, uuid := range s.index[key] {
+			if counts[uuid] == 0 {
+				order = append(order, uuid)
+			}
+			counts[uuid]++
+		}
+	}
+	results := []string{}
+	for _, uuid := range order {
+		if req.Operator != "and" || counts[uuid] == len(keys) {
+			results = append(results, uuid)
+		}
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// lookup accepts entry UUIDs with and without tree ID prefix.
+func (s *Server) lookup(uuid string) (int64, bool) {
+	if len(uuid) == 80 {
+		uuid = uuid[16:]
+	}
+	index, ok := s.byLeaf[strings.ToLower(uuid)]
+	return index, ok
+}
+
+// logEntry returns the entry at index in the form of the Rekor LogEntry
+// model, with an inclusion proof for the current tree size.
+func (s *Server) logEntry(index int64) map[string]any {
+	e := s.entries[index]
+	size := s.tree.size()
+	body := base64.StdEncoding.EncodeToString(e.body)
+	checkpoint, err := s.checkpoint(size)
+	if err != nil {
+		checkpoint = ""
+	}
+	verification := map[string]any{
+		"inclusionProof": map[string]any{
+			"checkpoint": checkpoint,
+			"hashes":     hexes(s.tree.inclusionProof(index, size)),
+			"logIndex":   index,
+			"rootHash":   hex.EncodeToString(s.tree.root(size)),
+			"treeSize":   size,
+		},
+	}
+	if set, err := s.signedEntryTimestamp(body, e.integratedTime, index); err == nil {
+		verification["signedEntryTimestamp"] = set
+	}
+	return map[string]any{s.uuid(e.leaf): map[string]any{
+		"body":           body,
+		"integratedTime": e.integratedTime,
+		"logID":          s.logID,
+		"logIndex":       index,
+		"verification":   verification,
+	}}
+}
+
+// signedEntryTimestamp signs the canonical JSON of the entry fields, which
+// encoding/json produces for this struct as its fields are in key order.
+func (s *Server) signedEntryTimestamp(body string, integratedTime int64, index int64) (string, error) {
+	payload, err := json.Marshal(struct {
+		Body           string `json:"body"`
+		IntegratedTime int64  `json:"integratedTime"`
+		LogID          string `json:"logID"`
+		LogIndex       int64  `json:"logIndex"`
+	}{body, integratedTime, s.logID, index})
+	if err != nil {
+		return "", err
+	}
+	signature, err := s.sign(payload)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// checkpoint returns the signed note of the tree head at size.
+func (s *Server) checkpoint(size int64) (string, error) {
+	note := fmt.Sprintf("%s - %d\n%d\n%s\n", Origin, s.TreeID, size, base64.StdEncoding.EncodeToString(s.tree.root(size)))
+	signature, err := s.sign([]byte(note))
+	if err != nil {
+		return "", err
+	}
+	keyHint := sha256.Sum256(s.pubDER)
+	return fmt.Sprintf("%s\n— %s %s\n", note, Origin, base64.StdEncoding.EncodeToString(append(keyHint[:4], signature...))), nil
+}
+
+func (s *Server) sign(data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+	return s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+func hexes(hashes [][]byte) []string {
+	out := make([]string, len(hashes))
+	for i, h := range hashes {
+		out[i] = hex.EncodeToString(h)
+	}
+	return out
+}
@@ -0,0 +1,339 @@
+package rekortest
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type logEntry struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+	Verification   struct {
+		InclusionProof struct {
+			Checkpoint string   `json:"checkpoint"`
+			Hashes     []string `json:"hashes"`
+			LogIndex   int64    `json:"logIndex"`
+			RootHash   string   `json:"rootHash"`
+			TreeSize   int64    `json:"treeSize"`
+		} `json:"inclusionProof"`
+		SignedEntryTimestamp string `json:"signedEntryTimestamp"`
+	} `json:"verification"`
+}
+
+func newServer(t *testing.T) *Server {
+	t.Helper()
+	s, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+func certificate(t *testing.T, email string) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:   big.NewInt(1),
+		Subject:        pkix.Name{CommonName: "sigstore"},
+		NotBefore:      time.Now(),
+		NotAfter:       time.Now().Add(10 * time.Minute),
+		EmailAddresses: []string{email},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func hashedRekord(digest string, cert []byte) string {
+	return fmt.Sprintf(`{"apiVersion":"0.0.1","kind":"hashedrekord","spec":{"data":{"hash":{"algorithm":"sha256","value":%q}},`+
+		`"signature":{"content":"c2ln","publicKey":{"content":%q}}}}`, digest, base64.StdEncoding.EncodeToString(cert))
+}
+
+func do(t *testing.T, method string, url string, body string) (int, http.Header, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, resp.Header, data
+}
+
+func decodeEntry(t *testing.T, data []byte) (string, logEntry) {
+	t.Helper()
+	var entries map[string]logEntry
+	if err := json.Unmarshal(data, &entries); err != nil || len(entries) != 1 {
+		t.Fatalf("not a single log entry (%v): %s", err, data)
+	}
+	for uuid, e := range entries {
+		return uuid, e
+	}
+	return "", logEntry{}
+}
+
+func verify(t *testing.T, s *Server, data []byte, signature []byte) {
+	t.Helper()
+	block, _ := pem.Decode(s.PublicKeyPEM())
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256(data)
+	if !ecdsa.VerifyASN1(key.(*ecdsa.PublicKey), digest[:], signature) {
+		t.Errorf("signature of %q does not verify", data)
+	}
+}
+
+func unhex(t *testing.T, hashes ...string) [][]byte {
+	t.Helper()
+	out := make([][]byte, len(hashes))
+	for i, h := range hashes {
+		b, err := hex.DecodeString(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out[i] = b
+	}
+	return out
+}
+
+func TestServerEntries(t *testing.T) {
+	s := newServer(t)
+	cert := certificate(t, "jdoe@example.com")
+	digests := []string{strings.Repeat("a", 64), strings.Repeat("b", 64), strings.Repeat("c", 64)}
+	var uuids []string
+	for _, digest := range digests {
+		status, header, data := do(t, http.MethodPost, s.URL+"/api/v1/log/entries", hashedRekord(digest, cert))
+		if status != http.StatusCreated {
+			t.Fatalf("create entry: %d %s", status, data)
+		}
+		uuid, _ := decodeEntry(t, data)
+		if header.Get("Location") != "/api/v1/log/entries/"+uuid || len(uuid) != 80 {
+			t.Errorf("unexpected location %q of entry %s", header.Get("Location"), uuid)
+		}
+		uuids = append(uuids, uuid)
+	}
+
+	status, header, _ := do(t, http.MethodPost, s.URL+"/api/v1/log/entries", hashedRekord(digests[1], cert))
+	if status != http.StatusConflict || header.Get("Location") != "/api/v1/log/entries/"+uuids[1] {
+		t.Errorf("duplicate entry: %d, location %q", status, header.Get("Location"))
+	}
+	if status, _, _ := do(t, http.MethodPost, s.URL+"/api/v1/log/entries", `{"kind":"hashedrekord"}`); status != http.StatusBadRequest {
+		t.Errorf("invalid entry: %d", status)
+	}
+
+	_, data := mustGet(t, s.URL+"/api/v1/log/entries?logIndex=1")
+	uuid, e := decodeEntry(t, data)
+	if uuid != uuids[1] || e.LogIndex != 1 || e.LogID != s.LogID() {
+		t.Errorf("entry 1 is %s at %d of log %s", uuid, e.LogIndex, e.LogID)
+	}
+	body, _ := base64.StdEncoding.DecodeString(e.Body)
+	proof := e.Verification.InclusionProof
+	if !verifyInclusion(proof.LogIndex, proof.TreeSize, leafHash(body), unhex(t, proof.Hashes...), unhex(t, proof.RootHash)[0]) {
+		t.Error("inclusion proof of entry 1 does not verify")
+	}
+	set, _ := base64.StdEncoding.DecodeString(e.Verification.SignedEntryTimestamp)
+	verify(t, s, []byte(fmt.Sprintf(`{"body":%q,"integratedTime":%d,"logID":%q,"logIndex":%d}`, e.Body, e.IntegratedTime, e.LogID, e.LogIndex)), set)
+
+	for _, id := range []string{uuids[2], uuids[2][16:]} {
+		if uuid, _ := decodeEntry(t, mustGetBody(t, s.URL+"/api/v1/log/entries/"+id)); uuid != uuids[2] {
+			t.Errorf("entry %s is %s", id, uuid)
+		}
+	}
+	if status, _, _ := do(t, http.MethodGet, s.URL+"/api/v1/log/entries/"+strings.Repeat("0", 64), ""); status != http.StatusNotFound {
+		t.Errorf("unknown entry: %d", status)
+	}
+
+	_, _, data = do(t, http.MethodPost, s.URL+"/api/v1/log/entries/retrieve", fmt.Sprintf(`{"entryUUIDs":[%q],"logIndexes":[0,7]}`, uuids[2]))
+	var retrieved []map[string]logEntry
+	if err := json.Unmarshal(data, &retrieved); err != nil || len(retrieved) != 2 || retrieved[0][uuids[2]].LogIndex != 2 || retrieved[1][uuids[0]].LogIndex != 0 {
+		t.Errorf("unexpected retrieved entries (%v): %s", err, data)
+	}
+	_, _, data = do(t, http.MethodPost, s.URL+"/api/v1/log/entries/retrieve", fmt.Sprintf(`{"entries":[%s,%s]}`,
+		hashedRekord(digests[1], cert), hashedRekord(strings.Repeat("d", 64), cert)))
+	if err := json.Unmarshal(data, &retrieved); err != nil || len(retrieved) != 1 || retrieved[0][uuids[1]].LogIndex != 1 {
+		t.Errorf("unexpected entries retrieved by content (%v): %s", err, data)
+	}
+}
+
+func mustGet(t *testing.T, url string) (http.Header, []byte) {
+	t.Helper()
+	status, header, data := do(t, http.MethodGet, url, "")
+	if status != http.StatusOK {
+		t.Fatalf("GET %s: %d %s", url, status, data)
+	}
+	return header, data
+}
+
+func mustGetBody(t *testing.T, url string) []byte {
+	t.Helper()
+	_, data := mustGet(t, url)
+	return data
+}
+
+func TestServerSearchIndex(t *testing.T) {
+	s := newServer(t)
+	jdoe, jane := certificate(t, "jdoe@example.com"), certificate(t, "Jane@example.com")
+	a, _ := s.Add([]byte(hashedRekord(strings.Repeat("a", 64), jdoe)))
+	b, _ := s.Add([]byte(hashedRekord(strings.Repeat("b", 64), jdoe)))
+	c, _ := s.Add([]byte(hashedRekord(strings.Repeat("a", 64), jane)))
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"hash", `{"hash":"sha256:` + strings.Repeat("a", 64) + `"}`, []string{a, c}},
+		{"hash without algorithm", `{"hash":"` + strings.Repeat("B", 64) + `"}`, []string{b}},
+		{"email", `{"email":"jane@example.com"}`, []string{c}},
+		{"public key", `{"publicKey":{"format":"x509","content":"` + base64.StdEncoding.EncodeToString(jdoe) + `"}}`, []string{a, b}},
+		{"or", `{"hash":"sha256:` + strings.Repeat("b", 64) + `","email":"jane@example.com"}`, []string{b, c}},
+		{"and", `{"hash":"sha256:` + strings.Repeat("a", 64) + `","email":"jdoe@example.com","operator":"and"}`, []string{a}},
+		{"no match", `{"email":"nobody@example.com"}`, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _, data := do(t, http.MethodPost, s.URL+"/api/v1/index/retrieve", tt.query)
+			var got []string
+			if err := json.Unmarshal(data, &got); err != nil || status != http.StatusOK {
+				t.Fatalf("search: %d %s", status, data)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if status, _, _ := do(t, http.MethodPost, s.URL+"/api/v1/index/retrieve", `{}`); status != http.StatusBadRequest {
+		t.Errorf("empty query: %d", status)
+	}
+}
+
+func TestServerLog(t *testing.T) {
+	s := newServer(t)
+	cert := certificate(t, "jdoe@example.com")
+	var roots [][]byte
+	for i := range 5 {
+		if _, err := s.Add([]byte(hashedRekord(fmt.Sprintf("%064x", i), cert))); err != nil {
+			t.Fatal(err)
+		}
+		var info struct {
+			RootHash       string `json:"rootHash"`
+			TreeSize       int64  `json:"treeSize"`
+			SignedTreeHead string `json:"signedTreeHead"`
+		}
+		if err := json.Unmarshal(mustGetBody(t, s.URL+"/api/v1/log"), &info); err != nil {
+			t.Fatal(err)
+		}
+		if info.TreeSize != int64(i+1) {
+			t.Fatalf("tree size %d after %d entries", info.TreeSize, i+1)
+		}
+		root := unhex(t, info.RootHash)[0]
+		roots = append(roots, root)
+
+		note, signature, ok := strings.Cut(info.SignedTreeHead, "\n\n")
+		want := fmt.Sprintf("%s - %d\n%d\n%s", Origin, s.TreeID, i+1, base64.StdEncoding.EncodeToString(root))
+		if !ok || note != want {
+			t.Fatalf("checkpoint %q, want note %q", info.SignedTreeHead, want)
+		}
+		fields := strings.Fields(signature)
+		sig, err := base64.StdEncoding.DecodeString(fields[len(fields)-1])
+		if err != nil || len(fields) != 3 || fields[1] != Origin {
+			t.Fatalf("invalid checkpoint signature line %q", signature)
+		}
+		verify(t, s, []byte(note+"\n"), sig[4:])
+	}
+
+	for first := int64(1); first <= 5; first++ {
+		var proof struct {
+			RootHash string   `json:"rootHash"`
+			Hashes   []string `json:"hashes"`
+		}
+		if err := json.Unmarshal(mustGetBody(t, fmt.Sprintf("%s/api/v1/log/proof?firstSize=%d&lastSize=5", s.URL, first)), &proof); err != nil {
+			t.Fatal(err)
+		}
+		if !verifyConsistency(first, 5, roots[first-1], roots[4], unhex(t, proof.Hashes...)) {
+			t.Errorf("consistency proof %d -> 5 does not verify", first)
+		}
+	}
+	if status, _, _ := do(t, http.MethodGet, s.URL+"/api/v1/log/proof?firstSize=2&lastSize=6", ""); status != http.StatusBadRequest {
+		t.Errorf("proof beyond the tree size: %d", status)
+	}
+
+	header, data := mustGet(t, s.URL+"/api/v1/log/publicKey")
+	if !bytes.Equal(data, s.PublicKeyPEM()) || header.Get("Content-Type") != "application/x-pem-file" {
+		t.Errorf("unexpected public key %s", data)
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	cert := base64.StdEncoding.EncodeToString(certificate(t, "jdoe@example.com"))
+	envelope := `{"payloadType":"application/vnd.in-toto+json","payload":"` + base64.StdEncoding.EncodeToString([]byte("statement")) + `","signatures":[{"sig":"c2ln"}]}`
+	tests := []struct {
+		name     string
+		proposed string
+		spec     string
+		wantErr  bool
+	}{
+		{
+			name:     "rekord content is hashed",
+			proposed: `{"apiVersion":"0.0.1","kind":"rekord","spec":{"data":{"content":"` + base64.StdEncoding.EncodeToString([]byte("artifact")) + `"},"signature":{"content":"c2ln","publicKey":{"content":"` + cert + `"}}}}`,
+			spec:     `"hash":{"algorithm":"sha256","value":"` + fmt.Sprintf("%x", sha256.Sum256([]byte("artifact"))) + `"}`,
+		},
+		{
+			name:     "dsse envelope is hashed",
+			proposed: `{"apiVersion":"0.0.1","kind":"dsse","spec":{"proposedContent":{"envelope":` + fmt.Sprintf("%q", envelope) + `,"verifiers":["` + cert + `"]}}}`,
+			spec:     `"payloadHash":{"algorithm":"sha256","value":"` + fmt.Sprintf("%x", sha256.Sum256([]byte("statement"))) + `"}`,
+		},
+		{
+			name:     "other kinds are stored as proposed",
+			proposed: `{"apiVersion":"0.0.1","kind":"intoto","spec":{"content":{"envelope":"x"}}}`,
+			spec:     `"spec":{"content":{"envelope":"x"}}`,
+		},
+		{name: "no spec", proposed: `{"apiVersion":"0.0.1","kind":"rekord"}`, wantErr: true},
+		{name: "hashedrekord without hash", proposed: `{"apiVersion":"0.0.1","kind":"hashedrekord","spec":{"signature":{"content":"c2ln"}}}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _, err := canonicalize([]byte(tt.proposed))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("canonicalize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !strings.Contains(string(body), tt.spec) {
+				t.Errorf("body %s does not contain %s", body, tt.spec)
+			}
+		})
+	}
+}
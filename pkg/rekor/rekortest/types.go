@@ -0,0 +1,170 @@
+package rekortest
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type hash struct {
+	Algorithm string `json:"algorithm"`
+	Value     string `json:"value"`
+}
+
+func sha256Hash(data []byte) hash {
+	sum := sha256.Sum256(data)
+	return hash{Algorithm: "sha256", Value: hex.EncodeToString(sum[:])}
+}
+
+type publicKey struct {
+	Content string `json:"content"`
+}
+
+type signature struct {
+	Format    string    `json:"format,omitempty"`
+	Content   string    `json:"content"`
+	PublicKey publicKey `json:"publicKey"`
+}
+
+// canonicalize returns the entry body stored for a proposed entry, and the
+// search index keys of the entry.
+func canonicalize(proposed []byte) ([]byte, []string, error) {
+	var e struct {
+		APIVersion string          `json:"apiVersion"`
+		Kind       string          `json:"kind"`
+		Spec       json.RawMessage `json:"spec"`
+	}
+	if err := json.Unmarshal(proposed, &e); err != nil {
+		return nil, nil, fmt.Errorf("invalid proposed entry: %w", err)
+	}
+	if e.Kind == "" || e.APIVersion == "" || len(e.Spec) == 0 {
+		return nil, nil, errors.New("proposed entry needs apiVersion, kind and spec")
+	}
+
+	var spec any
+	var keys []string
+	switch e.Kind {
+	case "hashedrekord":
+		var s struct {
+			Data struct {
+				Hash hash `json:"hash"`
+			} `json:"data"`
+			Signature signature `json:"signature"`
+		}
+		if err := json.Unmarshal(e.Spec, &s); err != nil {
+			return nil, nil, fmt.Errorf("invalid hashedrekord: %w", err)
+		}
+		if s.Data.Hash.Value == "" || s.Signature.Content == "" {
+			return nil, nil, errors.New("hashedrekord needs data.hash and signature.content")
+		}
+		spec = s
+		keys = append(keys, hashIndex(s.Data.Hash))
+		keys = append(keys, verifierIndexes(s.Signature.PublicKey.Content)...)
+	case "rekord":
+		var s struct {
+			Data struct {
+				Content string `json:"content,omitempty"`
+				Hash    *hash  `json:"hash,omitempty"`
+			} `json:"data"`
+			Signature signature `json:"signature"`
+		}
+		if err := json.Unmarshal(e.Spec, &s); err != nil {
+			return nil, nil, fmt.Errorf("invalid rekord: %w", err)
+		}
+		if s.Data.Content != "" {
+			content, err := base64.StdEncoding.DecodeString(s.Data.Content)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid rekord data: %w", err)
+			}
+			h := sha256Hash(content)
+			s.Data.Content, s.Data.Hash = "", &h
+		}
+		if s.Data.Hash == nil {
+			return nil, nil, errors.New("rekord needs data.content or data.hash")
+		}
+		spec = s
+		keys = append(keys, hashIndex(*s.Data.Hash))
+		keys = append(keys, verifierIndexes(s.Signature.PublicKey.Content)...)
+	case "dsse":
+		var s struct {
+			ProposedContent struct {
+				Envelope  string   `json:"envelope"`
+				Verifiers []string `json:"verifiers"`
+			} `json:"proposedContent"`
+		}
+		if err := json.Unmarshal(e.Spec, &s); err != nil || s.ProposedContent.Envelope == "" || len(s.ProposedContent.Verifiers) == 0 {
+			return nil, nil, fmt.Errorf("dsse needs proposedContent.envelope and verifiers: %v", err)
+		}
+		var envelope struct {
+			Payload    string `json:"payload"`
+			Signatures []struct {
+				Sig string `json:"sig"`
+			} `json:"signatures"`
+		}
+		if err := json.Unmarshal([]byte(s.ProposedContent.Envelope), &envelope); err != nil {
+			return nil, nil, fmt.Errorf("invalid DSSE envelope: %w", err)
+		}
+		payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid DSSE payload: %w", err)
+		}
+		type dsseSignature struct {
+			Signature string `json:"signature"`
+			Verifier  string `json:"verifier"`
+		}
+		canonical := struct {
+			EnvelopeHash hash            `json:"envelopeHash"`
+			PayloadHash  hash            `json:"payloadHash"`
+			Signatures   []dsseSignature `json:"signatures"`
+		}{EnvelopeHash: sha256Hash([]byte(s.ProposedContent.Envelope)), PayloadHash: sha256Hash(payload)}
+		for i, sig := range envelope.Signatures {
+			verifier := s.ProposedContent.Verifiers[min(i, len(s.ProposedContent.Verifiers)-1)]
+			canonical.Signatures = append(canonical.Signatures, dsseSignature{sig.Sig, verifier})
+		}
+		spec = canonical
+		keys = append(keys, hashIndex(canonical.PayloadHash), hashIndex(canonical.EnvelopeHash))
+		for _, verifier := range s.ProposedContent.Verifiers {
+			keys = append(keys, verifierIndexes(verifier)...)
+		}
+	default:
+		// stored as proposed
+		spec = e.Spec
+	}
+
+	body, err := json.Marshal(map[string]any{"apiVersion": e.APIVersion, "kind": e.Kind, "spec": spec})
+	return body, keys, err
+}
+
+func hashIndex(h hash) string {
+	return strings.ToLower(h.Algorithm + ":" + h.Value)
+}
+
+// keyIndex is the index key of a public key or certificate, as uploaded.
+func keyIndex(content []byte) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(string(content))))
+	return "key:" + hex.EncodeToString(sum[:])
+}
+
+// verifierIndexes returns the index keys of a base64 encoded public key or
+// certificate: the key itself and the email addresses of a certificate.
+func verifierIndexes(encoded string) []string {
+	content, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(content) == 0 {
+		return nil
+	}
+	keys := []string{keyIndex(content)}
+	if block, _ := pem.Decode(content); block != nil && block.Type == "CERTIFICATE" {
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			for _, email := range cert.EmailAddresses {
+				keys = append(keys, "email:"+strings.ToLower(email))
+			}
+		}
+	}
+	return keys
+}
@@ -0,0 +1,146 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/securesign/sigstore-e2e/pkg/redact"
+)
+
+// Entry is one recorded invocation of a tool.
+type Entry struct {
+	Tool string `json:"tool"`
+	// Args are normalized with Normalize.
+	Args     []string `json:"args"`
+	Stdout   string   `json:"stdout"`
+	Stderr   string   `json:"stderr"`
+	ExitCode int      `json:"exitCode"`
+	// Files holds the content of output files written by the command, keyed by
+	// the flag that named them (e.g. "--bundle").
+	Files map[string]string `json:"files,omitempty"`
+}
+
+// outputFileFlags name files that commands write and later steps read.
+var outputFileFlags = []string{"--bundle", "--output-file", "--output-signature", "--output-certificate", "--output"}
+
+// outputFileVars name files that commands write and that are passed in the
+// environment, like the log of gitsign, whose stderr git does not show.
+var outputFileVars = []string{"GITSIGN_LOG"}
+
+var (
+	uuidRegexp = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	hostRegexp = regexp.MustCompile(`(localhost|127\.0\.0\.1):\d+`)
+
+	tempRegexpOnce sync.Once
+	tempRegexp     *regexp.Regexp
+)
+
+// Normalize removes the parts of args that differ between runs: secrets,
+// temporary directories, random names and ports of local services. Paths
+// under the temp directory keep only their base name.
+func Normalize(args []string) []string {
+	tempRegexpOnce.Do(func() {
+		tempRegexp = regexp.MustCompile(regexp.QuoteMeta(filepath.Clean(os.TempDir())) + `/[^\s=,]+`)
+	})
+	out := make([]string, len(args))
+	for i, a := range redact.Strings(args) {
+		a = tempRegexp.ReplaceAllStringFunc(a, func(path string) string {
+			return "<tmp>/" + filepath.Base(path)
+		})
+		a = uuidRegexp.ReplaceAllString(a, "<uuid>")
+		out[i] = hostRegexp.ReplaceAllString(a, "<host>")
+	}
+	return out
+}
+
+func cassettePath(dir string, tool string) string {
+	return filepath.Join(dir, tool+".jsonl")
+}
+
+// Load reads the recorded invocations of tool from dir.
+func Load(dir string, tool string) ([]Entry, error) {
+	file, err := os.Open(cassettePath(dir, tool))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close() //nolint:errcheck
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024) //nolint:mnd
+	for scanner.Scan() {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("invalid entry in %s: %w", file.Name(), err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// Append adds e to the cassette of its tool in dir.
+func Append(dir string, e Entry) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(cassettePath(dir, e.Tool), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(line, '\n'))
+	return errors.Join(err, file.Close())
+}
+
+// match returns the entry recorded for args. When the same command was
+// recorded several times, the n-th call gets the n-th recording and later
+// calls repeat the last one.
+func match(entries []Entry, args []string, call int) (*Entry, bool) {
+	normalized := Normalize(args)
+	var found []*Entry
+	for i := range entries {
+		if slices.Equal(entries[i].Args, normalized) {
+			found = append(found, &entries[i])
+		}
+	}
+	if len(found) == 0 {
+		return nil, false
+	}
+	return found[min(call, len(found)-1)], true
+}
+
+// outputFiles returns the output files named in args, keyed by flag, and in
+// the environment, keyed by "$" and the variable.
+func outputFiles(args []string) map[string]string {
+	files := map[string]string{}
+	for i, a := range args {
+		for _, flag := range outputFileFlags {
+			switch {
+			case a == flag && i+1 < len(args):
+				files[flag] = args[i+1]
+			case strings.HasPrefix(a, flag+"="):
+				files[flag] = strings.TrimPrefix(a, flag+"=")
+			}
+		}
+	}
+	for _, key := range outputFileVars {
+		if path := os.Getenv(key); path != "" {
+			files["$"+key] = path
+		}
+	}
+	return files
+}
@@ -0,0 +1,132 @@
+package replay
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/securesign/sigstore-e2e/pkg/redact"
+)
+
+// The launcher installed by the strategies runs cmd/replay-cli with these
+// variables set.
+const (
+	envMode     = "E2E_REPLAY_MODE"
+	envTool     = "E2E_REPLAY_TOOL"
+	envCassette = "E2E_REPLAY_CASSETTE"
+	envState    = "E2E_REPLAY_STATE"
+	envReal     = "E2E_REPLAY_REAL"
+
+	modeReplay = "replay"
+	modeRecord = "record"
+)
+
+// RunFake answers a single invocation of the fake CLI with args and returns
+// its exit code. The mode, tool and recordings are read from the environment
+// set by the launcher.
+func RunFake(args []string, stdout, stderr io.Writer) int {
+	mode := os.Getenv(envMode)
+	tool := os.Getenv(envTool)
+	dir := os.Getenv(envCassette)
+
+	var err error
+	exitCode := 1
+	switch mode {
+	case modeReplay:
+		exitCode, err = replay(tool, dir, os.Getenv(envState), args, stdout, stderr)
+	case modeRecord:
+		exitCode, err = record(tool, dir, os.Getenv(envReal), args, stdout, stderr)
+	default:
+		err = fmt.Errorf("unknown mode %q", mode)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%s (%s): %v\n", tool, mode, err) //nolint:errcheck
+		return 1
+	}
+	return exitCode
+}
+
+func replay(tool, dir, state string, args []string, stdout, stderr io.Writer) (int, error) {
+	entries, err := Load(dir, tool)
+	if err != nil {
+		return 0, err
+	}
+	call, err := nextCall(state, tool, args)
+	if err != nil {
+		return 0, err
+	}
+	e, ok := match(entries, args, call)
+	if !ok {
+		return 0, fmt.Errorf("no recorded invocation for %q in %s", strings.Join(Normalize(args), " "), cassettePath(dir, tool))
+	}
+	for flag, path := range outputFiles(args) {
+		content, ok := e.Files[flag]
+		if !ok {
+			continue
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := io.WriteString(stdout, e.Stdout); err != nil {
+		return 0, err
+	}
+	if _, err := io.WriteString(stderr, e.Stderr); err != nil {
+		return 0, err
+	}
+	return e.ExitCode, nil
+}
+
+// nextCall counts the invocations of the same normalized command in state, so
+// that repeated commands replay their recordings in order.
+func nextCall(state, tool string, args []string) (int, error) {
+	sum := sha256.Sum256([]byte(tool + "\x00" + strings.Join(Normalize(args), "\x00")))
+	counter := filepath.Join(state, hex.EncodeToString(sum[:8]))
+	call := 0
+	if data, err := os.ReadFile(counter); err == nil {
+		call, _ = strconv.Atoi(string(data))
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return 0, err
+	}
+	return call, os.WriteFile(counter, []byte(strconv.Itoa(call+1)), 0600)
+}
+
+func record(tool, dir, real string, args []string, stdout, stderr io.Writer) (int, error) {
+	var out, errOut bytes.Buffer
+	cmd := exec.Command(real, args...) //nolint:gosec
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = io.MultiWriter(stdout, &out)
+	cmd.Stderr = io.MultiWriter(stderr, &errOut)
+
+	exitCode := 0
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return 0, err
+		}
+		exitCode = exitErr.ExitCode()
+	}
+
+	e := Entry{
+		Tool:     tool,
+		Args:     Normalize(args),
+		Stdout:   redact.String(out.String()),
+		Stderr:   redact.String(errOut.String()),
+		ExitCode: exitCode,
+		Files:    map[string]string{},
+	}
+	for flag, path := range outputFiles(args) {
+		if content, err := os.ReadFile(path); err == nil {
+			e.Files[flag] = string(content)
+		}
+	}
+	return exitCode, Append(dir, e)
+}
@@ -0,0 +1,158 @@
+// Package replay provides the "replay" and "record" CLI strategies. Instead of
+// a real binary they install a launcher running the replay-cli binary built
+// from cmd/replay-cli (see REPLAY_CLI), which answers from, or appends to,
+// recorded invocations in REPLAY_DIR, so suites can run offline.
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/securesign/sigstore-e2e/pkg/strategy"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeName is the binary built from cmd/replay-cli, looked up on PATH when
+// REPLAY_CLI is not set.
+const fakeName = "replay-cli"
+
+func init() {
+	strategy.Register(modeReplay, func() strategy.Strategy {
+		dir, err := cassetteDir()
+		if err != nil {
+			return failed(err)
+		}
+		return newReplayStrategy(dir)
+	})
+	strategy.Register(modeRecord, func() strategy.Strategy {
+		dir, err := cassetteDir()
+		if err != nil {
+			return failed(err)
+		}
+		base := api.GetValueFor(api.ReplayStrategy)
+		real, ok := strategy.Get(base)
+		if !ok || base == modeRecord || base == modeReplay {
+			return failed(fmt.Errorf("invalid base strategy for recording (%s): %q", api.ReplayStrategy, base))
+		}
+		return newRecordStrategy(dir, real)
+	})
+}
+
+// failed returns a strategy reporting err at client setup, where the
+// configuration checks of the suite have already described it.
+func failed(err error) strategy.Strategy {
+	return func(context.Context, string) (string, error) {
+		return "", err
+	}
+}
+
+func cassetteDir() (string, error) {
+	dir, err := filepath.Abs(api.GetValueFor(api.ReplayDir))
+	if err != nil {
+		return "", fmt.Errorf("invalid %s: %w", api.ReplayDir, err)
+	}
+	return dir, nil
+}
+
+func newReplayStrategy(dir string) strategy.Strategy {
+	return func(ctx context.Context, cliName string) (string, error) {
+		logrus.Info("Replaying '", cliName, "' from ", cassettePath(dir, cliName))
+		if _, err := os.Stat(cassettePath(dir, cliName)); err != nil {
+			return "", fmt.Errorf("no recordings for %s: %w", cliName, err)
+		}
+		return install(ctx, cliName, map[string]string{
+			envMode:     modeReplay,
+			envTool:     cliName,
+			envCassette: dir,
+		})
+	}
+}
+
+func newRecordStrategy(dir string, real strategy.Strategy) strategy.Strategy {
+	return func(ctx context.Context, cliName string) (string, error) {
+		path, err := real(ctx, cliName)
+		if err != nil {
+			return "", err
+		}
+		logrus.Info("Recording '", cliName, "' to ", cassettePath(dir, cliName))
+		return install(ctx, cliName, map[string]string{
+			envMode:     modeRecord,
+			envTool:     cliName,
+			envCassette: dir,
+			envReal:     path,
+		})
+	}
+}
+
+// install writes a launcher named cliName that runs the fake CLI with env.
+// It lives in a directory removed by strategy.Release, which also holds the
+// replay state.
+func install(_ context.Context, cliName string, env map[string]string) (string, error) {
+	if runtime.GOOS == "windows" {
+		return "", errors.New("record/replay strategies are not supported on windows")
+	}
+	fake, err := fakeBinary()
+	if err != nil {
+		return "", err
+	}
+	tmp, err := strategy.TempDir(cliName)
+	if err != nil {
+		return "", err
+	}
+	fileName := filepath.Join(tmp, cliName)
+	if err := writeLauncher(tmp, fileName, fake, env); err != nil {
+		_ = strategy.Release(fileName)
+		return "", err
+	}
+	return fileName, nil
+}
+
+// fakeBinary returns the absolute path of the fake CLI.
+func fakeBinary() (string, error) {
+	path := api.GetValueFor(api.ReplayCLI)
+	if path == "" {
+		var err error
+		if path, err = exec.LookPath(fakeName); err != nil {
+			return "", fmt.Errorf("%s not found, build it with 'make replay-cli' and set %s: %w", fakeName, api.ReplayCLI, err)
+		}
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s: %w", api.ReplayCLI, err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("invalid %s, build it with 'make replay-cli': %w", api.ReplayCLI, err)
+	}
+	return path, nil
+}
+
+func writeLauncher(tmp, fileName, fake string, env map[string]string) error {
+	if env[envMode] == modeReplay {
+		state := filepath.Join(tmp, "state")
+		if err := os.Mkdir(state, 0700); err != nil {
+			return err
+		}
+		env[envState] = state
+	}
+
+	var script strings.Builder
+	script.WriteString("#!/bin/sh\n")
+	for _, k := range []string{envMode, envTool, envCassette, envState, envReal} {
+		if v, ok := env[k]; ok {
+			fmt.Fprintf(&script, "%s=%s; export %s\n", k, shellQuote(v), k)
+		}
+	}
+	fmt.Fprintf(&script, "exec %s \"$@\"\n", shellQuote(fake))
+	return os.WriteFile(fileName, []byte(script.String()), 0700) //nolint:gosec
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,39 @@
+package strategy
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var (
+	tempMu   sync.Mutex
+	tempDirs = map[string]bool{}
+)
+
+// TempDir creates a temporary directory for the files of cliName. It is
+// removed by Release of an executable installed in it.
+func TempDir(cliName string) (string, error) {
+	dir, err := os.MkdirTemp("", cliName)
+	if err != nil {
+		return "", err
+	}
+	tempMu.Lock()
+	tempDirs[dir] = true
+	tempMu.Unlock()
+	return dir, nil
+}
+
+// Release removes the directory created by TempDir that holds the executable
+// at path. Executables resolved elsewhere are left alone.
+func Release(path string) error {
+	dir := filepath.Dir(path)
+	tempMu.Lock()
+	owned := tempDirs[dir]
+	delete(tempDirs, dir)
+	tempMu.Unlock()
+	if !owned {
+		return nil
+	}
+	return os.RemoveAll(dir)
+}
@@ -0,0 +1,43 @@
+package strategy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRelease(t *testing.T) {
+	dir, err := TempDir("tool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "tool")
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	foreign := filepath.Join(t.TempDir(), "tool")
+	if err := os.WriteFile(foreign, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		removed bool
+	}{
+		{name: "not created by TempDir", path: foreign, removed: false},
+		{name: "created by TempDir", path: path, removed: true},
+		{name: "already released", path: path, removed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Release(tt.path); err != nil {
+				t.Fatal(err)
+			}
+			_, err := os.Stat(filepath.Dir(tt.path))
+			if removed := os.IsNotExist(err); removed != tt.removed {
+				t.Errorf("removed = %v, want %v", removed, tt.removed)
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+// Package tuftest provides an in-process TUF repository serving Sigstore
+// trust root targets, with fault modes making clients reject its metadata.
+//
+// All top-level roles are signed by a single ed25519 key and the repository
+// uses consistent snapshots, like the repositories of the Sigstore deployments.
+package tuftest
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/theupdateframework/go-tuf/v2/metadata"
+)
+
+// Well-known targets of Sigstore TUF repositories.
+const (
+	CTFETarget        = "ctfe.pub"
+	RekorTarget       = "rekor.pub"
+	FulcioTarget      = "fulcio_v1.crt.pem"
+	TSATarget         = "tsa.certchain.pem"
+	TrustedRootTarget = "trusted_root.json"
+)
+
+// Fault is a way the repository misbehaves.
+type Fault string
+
+const (
+	// FaultNone serves valid metadata.
+	FaultNone Fault = ""
+	// FaultExpired serves metadata of all roles, root included, that expired an hour ago.
+	FaultExpired Fault = "expired"
+	// FaultWrongSignature signs targets, snapshot and timestamp with a key the root does not trust.
+	FaultWrongSignature Fault = "wrong-signature"
+	// FaultRollback serves validly signed metadata of version 1. Clients that
+	// fetched a later version (see Publish) must reject it.
+	FaultRollback Fault = "rollback"
+	// FaultFrozenTimestamp keeps serving the timestamp of version 1, which
+	// expired an hour ago, while the other roles are current.
+	FaultFrozenTimestamp Fault = "frozen-timestamp"
+)
+
+// Faults lists the fault modes.
+var Faults = []Fault{FaultExpired, FaultWrongSignature, FaultRollback, FaultFrozenTimestamp}
+
+// Server is an in-process TUF repository.
+type Server struct {
+	URL string
+	// Expires is the validity of metadata published from now on.
+	Expires time.Duration
+
+	server *httptest.Server
+	key    *metadata.Key
+	signer signature.Signer
+	rogue  signature.Signer
+	now    func() time.Time
+
+	mu      sync.Mutex
+	targets map[string][]byte
+	version int64
+	fault   Fault
+	files   map[string][]byte
+}
+
+// NewServer starts a repository publishing targets as version 1. Close it when done.
+func NewServer(targets map[string][]byte) (*Server, error) {
+	return start(targets, httptest.NewServer)
+}
+
+// NewTLSServer is like NewServer, but serves HTTPS with a self-signed
+// certificate, see Certificate.
+func NewTLSServer(targets map[string][]byte) (*Server, error) {
+	return start(targets, httptest.NewTLSServer)
+}
+
+func start(targets map[string][]byte, serve func(http.Handler) *httptest.Server) (*Server, error) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := signature.LoadED25519Signer(priv)
+	if err != nil {
+		return nil, err
+	}
+	key, err := metadata.KeyFromPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	_, roguePriv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return nil, err
+	}
+	rogue, err := signature.LoadED25519Signer(roguePriv)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		Expires: 24 * time.Hour,
+		key:     key,
+		signer:  signer,
+		rogue:   rogue,
+		now:     time.Now,
+		targets: map[string][]byte{},
+		files:   map[string][]byte{},
+	}
+	if err := s.Publish(targets); err != nil {
+		return nil, err
+	}
+	s.server = serve(http.HandlerFunc(s.serveFile))
+	s.URL = s.server.URL
+	return s, nil
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Certificate returns the PEM encoded certificate of a TLS server, nil otherwise.
+func (s *Server) Certificate() []byte {
+	cert := s.server.Certificate()
+	if cert == nil {
+		return nil
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+// RootJSON returns the root metadata clients are initialized with.
+func (s *Server) RootJSON() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.files["/root.json"]
+}
+
+// Version returns the version of the last published targets.
+func (s *Server) Version() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version
+}
+
+// Publish adds or replaces targets and publishes them as the next version of
+// the targets, snapshot and timestamp roles.
+func (s *Server) Publish(targets map[string][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, content := range targets {
+		s.targets[name] = content
+	}
+	s.version++
+	return s.render()
+}
+
+// SetFault switches the fault mode of the metadata served from now on.
+func (s *Server) SetFault(fault Fault) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fault = fault
+	return s.render()
+}
+
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	data, ok := s.files[r.URL.Path]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	_, _ = w.Write(data)
+}
+
+type signable interface {
+	Sign(signature.Signer) (*metadata.Signature, error)
+	ToBytes(bool) ([]byte, error)
+}
+
+// render signs the metadata of the current version and fault mode. Versioned
+// metadata and targets of earlier versions stay available.
+func (s *Server) render() error {
+	now := s.now()
+	valid, expired := now.Add(s.Expires), now.Add(-time.Hour)
+	rootExpires, expires, timestampExpires := valid, valid, valid
+	version, timestampVersion := s.version, s.version
+	signer := s.signer
+	switch s.fault {
+	case FaultNone:
+	case FaultExpired:
+		rootExpires, expires, timestampExpires = expired, expired, expired
+	case FaultWrongSignature:
+		signer = s.rogue
+	case FaultRollback:
+		version, timestampVersion = 1, 1
+	case FaultFrozenTimestamp:
+		timestampVersion, timestampExpires = 1, expired
+	default:
+		return fmt.Errorf("unknown fault %q", s.fault)
+	}
+
+	root := metadata.Root(rootExpires)
+	for _, role := range []string{metadata.ROOT, metadata.TARGETS, metadata.SNAPSHOT, metadata.TIMESTAMP} {
+		if err := root.Signed.AddKey(s.key, role); err != nil {
+			return err
+		}
+	}
+	targets := metadata.Targets(expires)
+	targets.Signed.Version = version
+	for name, content := range s.targets {
+		target, err := metadata.TargetFile().FromBytes(name, content, "sha256")
+		if err != nil {
+			return err
+		}
+		if usage := sigstoreUsage(name); usage != "" {
+			custom := json.RawMessage(fmt.Sprintf(`{"sigstore":{"usage":%q,"status":"Active"}}`, usage))
+			target.Custom = &custom
+		}
+		targets.Signed.Targets[name] = target
+		s.files["/targets/"+hex.EncodeToString(target.Hashes["sha256"])+"."+name] = content
+		s.files["/targets/"+name] = content
+	}
+	snapshot := metadata.Snapshot(expires)
+	snapshot.Signed.Version = version
+	snapshot.Signed.Meta["targets.json"] = metadata.MetaFile(version)
+	timestamp := metadata.Timestamp(timestampExpires)
+	timestamp.Signed.Version = timestampVersion
+	timestamp.Signed.Meta["snapshot.json"] = metadata.MetaFile(timestampVersion)
+
+	for _, md := range []struct {
+		paths  []string
+		md     signable
+		signer signature.Signer
+	}{
+		{[]string{"/1.root.json", "/root.json"}, root, s.signer},
+		{[]string{fmt.Sprintf("/%d.targets.json", version), "/targets.json"}, targets, signer},
+		{[]string{fmt.Sprintf("/%d.snapshot.json", version), "/snapshot.json"}, snapshot, signer},
+		{[]string{"/timestamp.json"}, timestamp, signer},
+	} {
+		if _, err := md.md.Sign(md.signer); err != nil {
+			return err
+		}
+		data, err := md.md.ToBytes(false)
+		if err != nil {
+			return err
+		}
+		for _, path := range md.paths {
+			s.files[path] = data
+		}
+	}
+	return nil
+}
+
+// sigstoreUsage returns the usage of a target in the custom metadata that
+// cosign clients without trusted_root.json support select targets by.
+func sigstoreUsage(name string) string {
+	for prefix, usage := range map[string]string{"ctfe": "CTFE", "rekor": "Rekor", "fulcio": "Fulcio", "tsa": "TSA"} {
+		if strings.HasPrefix(name, prefix) {
+			return usage
+		}
+	}
+	return ""
+}
+
+var hashPrefix = regexp.MustCompile(`^[0-9a-f]{64}\.`)
+
+// TargetsFromDir reads the targets in dir, e.g. the targets directory of a
+// repository built by tuftool. Consistent snapshot hash prefixes are removed
+// from the file names.
+func TargetsFromDir(dir string) (map[string][]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	targets := map[string][]byte{}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		targets[hashPrefix.ReplaceAllString(entry.Name(), "")] = content
+	}
+	return targets, nil
+}
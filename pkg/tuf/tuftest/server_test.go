@@ -0,0 +1,193 @@
+package tuftest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/theupdateframework/go-tuf/v2/metadata/config"
+	"github.com/theupdateframework/go-tuf/v2/metadata/updater"
+)
+
+func newServer(t *testing.T, targets map[string][]byte) *Server {
+	t.Helper()
+	s, err := NewServer(targets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+// refresh updates the metadata in the client cache dir from s and returns
+// the content of target.
+func refresh(s *Server, dir string, target string) ([]byte, error) {
+	cfg, err := config.New(s.URL, s.RootJSON())
+	if err != nil {
+		return nil, err
+	}
+	cfg.LocalMetadataDir = dir
+	cfg.LocalTargetsDir = filepath.Join(dir, "targets")
+	cfg.PrefixTargetsWithHash = true
+	up, err := updater.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := up.Refresh(); err != nil {
+		return nil, err
+	}
+	info, err := up.GetTargetInfo(target)
+	if err != nil {
+		return nil, err
+	}
+	_, data, err := up.DownloadTarget(info, filepath.Join(dir, "targets", target), "")
+	return data, err
+}
+
+func TestServer(t *testing.T) {
+	s := newServer(t, map[string][]byte{RekorTarget: []byte("rekor key"), TrustedRootTarget: []byte("{}")})
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "targets"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	data, err := refresh(s, dir, RekorTarget)
+	if err != nil || string(data) != "rekor key" {
+		t.Fatalf("refresh() = %q, %v", data, err)
+	}
+
+	if err := s.Publish(map[string][]byte{RekorTarget: []byte("rotated key")}); err != nil {
+		t.Fatal(err)
+	}
+	data, err = refresh(s, dir, RekorTarget)
+	if err != nil || string(data) != "rotated key" || s.Version() != 2 {
+		t.Fatalf("refresh() after publishing version %d = %q, %v", s.Version(), data, err)
+	}
+
+	var targets struct {
+		Signed struct {
+			Targets map[string]struct {
+				Custom json.RawMessage `json:"custom"`
+			} `json:"targets"`
+		} `json:"signed"`
+	}
+	if err := json.Unmarshal(get(t, http.DefaultClient, s.URL+"/2.targets.json"), &targets); err != nil {
+		t.Fatal(err)
+	}
+	if custom := string(targets.Signed.Targets[RekorTarget].Custom); custom != `{"sigstore":{"usage":"Rekor","status":"Active"}}` {
+		t.Errorf("unexpected custom metadata of %s: %s", RekorTarget, custom)
+	}
+	if custom := targets.Signed.Targets[TrustedRootTarget].Custom; custom != nil {
+		t.Errorf("unexpected custom metadata of %s: %s", TrustedRootTarget, custom)
+	}
+}
+
+func TestServerFaults(t *testing.T) {
+	tests := []struct {
+		fault Fault
+		// cached clients fetched version 2 before the fault was set
+		cached bool
+		want   string
+	}{
+		{fault: FaultExpired, want: "expired"},
+		{fault: FaultWrongSignature, want: "signature"},
+		{fault: FaultRollback, cached: true, want: "version"},
+		{fault: FaultFrozenTimestamp, want: "expired"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.fault), func(t *testing.T) {
+			s := newServer(t, map[string][]byte{CTFETarget: []byte("ctfe key")})
+			if err := s.Publish(nil); err != nil {
+				t.Fatal(err)
+			}
+			dir := t.TempDir()
+			if err := os.MkdirAll(filepath.Join(dir, "targets"), 0o700); err != nil {
+				t.Fatal(err)
+			}
+			if tt.cached {
+				if _, err := refresh(s, dir, CTFETarget); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := s.SetFault(tt.fault); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := refresh(s, dir, CTFETarget); err == nil || !strings.Contains(strings.ToLower(err.Error()), tt.want) {
+				t.Fatalf("refresh() error = %v, want error containing %q", err, tt.want)
+			}
+
+			if err := s.SetFault(FaultNone); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := refresh(s, dir, CTFETarget); err != nil {
+				t.Fatalf("refresh() without fault: %v", err)
+			}
+		})
+	}
+	if err := newServer(t, nil).SetFault("unknown"); err == nil {
+		t.Error("expected error for unknown fault")
+	}
+}
+
+func TestTLSServer(t *testing.T) {
+	s, err := NewTLSServer(map[string][]byte{FulcioTarget: []byte("fulcio cert")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(s.Certificate()) {
+		t.Fatalf("invalid certificate %s", s.Certificate())
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}}}
+	if !strings.HasPrefix(s.URL, "https://") || string(get(t, client, s.URL+"/root.json")) != string(s.RootJSON()) {
+		t.Fatalf("root.json not served over HTTPS at %s", s.URL)
+	}
+	if newServer(t, nil).Certificate() != nil {
+		t.Error("unexpected certificate of an HTTP server")
+	}
+}
+
+func TestTargetsFromDir(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		strings.Repeat("ab", 32) + ".ctfe.pub": "ctfe key",
+		"rekor.pub":                            "rekor key",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "subdir"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	targets, err := TargetsFromDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]byte{CTFETarget: []byte("ctfe key"), RekorTarget: []byte("rekor key")}
+	if !reflect.DeepEqual(targets, want) {
+		t.Errorf("TargetsFromDir() = %q, want %q", targets, want)
+	}
+}
+
+func get(t *testing.T, client *http.Client, url string) []byte {
+	t.Helper()
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: %d %v", url, resp.StatusCode, err)
+	}
+	return data
+}
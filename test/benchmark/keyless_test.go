@@ -14,6 +14,7 @@ import (
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/google/uuid"
 	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/securesign/sigstore-e2e/pkg/clients"
 	"github.com/securesign/sigstore-e2e/test/testsupport"
 	"github.com/sirupsen/logrus"
 	"github.com/testcontainers/testcontainers-go"
@@ -110,7 +111,7 @@ func BenchmarkKeyless(b *testing.B) {
 		// wait to container be ready, sometime there has been problem with connection refused DNS
 		time.Sleep(10 * time.Second)
 
-		exitCode, reader, err := container.Exec(ctx, []string{"cosign", "initialize"})
+		exitCode, reader, err := container.Exec(ctx, append([]string{"cosign"}, clients.InitializeOptions{}.Args()...))
 		if err != nil {
 			return nil, fmt.Errorf("failed to execute command: %w", err)
 		}
@@ -155,7 +156,12 @@ func keylessProcess(ctx context.Context, images *sync.Pool, containers *Containe
 
 	logrus.Info("sign: ", image)
 	token := tm.GetToken()
-	err := containers.ExecuteCommandInContainer(ctx, "cosign", "sign", "--allow-http-registry=true", "--upload=false", "--identity-token="+token, image)
+	args := clients.SignOptions{
+		IdentityToken:     token,
+		AllowHTTPRegistry: true,
+		NoUpload:          true,
+	}.Args(image)
+	err := containers.ExecuteCommandInContainer(ctx, append([]string{"cosign"}, args...)...)
 	if err != nil {
 		return err
 	}
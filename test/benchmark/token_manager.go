@@ -2,61 +2,30 @@ package benchmark
 
 import (
 	"context"
-	"sync"
-	"time"
 
 	"github.com/securesign/sigstore-e2e/test/testsupport"
 	"github.com/sirupsen/logrus"
 )
 
-// TokenManager handles shared access and periodic refresh of a token.
+// TokenManager hands out the OIDC token of the default identity to parallel
+// benchmark workers. The token is cached by testsupport and renewed shortly
+// before it expires.
 type TokenManager struct {
-	token string
-	mu    sync.RWMutex
+	ctx context.Context
 }
 
-// NewTokenManager initializes a new TokenManager.
+// NewTokenManager initializes a new TokenManager and obtains the first token.
 func NewTokenManager(ctx context.Context) *TokenManager {
-	manager := &TokenManager{}
-	// Start the token refresh in a background goroutine
-	manager.RefreshToken(ctx)
-	go manager.startTokenRefresher(ctx)
+	manager := &TokenManager{ctx: ctx}
+	manager.GetToken()
 	return manager
 }
 
-// GetToken returns the current token value with read lock.
+// GetToken returns a token that is valid for at least oidc.DefaultLeeway.
 func (tm *TokenManager) GetToken() string {
-	tm.mu.RLock()
-	defer tm.mu.RUnlock()
-	return tm.token
-}
-
-// RefreshToken refreshes the token value with write lock.
-func (tm *TokenManager) RefreshToken(ctx context.Context) {
-	tm.mu.Lock()
-	defer tm.mu.Unlock()
-
-	var err error
-	tm.token, err = testsupport.GetOIDCToken(ctx)
+	token, err := testsupport.GetOIDCToken(tm.ctx)
 	if err != nil {
 		logrus.Errorf("failed to get OIDC token %v", err)
 	}
-	logrus.Info("successfully refreshed token")
-}
-
-// startTokenRefresher runs a loop that refreshes the token every 30 seconds.
-func (tm *TokenManager) startTokenRefresher(ctx context.Context) {
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			tm.RefreshToken(ctx) // Refresh the token every 30 seconds
-		case <-ctx.Done():
-			// Context is canceled, stop the refresher
-			logrus.Info("Stopping token refresher")
-			return
-		}
-	}
+	return token
 }
@@ -0,0 +1,148 @@
+package cosign
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/securesign/sigstore-e2e/pkg/clients"
+	"github.com/securesign/sigstore-e2e/pkg/oidc/oidctest"
+	"github.com/securesign/sigstore-e2e/pkg/preflight"
+	"github.com/securesign/sigstore-e2e/test/testsupport"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+// identityMismatch matches the errors of cosign verifying a certificate
+// issued to another identity or by another issuer.
+const identityMismatch = `(?i)none of the expected identities matched|expected identity not found|no matching CertificateIdentity|expected oidc issuer not found`
+
+var _ = Describe("Identity mismatch test", Ordered, func() {
+
+	var (
+		cosign   *clients.Cosign
+		blobPath string
+	)
+
+	BeforeAll(func(ctx SpecContext) {
+		if len(testsupport.OtherIdentities()) == 0 {
+			Skip("no further identity configured in " + api.Identities)
+		}
+		logrus.Infof("Starting identity mismatch test")
+		Expect(testsupport.CheckMandatoryAPIConfigValues(api.OidcRealm)).To(Succeed())
+		testsupport.RequireServices(preflight.TUF, preflight.Fulcio, preflight.Rekor, preflight.OIDC)
+
+		cosign = clients.NewCosign()
+		Expect(testsupport.InstallPrerequisites(cosign)).To(Succeed())
+		DeferCleanup(func() {
+			if err := testsupport.DestroyPrerequisites(); err != nil {
+				logrus.Warn("Env was not cleaned-up" + err.Error())
+			}
+		})
+		Expect(cosign.Initialize(ctx, clients.InitializeOptions{})).To(Succeed())
+
+		blobPath = filepath.Join(GinkgoT().TempDir(), "blob.txt")
+		Expect(os.WriteFile(blobPath, []byte("signed by another identity"), 0600)).To(Succeed())
+	})
+
+	It("should only verify blobs against the identity that signed them", func(ctx SpecContext) {
+		signer := testsupport.GetIdentity(api.DefaultIdentity)
+		for _, other := range testsupport.OtherIdentities() {
+			if other.Email() == signer.Email() && other.IssuerURL == signer.IssuerURL {
+				logrus.Warnf("Identity %s equals the default identity, skipping it", other.Name)
+				continue
+			}
+			By("signing with identity " + other.Name)
+			token, err := testsupport.GetOIDCTokenFor(ctx, other.Name)
+			Expect(err).ToNot(HaveOccurred())
+			signed, err := cosign.SignBlob(ctx, blobPath, clients.SignBlobOptions{
+				IdentityToken: token,
+				BundlePath:    filepath.Join(GinkgoT().TempDir(), "bundle.json"),
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			By("verifying against identity " + other.Name)
+			verified, err := cosign.VerifyBlob(ctx, blobPath, clients.VerifyBlobOptions{
+				BundlePath: signed.BundlePath,
+				VerifyOptions: clients.VerifyOptions{
+					CertificateIdentity:   other.Email(),
+					CertificateOIDCIssuer: other.IssuerURL,
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(verified.CertificateIdentity).To(Equal(other.Email()))
+			Expect(verified.LogIndex).To(Equal(signed.LogIndex))
+
+			By("verifying against the default identity")
+			_, err = cosign.VerifyBlob(ctx, blobPath, clients.VerifyBlobOptions{
+				BundlePath: signed.BundlePath,
+				VerifyOptions: clients.VerifyOptions{
+					CertificateIdentity:   signer.Email(),
+					CertificateOIDCIssuer: signer.IssuerURL,
+				},
+			})
+			var cmdErr *clients.CommandError
+			Expect(errors.As(err, &cmdErr)).To(BeTrue(), "expected verify-blob to fail, got %v", err)
+			Expect(cmdErr.Stderr).To(MatchRegexp(identityMismatch),
+				"verify-blob should fail on the certificate identity, not for another reason")
+		}
+	})
+})
+
+var _ = Describe("Fulcio identity rejection test", Ordered, Serial, func() {
+
+	var (
+		cosign   *clients.Cosign
+		mock     *testsupport.MockOIDC
+		identity oidctest.Identity
+		blobPath string
+	)
+
+	BeforeAll(func(ctx SpecContext) {
+		if mock = testsupport.LocalStackOIDC(); mock == nil {
+			Skip("needs the local stack (" + api.LocalStack + "=true), whose Fulcio trusts the mock OIDC provider")
+		}
+		testsupport.RequireServices(preflight.TUF, preflight.Fulcio, preflight.Rekor, preflight.OIDC)
+
+		cosign = clients.NewCosign()
+		Expect(testsupport.InstallPrerequisites(cosign)).To(Succeed())
+		DeferCleanup(func() {
+			if err := testsupport.DestroyPrerequisites(); err != nil {
+				logrus.Warn("Env was not cleaned-up" + err.Error())
+			}
+		})
+		Expect(cosign.Initialize(ctx, clients.InitializeOptions{})).To(Succeed())
+
+		identity = oidctest.Email(testsupport.MockOIDCUser + "@" + testsupport.MockOIDCDomain)
+		blobPath = filepath.Join(GinkgoT().TempDir(), "blob.txt")
+		Expect(os.WriteFile(blobPath, []byte("signed with a rejected token"), 0600)).To(Succeed())
+	})
+
+	It("should issue a certificate for a valid token", func(ctx SpecContext) {
+		token, err := mock.Token(identity)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = cosign.SignBlob(ctx, blobPath, clients.SignBlobOptions{IdentityToken: token})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	DescribeTable("should not issue a certificate",
+		func(ctx SpecContext, token func() oidctest.Identity, rejection string) {
+			signed, err := mock.Token(token())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = cosign.SignBlob(ctx, blobPath, clients.SignBlobOptions{IdentityToken: signed})
+			var cmdErr *clients.CommandError
+			Expect(errors.As(err, &cmdErr)).To(BeTrue(), "expected sign-blob to fail, got %v", err)
+			Expect(cmdErr.Stderr).To(MatchRegexp(rejection))
+			Expect(cmdErr.Stderr).ToNot(ContainSubstring("tlog entry created"))
+		},
+		Entry("for an expired token", func() oidctest.Identity { return identity.Expired() },
+			`(?i)expired|error processing the credentials|401`),
+		Entry("for a token issued for another audience", func() oidctest.Identity { return identity.WithAudience("other-client") },
+			`(?i)audience|error processing the credentials|401`),
+		Entry("for a token with an unverified email", func() oidctest.Identity { return identity.WithUnverifiedEmail() },
+			`(?i)email_verified|not verified|error processing the identity token|400`),
+	)
+})
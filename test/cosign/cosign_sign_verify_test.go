@@ -2,22 +2,14 @@ package cosign
 
 import (
 	"encoding/base64"
-	"encoding/json"
-	"fmt"
-	"io"
+	"errors"
 	"os"
 	"path/filepath"
 	"regexp"
-	"strconv"
-	"strings"
-	"time"
 
-	"github.com/docker/docker/api/types/image"
-
-	"github.com/docker/docker/client"
-	"github.com/google/uuid"
 	"github.com/securesign/sigstore-e2e/pkg/api"
 	"github.com/securesign/sigstore-e2e/pkg/clients"
+	"github.com/securesign/sigstore-e2e/pkg/preflight"
 	"github.com/securesign/sigstore-e2e/test/testsupport"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -40,11 +32,10 @@ var targetImageName string
 var _ = Describe("Cosign test", Ordered, func() {
 
 	var (
-		err       error
-		dockerCli *client.Client
-		cosign    *clients.Cosign
-		rekorCli  *clients.RekorCli
-		ec        *clients.EnterpriseContract
+		err      error
+		cosign   *clients.Cosign
+		rekorCli *clients.RekorCli
+		ec       *clients.EnterpriseContract
 	)
 
 	BeforeAll(func() {
@@ -53,6 +44,7 @@ var _ = Describe("Cosign test", Ordered, func() {
 		if err != nil {
 			Fail(err.Error())
 		}
+		testsupport.RequireServices(preflight.TUF, preflight.Fulcio, preflight.Rekor, preflight.OIDC)
 
 		cosign = clients.NewCosign()
 
@@ -60,7 +52,9 @@ var _ = Describe("Cosign test", Ordered, func() {
 
 		ec = clients.NewEnterpriseContract()
 
-		Expect(testsupport.InstallPrerequisites(cosign, rekorCli, ec)).To(Succeed())
+		image := testsupport.NewTestImage(testImage, testsupport.NewRegistry(), cosign)
+
+		Expect(testsupport.InstallPrerequisites(rekorCli, ec, image)).To(Succeed())
 
 		DeferCleanup(func() {
 			if err := testsupport.DestroyPrerequisites(); err != nil {
@@ -68,115 +62,45 @@ var _ = Describe("Cosign test", Ordered, func() {
 			}
 		})
 
+		targetImageName = image.Name
+
 		tempDir, err = os.MkdirTemp("", "tmp")
 		Expect(err).ToNot(HaveOccurred())
-
-		manualImageSetup := api.GetValueFor(api.ManualImageSetup) == "true"
-		if !manualImageSetup {
-			targetImageName = "ttl.sh/" + uuid.New().String() + ":5m"
-			dockerCli, err = client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-			Expect(err).ToNot(HaveOccurred())
-
-			var pull io.ReadCloser
-			pull, err = dockerCli.ImagePull(testsupport.TestContext, testImage, image.PullOptions{})
-			Expect(err).ToNot(HaveOccurred())
-			_, err = io.Copy(os.Stdout, pull)
-			Expect(err).ToNot(HaveOccurred())
-			defer pull.Close()
-
-			Expect(dockerCli.ImageTag(testsupport.TestContext, testImage, targetImageName)).To(Succeed())
-			var push io.ReadCloser
-			// use empty auth to avoid  "invalid X-Registry-Auth header: EOF" (https://github.com/moby/moby/issues/10983
-			push, err = dockerCli.ImagePush(testsupport.TestContext, targetImageName, image.PushOptions{RegistryAuth: base64.StdEncoding.EncodeToString([]byte("{}"))})
-			Expect(err).ToNot(HaveOccurred())
-			_, err = io.Copy(os.Stdout, push)
-			Expect(err).ToNot(HaveOccurred())
-			defer push.Close()
-		} else {
-			targetImageName = api.GetValueFor(api.TargetImageName)
-			Expect(targetImageName).NotTo(BeEmpty(), "TARGET_IMAGE_NAME environment variable must be set when MANUAL_IMAGE_SETUP is true")
-		}
+		DeferCleanup(os.RemoveAll, tempDir)
 	})
 
 	Describe("Cosign initialize", func() {
-		It("should initialize the TUF root", func() {
-			Eventually(func() error {
-				return cosign.Command(testsupport.TestContext, "initialize").Run()
-			}).WithTimeout(testsupport.CommandRetryTimeout).WithPolling(testsupport.CommandRetryInterval).Should(Succeed())
+		It("should initialize the TUF root", func(ctx SpecContext) {
+			Expect(cosign.Initialize(ctx, clients.InitializeOptions{})).To(Succeed())
 		})
 	})
 
 	Describe("cosign sign", func() {
-		It("should sign the container", func() {
-			token, err := testsupport.GetOIDCToken(testsupport.TestContext)
+		It("should sign the container", func(ctx SpecContext) {
+			token, err := testsupport.GetOIDCToken(ctx)
 			Expect(err).ToNot(HaveOccurred())
 
-			Expect(cosign.Command(testsupport.TestContext, "sign", "--identity-token="+token, targetImageName).Run()).To(Succeed())
+			_, err = cosign.Sign(ctx, targetImageName, clients.SignOptions{IdentityToken: token})
+			Expect(err).ToNot(HaveOccurred())
 
 			// Extract logIndex by downloading signature bundles from the registry.
 			// Multiple bundles may exist if the image was signed more than once;
 			// pick the one with the highest logIndex (most recent).
-			// Retry the download — the registry may not have propagated the
-			// signature immediately after signing.
-			type sigBundle struct {
-				VerificationMaterial struct {
-					TlogEntries []struct {
-						LogIndex json.RawMessage `json:"logIndex"`
-					} `json:"tlogEntries"`
-				} `json:"verificationMaterial"`
-				DSSEEnvelope json.RawMessage `json:"dsseEnvelope"`
-			}
-
-			var bestBundle sigBundle
-			var lastErr error
-			logIndex = -1
-			const (
-				maxRetries = 5
-				retryDelay = 2 * time.Second
-			)
-			for attempt := 0; attempt < maxRetries; attempt++ {
-				if attempt > 0 {
-					logrus.Warnf("bundle download: attempt %d/%d (previous: %v)", attempt+1, maxRetries, lastErr)
-					time.Sleep(retryDelay)
-				}
-
-				bundleOutput, err := cosign.CommandOutput(testsupport.TestContext, "download", "signature", targetImageName)
+			// The download can succeed before the registry serves the bundle
+			// with the tlog entry. The client only retries failed downloads, so
+			// poll until the entry shows up.
+			var bestBundle *clients.Bundle
+			Eventually(func() error {
+				bundles, err := cosign.Download(ctx, targetImageName)
 				if err != nil {
-					lastErr = fmt.Errorf("download failed: %w", err)
-					continue
+					return err
 				}
-
-				startIdx := strings.Index(string(bundleOutput), "{")
-				if startIdx == -1 {
-					lastErr = fmt.Errorf("no JSON object in bundle output")
-					continue
+				if bestBundle = clients.LatestBundle(bundles); bestBundle == nil {
+					return errors.New("no signature bundle with a tlog entry yet")
 				}
-
-				decoder := json.NewDecoder(strings.NewReader(string(bundleOutput[startIdx:])))
-				for decoder.More() {
-					var b sigBundle
-					if err := decoder.Decode(&b); err != nil {
-						break
-					}
-					if len(b.VerificationMaterial.TlogEntries) == 0 {
-						continue
-					}
-					idx, err := strconv.Atoi(strings.Trim(string(b.VerificationMaterial.TlogEntries[0].LogIndex), "\""))
-					if err != nil {
-						continue
-					}
-					if idx > logIndex {
-						logIndex = idx
-						bestBundle = b
-					}
-				}
-
-				if logIndex >= 0 {
-					break
-				}
-				lastErr = fmt.Errorf("no bundle with valid tlog entry")
-			}
-			Expect(logIndex).To(BeNumerically(">=", 0), fmt.Sprintf("no valid signature bundle found after %d attempts: %v", maxRetries, lastErr))
+				return nil
+			}).WithTimeout(clients.RegistryRetryPolicy.MaxElapsed).WithPolling(clients.RegistryRetryPolicy.InitialInterval).Should(Succeed())
+			logIndex = int(bestBundle.LogIndex())
 
 			if len(bestBundle.DSSEEnvelope) > 0 {
 				dsseEnvelopePath = filepath.Join(tempDir, "dsse-envelope.json")
@@ -186,52 +110,25 @@ var _ = Describe("Cosign test", Ordered, func() {
 	})
 
 	Describe("cosign verify", func() {
-		It("should verify the signature", func() {
-			Eventually(func() error {
-				_, err := cosign.CommandOutput(testsupport.TestContext, "verify", "--certificate-identity-regexp", ".*"+regexp.QuoteMeta(api.GetValueFor(api.OidcUserDomain)), "--certificate-oidc-issuer-regexp", regexp.QuoteMeta(api.GetValueFor(api.OidcIssuerURL)), targetImageName)
-				return err
-			}).WithTimeout(testsupport.CommandRetryTimeout).WithPolling(testsupport.CommandRetryInterval).Should(Succeed())
+		It("should verify the signature", func(ctx SpecContext) {
+			_, err := cosign.Verify(ctx, targetImageName, clients.VerifyOptions{
+				CertificateIdentityRegexp:   ".*" + regexp.QuoteMeta(api.GetValueFor(api.OidcUserDomain)),
+				CertificateOIDCIssuerRegexp: regexp.QuoteMeta(api.GetValueFor(api.OidcIssuerURL)),
+			})
+			Expect(err).ToNot(HaveOccurred())
 		})
 	})
 
 	Describe("rekor-cli get (via --log-index)", func() {
-		It("should retrieve the entry from Rekor and create public-key and signature files", func() {
-			rekorServerURL := api.GetValueFor(api.RekorURL)
-			logIndexStr := strconv.Itoa(logIndex)
-
-			output, err := rekorCli.CommandOutput(testsupport.TestContext, "get", "--rekor_server", rekorServerURL, "--log-index", logIndexStr)
-			Expect(err).ToNot(HaveOccurred())
-
-			// Look for JSON start
-			startIndex := strings.Index(string(output), "{")
-			Expect(startIndex).NotTo(Equal(-1), "JSON start - '{' not found")
-
-			jsonStr := string(output[startIndex:])
-
-			var rekorGetOutput testsupport.RekorCLIGetOutput
-			err = json.Unmarshal([]byte(jsonStr), &rekorGetOutput)
+		It("should retrieve the entry from Rekor and create public-key and signature files", func(ctx SpecContext) {
+			entry, err := rekorCli.Get(ctx, clients.RekorEntryRef{LogIndex: int64(logIndex)})
 			Expect(err).ToNot(HaveOccurred())
 
 			// Extract values from rekor-cli get output - handle both HashedRekordObj and DSSEObj
-			var signatureContent, publicKeyContent string
-			if len(rekorGetOutput.DSSEObj.Signatures) > 0 {
-				rekorEntryType = "dsse:0.0.1"
-				signatureContent = rekorGetOutput.DSSEObj.Signatures[0].Signature
-				publicKeyContent = rekorGetOutput.DSSEObj.Signatures[0].Verifier
-				hashValue = rekorGetOutput.DSSEObj.PayloadHash.Value
-			} else if rekorGetOutput.HashedRekordObj.Signature.Content != "" {
-				rekorEntryType = "hashedrekord:0.0.1"
-				signatureContent = rekorGetOutput.HashedRekordObj.Signature.Content
-				publicKeyContent = rekorGetOutput.HashedRekordObj.Signature.PublicKey.Content
-				hashValue = rekorGetOutput.HashedRekordObj.Data.Hash.Value
-			} else if rekorGetOutput.RekordObj.Signature.Content != "" {
-				rekorEntryType = "rekord:0.0.1"
-				signatureContent = rekorGetOutput.RekordObj.Signature.Content
-				publicKeyContent = rekorGetOutput.RekordObj.Signature.PublicKey.Content
-				hashValue = rekorGetOutput.RekordObj.Data.Hash.Value
-			} else {
-				Fail("Unrecognized Rekor entry type in rekor-cli get output")
-			}
+			rekorEntryType = entry.Body.Type()
+			Expect(rekorEntryType).ToNot(BeEmpty(), "Unrecognized Rekor entry type in rekor-cli get output")
+			signatureContent, publicKeyContent := entry.Body.Signature()
+			hashValue = entry.Body.Hash().Value
 
 			// Decode signatureContent and publicKeyContent from base64
 			decodedSignatureContent, err := base64.StdEncoding.DecodeString(signatureContent)
@@ -250,15 +147,21 @@ var _ = Describe("Cosign test", Ordered, func() {
 	})
 
 	Describe("rekor-cli verify", func() {
-		It("should verify the artifact using rekor-cli", func() {
-			rekorServerURL := api.GetValueFor(api.RekorURL)
-
-			if rekorEntryType == "dsse:0.0.1" {
+		It("should verify the artifact using rekor-cli", func(ctx SpecContext) {
+			opts := clients.RekorArtifactOptions{
+				PublicKey: publicKeyPath,
+				PKIFormat: "x509",
+				Type:      rekorEntryType,
+			}
+			if rekorEntryType == clients.RekorTypeDSSE {
 				// DSSE entries require the full envelope as --artifact
-				Expect(rekorCli.Command(testsupport.TestContext, "verify", "--rekor_server", rekorServerURL, "--artifact", dsseEnvelopePath, "--public-key", publicKeyPath, "--pki-format", "x509", "--type", rekorEntryType).Run()).To(Succeed())
+				opts.Artifact = dsseEnvelopePath
 			} else {
-				Expect(rekorCli.Command(testsupport.TestContext, "verify", "--rekor_server", rekorServerURL, "--signature", signaturePath, "--public-key", publicKeyPath, "--pki-format", "x509", "--type", rekorEntryType, "--artifact-hash", hashValue).Run()).To(Succeed())
+				opts.Signature = signaturePath
+				opts.ArtifactHash = hashValue
 			}
+			_, err := rekorCli.Verify(ctx, opts, nil)
+			Expect(err).ToNot(HaveOccurred())
 		})
 	})
 
@@ -287,83 +190,51 @@ var _ = Describe("Cosign test", Ordered, func() {
 			Expect(os.WriteFile(predicatePath, []byte(predicateJSONContent), 0600)).To(Succeed())
 		})
 
-		It("should sign and attach the predicate as an attestation to the image", func() {
-			token, err := testsupport.GetOIDCToken(testsupport.TestContext)
+		It("should sign and attach the predicate as an attestation to the image", func(ctx SpecContext) {
+			token, err := testsupport.GetOIDCToken(ctx)
 			Expect(err).ToNot(HaveOccurred())
 
-			Expect(cosign.Command(testsupport.TestContext, "attest", "--identity-token="+token, "--predicate", predicatePath, "--type", "slsaprovenance", targetImageName).Run()).To(Succeed())
+			_, err = cosign.Attest(ctx, targetImageName, clients.AttestOptions{
+				IdentityToken: token,
+				PredicatePath: predicatePath,
+				Type:          "slsaprovenance",
+			})
+			Expect(err).ToNot(HaveOccurred())
 		})
 	})
 
 	Describe("cosign tree", func() {
-		It("should verify that the container image has at least one attestation and signature", func() {
-			output, err := cosign.CommandOutput(testsupport.TestContext, "tree", targetImageName)
-			Expect(err).ToNot(HaveOccurred())
-
-			// Matching (generic) hash entries
-			hashPattern := regexp.MustCompile(`└── 🍒 \w+:[0-9a-f]{64}`)
+		It("should list the signature and attestation of the image by tag", func(ctx SpecContext) {
+			testsupport.SkipWithCapability(cosign, clients.CosignOCIReferrersDefault)
 
-			lines := strings.Split(string(output), "\n")
+			tree, err := cosign.Tree(ctx, targetImageName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tree.Attestations).ToNot(BeEmpty(), "Expected the image to have at least one attestation")
+			Expect(tree.Signatures).ToNot(BeEmpty(), "Expected the image to have at least one signature")
+		})
 
-			usesOCIReferrers := false
-			for _, line := range lines {
-				if strings.Contains(line, "OCI referrer") || strings.Contains(line, "via OCI") {
-					usesOCIReferrers = true
-					break
-				}
-			}
+		It("should list the signature and attestation of the image as OCI referrers", func(ctx SpecContext) {
+			testsupport.RequireCapabilities(cosign, clients.CosignOCIReferrersDefault)
 
-			if usesOCIReferrers {
-				artifactCount := 0
-				for _, line := range lines {
-					if hashPattern.MatchString(line) {
-						artifactCount++
-					}
-				}
-				Expect(artifactCount).To(BeNumerically(">=", 2),
-					"Expected at least 2 artifacts (signature + attestation) in cosign tree output")
-			} else {
-				inSignatureSection := false
-				inAttestationSection := false
-				hasSignature := false
-				hasAttestation := false
-
-				for _, line := range lines {
-					if strings.Contains(line, "Signatures for an image tag:") {
-						inSignatureSection = true
-						inAttestationSection = false
-						continue
-					} else if strings.Contains(line, "Attestations for an image tag:") {
-						inSignatureSection = false
-						inAttestationSection = true
-						continue
-					}
-
-					if inSignatureSection && hashPattern.MatchString(line) {
-						hasSignature = true
-					} else if inAttestationSection && hashPattern.MatchString(line) {
-						hasAttestation = true
-					}
-				}
-
-				Expect(hasAttestation).To(BeTrue(), "Expected the image to have at least one attestation")
-				Expect(hasSignature).To(BeTrue(), "Expected the image to have at least one signature")
-			}
+			tree, err := cosign.Tree(ctx, targetImageName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tree.OCIReferrers).To(BeTrue(), "Expected cosign tree to list OCI referrers")
+			Expect(tree.Artifacts()).To(BeNumerically(">=", 2),
+				"Expected at least 2 artifacts (signature + attestation) in cosign tree output")
 		})
 	})
 
 	Describe("ec validate", func() {
-		It("should initialize ec TUF root", func() {
+		It("should initialize ec TUF root", func(ctx SpecContext) {
 			tufURL := api.GetValueFor(api.TufURL)
-			Eventually(func() error {
-				return ec.Command(testsupport.TestContext, "sigstore", "initialize",
-					"--mirror", tufURL,
-					"--root", tufURL+"/root.json").Run()
-			}).WithTimeout(testsupport.CommandRetryTimeout).WithPolling(testsupport.CommandRetryInterval).Should(Succeed())
+			Expect(ec.Initialize(ctx, clients.InitializeOptions{
+				Mirror: tufURL,
+				Root:   tufURL + "/root.json",
+			})).To(Succeed())
 		})
 
-		It("should verify signature and attestation of the image", func() {
-			output, err := ec.CommandOutput(testsupport.TestContext, "validate", "image", "--image", targetImageName, "--certificate-identity-regexp", ".*"+regexp.QuoteMeta(api.GetValueFor(api.OidcUserDomain)), "--certificate-oidc-issuer-regexp", ".*"+regexp.QuoteMeta(api.GetValueFor(api.OidcIssuerURL)), "--output", "yaml", "--show-successes")
+		It("should verify signature and attestation of the image", func(ctx SpecContext) {
+			output, err := ec.CommandOutput(ctx, "validate", "image", "--image", targetImageName, "--certificate-identity-regexp", ".*"+regexp.QuoteMeta(api.GetValueFor(api.OidcUserDomain)), "--certificate-oidc-issuer-regexp", ".*"+regexp.QuoteMeta(api.GetValueFor(api.OidcIssuerURL)), "--output", "yaml", "--show-successes")
 			Expect(err).ToNot(HaveOccurred())
 
 			successPatterns := []*regexp.Regexp{
@@ -382,8 +253,3 @@ var _ = Describe("Cosign test", Ordered, func() {
 		})
 	})
 })
-
-var _ = AfterSuite(func() {
-	// Cleanup shared resources after all tests have run.
-	Expect(os.RemoveAll(tempDir)).To(Succeed())
-})
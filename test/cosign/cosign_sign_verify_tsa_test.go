@@ -1,17 +1,11 @@
 package cosign
 
 import (
-	"encoding/base64"
-	"io"
-	"os"
 	"regexp"
 
-	"github.com/docker/docker/api/types/image"
-
-	"github.com/docker/docker/client"
-	"github.com/google/uuid"
 	"github.com/securesign/sigstore-e2e/pkg/api"
 	"github.com/securesign/sigstore-e2e/pkg/clients"
+	"github.com/securesign/sigstore-e2e/pkg/preflight"
 	"github.com/securesign/sigstore-e2e/test/testsupport"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -26,9 +20,8 @@ var tsaTargetImageName string
 var _ = Describe("TSA test", Ordered, func() {
 
 	var (
-		err       error
-		dockerCli *client.Client
-		cosign    *clients.Cosign
+		err    error
+		cosign *clients.Cosign
 	)
 
 	BeforeAll(func() {
@@ -37,10 +30,13 @@ var _ = Describe("TSA test", Ordered, func() {
 		if err != nil {
 			Fail(err.Error())
 		}
+		testsupport.RequireServices(preflight.TUF, preflight.Fulcio, preflight.Rekor, preflight.TSA, preflight.OIDC)
 
 		cosign = clients.NewCosign()
 
-		Expect(testsupport.InstallPrerequisites(cosign)).To(Succeed())
+		image := testsupport.NewTestImage(tsaTestImage, testsupport.NewRegistry(), cosign)
+
+		Expect(testsupport.InstallPrerequisites(image)).To(Succeed())
 
 		DeferCleanup(func() {
 			if err := testsupport.DestroyPrerequisites(); err != nil {
@@ -48,53 +44,33 @@ var _ = Describe("TSA test", Ordered, func() {
 			}
 		})
 
-		manualImageSetup := api.GetValueFor(api.ManualImageSetup) == "true"
-		if !manualImageSetup {
-			tsaTargetImageName = "ttl.sh/" + uuid.New().String() + ":5m"
-			dockerCli, err = client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-			Expect(err).ToNot(HaveOccurred())
-
-			var pull io.ReadCloser
-			pull, err = dockerCli.ImagePull(testsupport.TestContext, tsaTestImage, image.PullOptions{})
-			Expect(err).ToNot(HaveOccurred())
-			_, err = io.Copy(os.Stdout, pull)
-			Expect(err).ToNot(HaveOccurred())
-			defer pull.Close()
-
-			Expect(dockerCli.ImageTag(testsupport.TestContext, tsaTestImage, tsaTargetImageName)).To(Succeed())
-			var push io.ReadCloser
-			push, err = dockerCli.ImagePush(testsupport.TestContext, tsaTargetImageName, image.PushOptions{RegistryAuth: base64.StdEncoding.EncodeToString([]byte("{}"))})
-			Expect(err).ToNot(HaveOccurred())
-			_, err = io.Copy(os.Stdout, push)
-			Expect(err).ToNot(HaveOccurred())
-			defer push.Close()
-		} else {
-			tsaTargetImageName = api.GetValueFor(api.TargetImageName)
-			Expect(tsaTargetImageName).NotTo(BeEmpty(), "TARGET_IMAGE_NAME environment variable must be set when MANUAL_IMAGE_SETUP is true")
-		}
+		tsaTargetImageName = image.Name
 	})
 
 	Describe("Cosign initialize", func() {
-		It("should initialize the cosign root", func() {
-			Eventually(func() error {
-				return cosign.Command(testsupport.TestContext, "initialize").Run()
-			}).WithTimeout(testsupport.CommandRetryTimeout).WithPolling(testsupport.CommandRetryInterval).Should(Succeed())
+		It("should initialize the cosign root", func(ctx SpecContext) {
+			Expect(cosign.Initialize(ctx, clients.InitializeOptions{})).To(Succeed())
 		})
 	})
 
 	Describe("cosign sign tsa", func() {
-		It("should sign the container using TSA", func() {
-			token, err := testsupport.GetOIDCToken(testsupport.TestContext)
+		It("should sign the container using TSA", func(ctx SpecContext) {
+			token, err := testsupport.GetOIDCToken(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = cosign.Sign(ctx, tsaTargetImageName, clients.SignOptions{IdentityToken: token})
 			Expect(err).ToNot(HaveOccurred())
-			Expect(cosign.Command(testsupport.TestContext, "sign", "--identity-token="+token, tsaTargetImageName).Run()).To(Succeed())
 		})
 	})
 
 	Describe("cosign verify tsa", func() {
-		It("should verify the signature using TSA", func() {
-			Eventually(func() error {
-				return cosign.Command(testsupport.TestContext, "verify", "--use-signed-timestamps", "--certificate-identity-regexp", ".*"+regexp.QuoteMeta(api.GetValueFor(api.OidcUserDomain)), "--certificate-oidc-issuer-regexp", regexp.QuoteMeta(api.GetValueFor(api.OidcIssuerURL)), tsaTargetImageName).Run()
-			}).WithTimeout(testsupport.CommandRetryTimeout).WithPolling(testsupport.CommandRetryInterval).Should(Succeed())
+		It("should verify the signature using TSA", func(ctx SpecContext) {
+			testsupport.RequireCapabilities(cosign, clients.CosignSignedTimestamps)
+			_, err := cosign.Verify(ctx, tsaTargetImageName, clients.VerifyOptions{
+				CertificateIdentityRegexp:   ".*" + regexp.QuoteMeta(api.GetValueFor(api.OidcUserDomain)),
+				CertificateOIDCIssuerRegexp: regexp.QuoteMeta(api.GetValueFor(api.OidcIssuerURL)),
+				UseSignedTimestamps:         true,
+			})
+			Expect(err).ToNot(HaveOccurred())
 		})
 	})
 })
@@ -0,0 +1,74 @@
+package cosign
+
+import (
+	"context"
+	"errors"
+
+	"github.com/securesign/sigstore-e2e/pkg/clients"
+	"github.com/securesign/sigstore-e2e/pkg/tuf/tuftest"
+	"github.com/securesign/sigstore-e2e/test/testsupport"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+var _ = Describe("Trust root test", Ordered, func() {
+
+	var (
+		cosign *clients.Cosign
+		tuf    *testsupport.LocalTUF
+	)
+
+	initialize := func(ctx context.Context) error {
+		return cosign.Initialize(ctx, clients.InitializeOptions{Mirror: tuf.URL, Root: tuf.RootPath})
+	}
+
+	BeforeAll(func() {
+		logrus.Infof("Starting trust root test")
+		tuf = testsupport.NewLocalTUF(map[string][]byte{
+			tuftest.TrustedRootTarget: []byte(`{"mediaType": "application/vnd.dev.sigstore.trustedroot+json;version=0.1"}`),
+		})
+		tuf.Rekor = testsupport.NewFakeRekor()
+		cosign = clients.NewCosign()
+		Expect(testsupport.InstallPrerequisites(tuf, cosign)).To(Succeed())
+		DeferCleanup(func() {
+			if err := testsupport.DestroyPrerequisites(); err != nil {
+				logrus.Warn("Env was not cleaned-up" + err.Error())
+			}
+		})
+	})
+
+	BeforeEach(func() {
+		// start every spec with an empty TUF cache
+		Expect(cosign.Env().Remove()).To(Succeed())
+		Expect(cosign.Env().Prepare(cosign.GetName())).To(Succeed())
+	})
+
+	It("should initialize from a valid repository", func(ctx SpecContext) {
+		Expect(initialize(ctx)).To(Succeed())
+	})
+
+	DescribeTable("should reject a bad trust root",
+		func(ctx SpecContext, fault tuftest.Fault, cached bool, rejection string) {
+			if cached {
+				By("initializing from a newer version of the repository")
+				Expect(tuf.Publish(nil)).To(Succeed())
+				Expect(initialize(ctx)).To(Succeed())
+			}
+			Expect(tuf.SetFault(fault)).To(Succeed())
+			DeferCleanup(tuf.SetFault, tuftest.FaultNone)
+			err := initialize(ctx)
+			var cmdErr *clients.CommandError
+			Expect(errors.As(err, &cmdErr)).To(BeTrue(), "expected initialize to fail, got %v", err)
+			Expect(cmdErr.Stderr).To(MatchRegexp(rejection), "initialize should fail because of the %s fault", fault)
+		},
+		// the patterns cover the errors of both go-tuf v0 and v2
+		Entry("with expired metadata", tuftest.FaultExpired, false, `(?i)expired`),
+		Entry("with metadata signed by an untrusted key", tuftest.FaultWrongSignature, false,
+			`(?i)signature|threshold|unsigned metadata`),
+		Entry("with a rollback to an older version", tuftest.FaultRollback, true,
+			`(?i)version \d+ is lower than|bad version number|version.*(rollback|must be)`),
+		Entry("with a frozen timestamp", tuftest.FaultFrozenTimestamp, false, `(?i)expired`),
+	)
+})
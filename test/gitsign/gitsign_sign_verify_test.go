@@ -1,31 +1,27 @@
 package gitsign
 
 import (
-	"bytes"
 	"encoding/base64"
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
-	"strings"
 	"time"
 
 	"github.com/securesign/sigstore-e2e/test/testsupport"
 
 	"github.com/securesign/sigstore-e2e/pkg/clients"
+	"github.com/securesign/sigstore-e2e/pkg/preflight"
 
 	"github.com/securesign/sigstore-e2e/pkg/api"
 
 	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/config"
 	"github.com/google/uuid"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/sirupsen/logrus"
 )
 
-var logIndex string
+var logIndex int64
 var hashValue string
 var rekorEntryType string
 var tempDir string
@@ -37,16 +33,16 @@ var _ = Describe("Signing and verifying commits by using Gitsign from the comman
 	var rekorCli = clients.NewRekorCli()
 
 	var (
-		dir    string
-		config *config.Config
-		repo   *git.Repository
-		err    error
+		dir  string
+		repo *git.Repository
+		err  error
 	)
 	BeforeAll(func() {
 		err = testsupport.CheckMandatoryAPIConfigValues(api.OidcIssuerURL, api.RekorURL, api.TufURL, api.FulcioURL)
 		if err != nil {
 			Fail(err.Error())
 		}
+		testsupport.RequireServices(preflight.TUF, preflight.Fulcio, preflight.Rekor, preflight.OIDC)
 
 		Expect(testsupport.InstallPrerequisites(
 			gitsign,
@@ -62,45 +58,34 @@ var _ = Describe("Signing and verifying commits by using Gitsign from the comman
 		// tempDir for publickey and signature
 		tempDir, err = os.MkdirTemp("", "rekorTest")
 		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(os.RemoveAll, tempDir)
 
 		// initialize local git repository
 		dir, err = os.MkdirTemp("", "repository")
 		Expect(err).ToNot(HaveOccurred())
 		repo, err = git.PlainInit(dir, false)
 		Expect(err).ToNot(HaveOccurred())
-		config, err = repo.Config()
-		Expect(err).ToNot(HaveOccurred())
 	})
 
 	Describe("Gitsign initialize", func() {
-		It("should initialize the TUF root", func() {
+		It("should initialize the TUF root", func(ctx SpecContext) {
 			tufURL := api.GetValueFor(api.TufURL)
-			Eventually(func() error {
-				return gitsign.Command(testsupport.TestContext, "initialize",
-					"--mirror", tufURL,
-					"--root", tufURL+"/root.json").Run()
-			}).WithTimeout(testsupport.CommandRetryTimeout).WithPolling(testsupport.CommandRetryInterval).Should(Succeed())
+			Expect(gitsign.Initialize(ctx, clients.InitializeOptions{
+				Mirror: tufURL,
+				Root:   tufURL + "/root.json",
+			})).To(Succeed())
 		})
 	})
 
 	Context("With configured git", func() {
-		It("sets the local repository to use OIDC user", func() {
-			config.User.Name = "John Doe"
-			config.User.Email = fmt.Sprintf("%s@%s", api.GetValueFor(api.OidcUser), api.GetValueFor(api.OidcUserDomain))
-
-			Expect(repo.SetConfig(config)).To(Succeed())
-		})
-
-		It("configures the local repository to sign commits with gitsign", func() {
-			config.Raw.AddOption("commit", "", "gpgsign", "true")
-			config.Raw.AddOption("tag", "", "gpgsign", "true")
-			config.Raw.AddOption("gpg", "x509", "program", "gitsign")
-			config.Raw.AddOption("gpg", "", "format", "x509")
-			config.Raw.AddOption("gitsign", "", "fulcio", api.GetValueFor(api.FulcioURL))
-			config.Raw.AddOption("gitsign", "", "rekor", api.GetValueFor(api.RekorURL))
-			config.Raw.AddOption("gitsign", "", "issuer", api.GetValueFor(api.OidcIssuerURL))
-
-			Expect(repo.SetConfig(config)).To(Succeed())
+		It("configures the local repository to sign commits with gitsign as OIDC user", func(ctx SpecContext) {
+			Expect(gitsign.SetupRepository(ctx, dir, clients.GitsignRepoConfig{
+				UserName:  "John Doe",
+				UserEmail: fmt.Sprintf("%s@%s", api.GetValueFor(api.OidcUser), api.GetValueFor(api.OidcUserDomain)),
+				Fulcio:    api.GetValueFor(api.FulcioURL),
+				Rekor:     api.GetValueFor(api.RekorURL),
+				Issuer:    api.GetValueFor(api.OidcIssuerURL),
+			})).To(Succeed())
 		})
 	})
 
@@ -114,11 +99,13 @@ var _ = Describe("Signing and verifying commits by using Gitsign from the comman
 			Expect(err).ToNot(HaveOccurred())
 		})
 
-		It("gets ID token and makes commit", func() {
-			token, err := testsupport.GetOIDCToken(testsupport.TestContext)
+		It("gets ID token and makes commit", func(ctx SpecContext) {
+			token, err := testsupport.GetOIDCToken(ctx)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(token).To(Not(BeEmpty()))
-			Expect(gitsign.GitWithGitSign(testsupport.TestContext, dir, token, "commit", "-S", "-m", "CI commit "+time.Now().String())).To(Succeed())
+			result, err := gitsign.Commit(ctx, dir, token, "CI commit "+time.Now().String())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.LogIndex).To(BeNumerically(">=", 0))
 		})
 
 		It("checks that commit has PGP signature", func() {
@@ -136,89 +123,49 @@ var _ = Describe("Signing and verifying commits by using Gitsign from the comman
 
 	Describe("Verify the commit", func() {
 		When("commiter is authorized", func() {
-			It("should verify HEAD signature by gitsign", func() {
-				// Retry gitsign verify — the TUF client's atomic temp-dir
-				// rename can race with disk I/O on CI runners.
-				const (
-					maxRetries = 5
-					retryDelay = 3 * time.Second
-				)
-				var output bytes.Buffer
-				var lastErr error
-
-				for attempt := 0; attempt < maxRetries; attempt++ {
-					if attempt > 0 {
-						logrus.Warnf("gitsign verify: attempt %d/%d (previous: %v)", attempt+1, maxRetries, lastErr)
-						time.Sleep(retryDelay)
-					}
-
-					cmd := gitsign.Command(testsupport.TestContext, "verify",
-						"--certificate-identity", fmt.Sprintf("%s@%s", api.GetValueFor(api.OidcUser), api.GetValueFor(api.OidcUserDomain)),
-						"--certificate-oidc-issuer", api.GetValueFor(api.OidcIssuerURL),
-						"HEAD")
-
-					cmd.Dir = dir
-					cmd.Env = os.Environ()
-
-					output.Reset()
-					cmd.Stdout = &output
-
-					if err := cmd.Run(); err != nil {
-						lastErr = fmt.Errorf("gitsign verify failed: %w", err)
-						continue
-					}
-					lastErr = nil
-					break
-				}
-				Expect(lastErr).ToNot(HaveOccurred(), fmt.Sprintf("gitsign verify failed after %d attempts", maxRetries))
-
-				logrus.WithField("app", "gitsign").Info(output.String())
-
-				re := regexp.MustCompile(`tlog index: (\d+)`)
-				match := re.FindStringSubmatch(output.String())
-
-				logIndex = match[1]
+			It("should verify HEAD signature by gitsign", func(ctx SpecContext) {
+				result, err := gitsign.Verify(ctx, dir, "HEAD", clients.GitsignVerifyOptions{
+					CertificateIdentity:   fmt.Sprintf("%s@%s", api.GetValueFor(api.OidcUser), api.GetValueFor(api.OidcUserDomain)),
+					CertificateOIDCIssuer: api.GetValueFor(api.OidcIssuerURL),
+				})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.GitSignatureValid).To(BeTrue())
+				Expect(result.LogIndex).To(BeNumerically(">=", 0))
+
+				logIndex = result.LogIndex
 			})
 		})
 	})
 
-	Describe("rekor-cli get with logIndex", func() {
-		It("should retrieve the entry from Rekor", func() {
-			rekorServerURL := api.GetValueFor(api.RekorURL)
-
-			output, err := rekorCli.CommandOutput(testsupport.TestContext, "get", "--rekor_server", rekorServerURL, "--log-index", logIndex)
+	Describe("Sign and verify a tag", func() {
+		It("creates a signed tag of HEAD", func(ctx SpecContext) {
+			token, err := testsupport.GetOIDCToken(ctx)
 			Expect(err).ToNot(HaveOccurred())
+			result, err := gitsign.Tag(ctx, dir, token, "v0.0.1", "CI tag")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.LogIndex).To(BeNumerically(">=", 0))
+		})
 
-			// Look for JSON start
-			startIndex := strings.Index(string(output), "{")
-			Expect(startIndex).NotTo(Equal(-1), "JSON start - '{' not found")
-
-			jsonStr := string(output[startIndex:])
+		It("should verify the tag signature by gitsign", func(ctx SpecContext) {
+			result, err := gitsign.VerifyTag(ctx, dir, "v0.0.1", clients.GitsignVerifyOptions{
+				CertificateIdentity:   fmt.Sprintf("%s@%s", api.GetValueFor(api.OidcUser), api.GetValueFor(api.OidcUserDomain)),
+				CertificateOIDCIssuer: api.GetValueFor(api.OidcIssuerURL),
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.GitSignatureValid).To(BeTrue())
+		})
+	})
 
-			var rekorGetOutput testsupport.RekorCLIGetOutput
-			err = json.Unmarshal([]byte(jsonStr), &rekorGetOutput)
+	Describe("rekor-cli get with logIndex", func() {
+		It("should retrieve the entry from Rekor", func(ctx SpecContext) {
+			entry, err := rekorCli.Get(ctx, clients.RekorEntryRef{LogIndex: logIndex})
 			Expect(err).ToNot(HaveOccurred())
 
 			// Extract values from rekor-cli get output - handle both HashedRekordObj and DSSEObj
-			var signatureContent, publicKeyContent string
-			if len(rekorGetOutput.DSSEObj.Signatures) > 0 {
-				rekorEntryType = "dsse:0.0.1"
-				signatureContent = rekorGetOutput.DSSEObj.Signatures[0].Signature
-				publicKeyContent = rekorGetOutput.DSSEObj.Signatures[0].Verifier
-				hashValue = rekorGetOutput.DSSEObj.PayloadHash.Value
-			} else if rekorGetOutput.HashedRekordObj.Signature.Content != "" {
-				rekorEntryType = "hashedrekord:0.0.1"
-				signatureContent = rekorGetOutput.HashedRekordObj.Signature.Content
-				publicKeyContent = rekorGetOutput.HashedRekordObj.Signature.PublicKey.Content
-				hashValue = rekorGetOutput.HashedRekordObj.Data.Hash.Value
-			} else if rekorGetOutput.RekordObj.Signature.Content != "" {
-				rekorEntryType = "rekord:0.0.1"
-				signatureContent = rekorGetOutput.RekordObj.Signature.Content
-				publicKeyContent = rekorGetOutput.RekordObj.Signature.PublicKey.Content
-				hashValue = rekorGetOutput.RekordObj.Data.Hash.Value
-			} else {
-				Fail("Unrecognized Rekor entry type in rekor-cli get output")
-			}
+			rekorEntryType = entry.Body.Type()
+			Expect(rekorEntryType).ToNot(BeEmpty(), "Unrecognized Rekor entry type in rekor-cli get output")
+			signatureContent, publicKeyContent := entry.Body.Signature()
+			hashValue = entry.Body.Hash().Value
 
 			// Decode signatureContent and publicKeyContent from base64
 			decodedSignatureContent, err := base64.StdEncoding.DecodeString(signatureContent)
@@ -238,14 +185,15 @@ var _ = Describe("Signing and verifying commits by using Gitsign from the comman
 	})
 
 	Describe("Rekor CLI Verify Artifact", func() {
-		It("should verify the artifact using rekor-cli", func() {
-			rekorServerURL := api.GetValueFor(api.RekorURL)
-			Expect(rekorCli.Command(testsupport.TestContext, "verify", "--rekor_server", rekorServerURL, "--signature", signaturePath, "--public-key", publicKeyPath, "--pki-format", "x509", "--type", rekorEntryType, "--artifact-hash", hashValue).Run()).To(Succeed())
+		It("should verify the artifact using rekor-cli", func(ctx SpecContext) {
+			_, err := rekorCli.Verify(ctx, clients.RekorArtifactOptions{
+				Signature:    signaturePath,
+				PublicKey:    publicKeyPath,
+				PKIFormat:    "x509",
+				Type:         rekorEntryType,
+				ArtifactHash: hashValue,
+			}, nil)
+			Expect(err).ToNot(HaveOccurred())
 		})
 	})
 })
-
-var _ = AfterSuite(func() {
-	// Cleanup shared resources after all tests have run.
-	Expect(os.RemoveAll(tempDir)).To(Succeed())
-})
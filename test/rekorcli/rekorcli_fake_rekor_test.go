@@ -0,0 +1,125 @@
+package rekorcli
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/securesign/sigstore-e2e/pkg/clients"
+	"github.com/securesign/sigstore-e2e/test/testsupport"
+	"github.com/sirupsen/logrus"
+)
+
+// The fake Rekor log is installed before rekor-cli, so REKOR_URL points at
+// it for the whole container. It needs no deployment, only rekor-cli.
+var _ = Describe("rekor-cli against a fake Rekor log", Ordered, func() {
+	const artifact = "artifact logged in the fake Rekor\n"
+
+	var (
+		rekor    *testsupport.FakeRekor
+		rekorCli *clients.RekorCli
+		options  clients.RekorArtifactOptions
+		entry    *clients.RekorUploadResult
+	)
+
+	BeforeAll(func() {
+		rekor = testsupport.NewFakeRekor()
+		rekorCli = clients.NewRekorCli()
+		Expect(testsupport.InstallPrerequisites(rekor, rekorCli)).To(Succeed())
+		DeferCleanup(func() {
+			if err := testsupport.DestroyPrerequisites(); err != nil {
+				logrus.Warn("Env was not cleaned-up" + err.Error())
+			}
+		})
+		Expect(api.GetValueFor(api.RekorURL)).To(Equal(rekor.URL))
+
+		dir := GinkgoT().TempDir()
+		options = clients.RekorArtifactOptions{
+			Artifact:  filepath.Join(dir, "artifact.txt"),
+			Signature: filepath.Join(dir, "artifact.sig"),
+			PublicKey: rekorKey,
+			PKIFormat: "x509",
+		}
+		Expect(os.WriteFile(options.Artifact, []byte(artifact), 0600)).To(Succeed())
+		signature, err := signWithKey("ec_private.pem", []byte(artifact))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.WriteFile(options.Signature, signature, 0600)).To(Succeed())
+	})
+
+	It("should upload the artifact", func(ctx SpecContext) {
+		var err error
+		entry, err = rekorCli.Upload(ctx, options)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entry.Index).To(BeNumerically(">=", 0))
+		Expect(rekor.Size()).To(BeNumerically(">", entry.Index))
+	})
+
+	It("should verify the inclusion of the artifact", func(ctx SpecContext) {
+		result, err := rekorCli.Verify(ctx, options, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Index).To(Equal(entry.Index))
+		Expect(result.RootHash).ToNot(BeEmpty())
+	})
+
+	It("should get the entry by index and UUID", func(ctx SpecContext) {
+		byIndex, err := rekorCli.Get(ctx, clients.RekorEntryRef{LogIndex: entry.Index})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(byIndex.Body.Type()).To(Equal(clients.RekorTypeRekord))
+		sum := sha256.Sum256([]byte(artifact))
+		Expect(byIndex.Body.Hash().Value).To(Equal(hex.EncodeToString(sum[:])))
+
+		byUUID, err := rekorCli.Get(ctx, clients.RekorEntryRef{UUID: byIndex.UUID})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(byUUID.LogIndex).To(Equal(entry.Index))
+	})
+
+	It("should find the entry by artifact, hash and public key", func(ctx SpecContext) {
+		sum := sha256.Sum256([]byte(artifact))
+		for _, search := range []clients.RekorSearchOptions{
+			{Artifact: options.Artifact},
+			{SHA: "sha256:" + hex.EncodeToString(sum[:])},
+			{PublicKey: rekorKey, PKIFormat: "x509"},
+		} {
+			uuids, err := rekorCli.Search(ctx, search)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(uuids).To(HaveLen(1), "search %+v", search)
+		}
+	})
+
+	It("should report a missing entry", func(ctx SpecContext) {
+		_, err := rekorCli.Get(ctx, clients.RekorEntryRef{LogIndex: entry.Index + 1000})
+		var rekorErr *clients.RekorError
+		Expect(errors.As(err, &rekorErr)).To(BeTrue(), "expected a Rekor API error, got %v", err)
+		Expect(rekorErr.StatusCode).To(Equal(404))
+	})
+})
+
+// signWithKey returns the ASN.1 ECDSA signature of the SHA-256 digest of
+// data with the PEM encoded EC key at keyPath, as 'openssl dgst -sha256 -sign' does.
+func signWithKey(keyPath string, data []byte) ([]byte, error) {
+	content, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	for block, rest := pem.Decode(content); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "EC PRIVATE KEY" {
+			continue
+		}
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		digest := sha256.Sum256(data)
+		return ecdsa.SignASN1(rand.Reader, key, digest[:])
+	}
+	return nil, errors.New(keyPath + ": no EC private key")
+}
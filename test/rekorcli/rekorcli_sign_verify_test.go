@@ -1,24 +1,22 @@
 package rekorcli
 
 import (
-	"encoding/json"
-	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
-	"strconv"
-	"strings"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/securesign/sigstore-e2e/pkg/api"
 	"github.com/securesign/sigstore-e2e/pkg/clients"
+	"github.com/securesign/sigstore-e2e/pkg/preflight"
 	"github.com/securesign/sigstore-e2e/test/testsupport"
 	"github.com/sirupsen/logrus"
 )
 
-var entryIndex int
+const rekorKey = "ec_public.pem"
+
+var entryIndex int64
 var hashWithAlg string
 var tempDir string
 var dirFilePath string
@@ -38,6 +36,7 @@ var _ = Describe("Verify entries, query the transparency log for inclusion proof
 		if err != nil {
 			Fail(err.Error())
 		}
+		testsupport.RequireServices(preflight.Rekor)
 
 		rekorCli = clients.NewRekorCli()
 
@@ -54,6 +53,7 @@ var _ = Describe("Verify entries, query the transparency log for inclusion proof
 		// tempDir for tarball and signature
 		tempDir, err = os.MkdirTemp("", "rekorTest")
 		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(os.RemoveAll, tempDir)
 
 		dirFilePath = filepath.Join(tempDir, "myrelease")
 		tarFilePath = filepath.Join(tempDir, "myrelease.tar.gz")
@@ -82,128 +82,98 @@ var _ = Describe("Verify entries, query the transparency log for inclusion proof
 	})
 
 	Describe("Upload artifact", func() {
-		It("should upload artifact", func() {
-			rekorServerURL := api.GetValueFor(api.RekorURL)
-			rekorKey := "ec_public.pem"
-			output, err := rekorCli.CommandOutput(testsupport.TestContext, "upload", "--rekor_server", rekorServerURL, "--signature", signatureFilePath, "--pki-format=x509", "--public-key", rekorKey, "--artifact", tarFilePath)
-			Expect(err).ToNot(HaveOccurred())
-
-			createdMessage := regexp.MustCompile(`Created entry at index (\d+)`)
-			matches := createdMessage.FindStringSubmatch(string(output))
-			Expect(matches).To(HaveLen(2))
-
-			entryIndex, err = strconv.Atoi(matches[1])
+		It("should upload artifact", func(ctx SpecContext) {
+			result, err := rekorCli.Upload(ctx, artifactOptions())
 			Expect(err).ToNot(HaveOccurred())
+			entryIndex = result.Index
 		})
 	})
 
 	Describe("Verify upload", func() {
-		It("should verify uploaded artifact", func() {
-			parseOutput := func(output string) string {
-				hashRe := regexp.MustCompile(`Entry Hash:\s+([a-f0-9]+)`)
-				hashMatches := hashRe.FindStringSubmatch(output)
-				Expect(hashMatches).To(HaveLen(2))
-
-				return hashMatches[1]
-			}
-
-			rekorServerURL := api.GetValueFor(api.RekorURL)
-			rekorKey := "ec_public.pem"
-			output, err := rekorCli.CommandOutput(testsupport.TestContext, "verify", "--rekor_server", rekorServerURL, "--signature", signatureFilePath, "--pki-format=x509", "--public-key", rekorKey, "--artifact", tarFilePath)
+		It("should verify uploaded artifact", func(ctx SpecContext) {
+			result, err := rekorCli.Verify(ctx, artifactOptions(), nil)
 			Expect(err).ToNot(HaveOccurred())
-			outputString := string(output)
-			rekorHash = parseOutput(outputString)
+			Expect(result.EntryUUID).To(MatchRegexp(`^[a-f0-9]+$`))
+			Expect(result.Index).To(Equal(entryIndex))
+			rekorHash = result.EntryUUID
 		})
 	})
 
 	Describe("Verify entry consistency", func() {
-		It("should use the same entry across tests", func() {
-			rekorServerURL := api.GetValueFor(api.RekorURL)
-			output, err := rekorCli.CommandOutput(testsupport.TestContext, "get", "--rekor_server", rekorServerURL, "--uuid", rekorHash)
+		It("should use the same entry across tests", func(ctx SpecContext) {
+			byUUID, err := rekorCli.Get(ctx, clients.RekorEntryRef{UUID: rekorHash})
 			Expect(err).ToNot(HaveOccurred())
-			Expect(strings.Contains(string(output), strconv.Itoa(entryIndex))).To(BeTrue())
-			Expect(strings.Contains(string(output), rekorHash)).To(BeTrue())
+			Expect(byUUID.LogIndex).To(Equal(entryIndex))
+			Expect(byUUID.UUID).To(ContainSubstring(rekorHash))
 
-			output, err = rekorCli.CommandOutput(testsupport.TestContext, "get", "--rekor_server", rekorServerURL, "--log-index", strconv.Itoa(entryIndex))
+			byIndex, err := rekorCli.Get(ctx, clients.RekorEntryRef{LogIndex: entryIndex})
 			Expect(err).ToNot(HaveOccurred())
-			Expect(strings.Contains(string(output), strconv.Itoa(entryIndex))).To(BeTrue())
-			Expect(strings.Contains(string(output), rekorHash)).To(BeTrue())
+			Expect(byIndex.LogIndex).To(Equal(entryIndex))
+			Expect(byIndex.UUID).To(Equal(byUUID.UUID))
 		})
 	})
 
 	Describe("Get with UUID", func() {
-		It("should get data from rekor server", func() {
-			rekorServerURL := api.GetValueFor(api.RekorURL)
-			Expect(rekorCli.Command(testsupport.TestContext, "get", "--rekor_server", rekorServerURL, "--uuid", rekorHash).Run()).To(Succeed()) // UUID = Entry Hash here
+		It("should get data from rekor server", func(ctx SpecContext) {
+			_, err := rekorCli.Get(ctx, clients.RekorEntryRef{UUID: rekorHash}) // UUID = Entry Hash here
 			Expect(err).ToNot(HaveOccurred())
 		})
 	})
 
 	Describe("Get with logindex", func() {
-		It("should get data from rekor server", func() {
-			rekorServerURL := api.GetValueFor(api.RekorURL)
-			entryIndexStr := strconv.Itoa(entryIndex)
-
-			// extrract of hash value for searching with --sha
-			output, err := rekorCli.CommandOutput(testsupport.TestContext, "get", "--rekor_server", rekorServerURL, "--log-index", entryIndexStr)
-			Expect(err).ToNot(HaveOccurred())
-
-			// Look for JSON start
-			startIndex := strings.Index(string(output), "{")
-			Expect(startIndex).NotTo(Equal(-1), "JSON start - '{' not found")
-
-			jsonStr := string(output[startIndex:])
-
-			var rekorGetOutput testsupport.RekorCLIGetOutput
-			err = json.Unmarshal([]byte(jsonStr), &rekorGetOutput)
+		It("should get data from rekor server", func(ctx SpecContext) {
+			// extract of hash value for searching with --sha
+			entry, err := rekorCli.Get(ctx, clients.RekorEntryRef{LogIndex: entryIndex})
 			Expect(err).ToNot(HaveOccurred())
+			Expect(entry.Body.Type()).To(Equal(clients.RekorTypeRekord))
 
 			// algorithm:hashValue
-			hashWithAlg = rekorGetOutput.RekordObj.Data.Hash.Algorithm + ":" + rekorGetOutput.RekordObj.Data.Hash.Value
+			hash := entry.Body.Hash()
+			hashWithAlg = hash.Algorithm + ":" + hash.Value
 		})
 	})
 
 	Describe("Get loginfo", func() {
-		It("should get loginfo from rekor server", func() {
-			rekorServerURL := api.GetValueFor(api.RekorURL)
-			output, err := rekorCli.CommandOutput(testsupport.TestContext, "loginfo", "--rekor_server", rekorServerURL)
+		It("should get loginfo from rekor server", func(ctx SpecContext) {
+			info, err := rekorCli.LogInfo(ctx)
 			Expect(err).ToNot(HaveOccurred())
-			fmt.Println(output)
+			Expect(info.ActiveTreeSize).To(BeNumerically(">", entryIndex))
+			Expect(info.RootHash).ToNot(BeEmpty())
 		})
 	})
 
 	Describe("Search entries", func() {
-		It("should search entries with artifact ", func() {
-			rekorServerURL := api.GetValueFor(api.RekorURL)
-			output, err := rekorCli.CommandOutput(testsupport.TestContext, "search", "--rekor_server", rekorServerURL, "--artifact", tarFilePath)
+		It("should search entries with artifact ", func(ctx SpecContext) {
+			uuids, err := rekorCli.Search(ctx, clients.RekorSearchOptions{Artifact: tarFilePath})
 			Expect(err).ToNot(HaveOccurred())
-			fmt.Println(output)
+			Expect(uuids).ToNot(BeEmpty())
 		})
 	})
 
 	Describe("Search entries", func() {
-		It("should search entries with public key", func() {
-			rekorServerURL := api.GetValueFor(api.RekorURL)
-			rekorKey := "ec_public.pem"
-			output, err := rekorCli.CommandOutput(testsupport.TestContext, "search", "--rekor_server", rekorServerURL, "--public-key", rekorKey, "--pki-format=x509")
+		It("should search entries with public key", func(ctx SpecContext) {
+			uuids, err := rekorCli.Search(ctx, clients.RekorSearchOptions{PublicKey: rekorKey, PKIFormat: "x509"})
 			Expect(err).ToNot(HaveOccurred())
-			fmt.Println(output)
+			Expect(uuids).ToNot(BeEmpty())
 		})
 
 	})
 
 	Describe("Search entries", func() {
-		It("should search entries with hash", func() {
-			rekorServerURL := api.GetValueFor(api.RekorURL)
-			output, err := rekorCli.CommandOutput(testsupport.TestContext, "search", "--rekor_server", rekorServerURL, "--sha", hashWithAlg)
+		It("should search entries with hash", func(ctx SpecContext) {
+			uuids, err := rekorCli.Search(ctx, clients.RekorSearchOptions{SHA: hashWithAlg})
 			Expect(err).ToNot(HaveOccurred())
-			fmt.Println(output)
+			Expect(uuids).ToNot(BeEmpty())
 		})
 	})
 
 })
 
-var _ = AfterSuite(func() {
-	// Cleanup shared resources after all tests have run.
-	Expect(os.RemoveAll(tempDir)).To(Succeed())
-})
+func artifactOptions() clients.RekorArtifactOptions {
+	return clients.RekorArtifactOptions{
+		Artifact:  tarFilePath,
+		Signature: signatureFilePath,
+		PublicKey: rekorKey,
+		PKIFormat: "x509",
+	}
+}
@@ -1,24 +1,22 @@
 package rekorsearchui
 
 import (
-	"bytes"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/config"
 	"github.com/google/uuid"
 	"github.com/mxschmitt/playwright-go"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/securesign/sigstore-e2e/pkg/api"
 	"github.com/securesign/sigstore-e2e/pkg/clients"
+	"github.com/securesign/sigstore-e2e/pkg/preflight"
 	"github.com/securesign/sigstore-e2e/test/testsupport"
 	"github.com/sirupsen/logrus"
 )
@@ -36,13 +34,13 @@ const (
 func getBrowsersToTest() []BrowserType {
 	browsersToTest := []BrowserType{Chrome} // Default to Chrome
 
-	if api.GetValueFor(api.TestFirefox) == "true" {
+	if api.GetBool(api.TestFirefox) {
 		browsersToTest = append(browsersToTest, Firefox)
 	}
-	if api.GetValueFor(api.TestSafari) == "true" {
+	if api.GetBool(api.TestSafari) {
 		browsersToTest = append(browsersToTest, Safari)
 	}
-	if api.GetValueFor(api.TestEdge) == "true" {
+	if api.GetBool(api.TestEdge) {
 		browsersToTest = append(browsersToTest, Edge)
 	}
 
@@ -494,7 +492,6 @@ var _ = Describe("Test the Rekor Search UI", Ordered, func() {
 		tempDir     string
 		dirFilePath string
 		tarFilePath string
-		config      *config.Config
 		dir         string
 		repo        *git.Repository
 		testData    TestData
@@ -502,11 +499,12 @@ var _ = Describe("Test the Rekor Search UI", Ordered, func() {
 
 	appURL := api.GetValueFor(api.RekorUIURL)
 
-	BeforeAll(func() {
+	BeforeAll(func(ctx SpecContext) {
 		err := testsupport.CheckMandatoryAPIConfigValues(api.OidcRealm, api.RekorUIURL, api.OidcIssuerURL, api.FulcioURL, api.RekorURL)
 		if err != nil {
 			Fail(err.Error())
 		}
+		testsupport.RequireServices(preflight.RekorUI, preflight.Fulcio, preflight.Rekor, preflight.OIDC)
 
 		rekorCli = clients.NewRekorCli()
 		cosign = clients.NewCosign()
@@ -544,23 +542,15 @@ var _ = Describe("Test the Rekor Search UI", Ordered, func() {
 		Expect(err).ToNot(HaveOccurred())
 		repo, err = git.PlainInit(dir, false)
 		Expect(err).ToNot(HaveOccurred())
-		config, err = repo.Config()
-		Expect(err).ToNot(HaveOccurred())
-
-		// Configure git user
-		config.User.Name = "John Doe"
-		config.User.Email = fmt.Sprintf("%s@%s", api.GetValueFor(api.OidcUser), api.GetValueFor(api.OidcUserDomain))
 
-		// Configure gitsign
-		config.Raw.AddOption("commit", "", "gpgsign", "true")
-		config.Raw.AddOption("tag", "", "gpgsign", "true")
-		config.Raw.AddOption("gpg", "x509", "program", "gitsign")
-		config.Raw.AddOption("gpg", "", "format", "x509")
-		config.Raw.AddOption("gitsign", "", "fulcio", api.GetValueFor(api.FulcioURL))
-		config.Raw.AddOption("gitsign", "", "rekor", api.GetValueFor(api.RekorURL))
-		config.Raw.AddOption("gitsign", "", "issuer", api.GetValueFor(api.OidcIssuerURL))
-
-		Expect(repo.SetConfig(config)).To(Succeed())
+		// Configure gitsign with OIDC user
+		Expect(gitsign.SetupRepository(ctx, dir, clients.GitsignRepoConfig{
+			UserName:  "John Doe",
+			UserEmail: fmt.Sprintf("%s@%s", api.GetValueFor(api.OidcUser), api.GetValueFor(api.OidcUserDomain)),
+			Fulcio:    api.GetValueFor(api.FulcioURL),
+			Rekor:     api.GetValueFor(api.RekorURL),
+			Issuer:    api.GetValueFor(api.OidcIssuerURL),
+		})).To(Succeed())
 
 		// Create and commit file
 		testFileName := dir + "/testFile.txt"
@@ -571,11 +561,12 @@ var _ = Describe("Test the Rekor Search UI", Ordered, func() {
 		Expect(err).ToNot(HaveOccurred())
 
 		// Sign commit with gitsign
-		token, err := testsupport.GetOIDCToken(testsupport.TestContext)
+		token, err := testsupport.GetOIDCToken(ctx)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(token).To(Not(BeEmpty()))
 
-		Expect(gitsign.GitWithGitSign(testsupport.TestContext, dir, token, "commit", "-S", "-m", "CI commit "+time.Now().String())).To(Succeed())
+		_, err = gitsign.Commit(ctx, dir, token, "CI commit "+time.Now().String())
+		Expect(err).ToNot(HaveOccurred())
 
 		// Get commit SHA
 		head, err := repo.Head()
@@ -593,46 +584,26 @@ var _ = Describe("Test the Rekor Search UI", Ordered, func() {
 
 		Expect(exec.Command("tar", "-czvf", tarFilePath, dirFilePath).Run()).To(Succeed())
 		tufURL := api.GetValueFor(api.TufURL)
-		Eventually(func() error {
-			return gitsign.Command(testsupport.TestContext, "initialize",
-				"--mirror", tufURL,
-				"--root", tufURL+"/root.json").Run()
-		}).WithTimeout(testsupport.CommandRetryTimeout).WithPolling(testsupport.CommandRetryInterval).Should(Succeed())
-
-		cmd := gitsign.Command(testsupport.TestContext, "verify",
-			"--certificate-identity", fmt.Sprintf("%s@%s", api.GetValueFor(api.OidcUser), api.GetValueFor(api.OidcUserDomain)),
-			"--certificate-oidc-issuer", api.GetValueFor(api.OidcIssuerURL),
-			"HEAD")
-
-		cmd.Dir = dir
-		cmd.Env = os.Environ()
-
-		var output bytes.Buffer
-		cmd.Stdout = &output
-		Expect(cmd.Run()).To(Succeed())
-		logrus.WithField("app", "gitsign").Info(output.String())
-
-		// Extract log index
-		re := regexp.MustCompile(`tlog index: (\d+)`)
-		match := re.FindStringSubmatch(output.String())
-		testData.LogIndex = match[1]
+		Expect(gitsign.Initialize(ctx, clients.InitializeOptions{
+			Mirror: tufURL,
+			Root:   tufURL + "/root.json",
+		})).To(Succeed())
+
+		result, err := gitsign.Verify(ctx, dir, "HEAD", clients.GitsignVerifyOptions{
+			CertificateIdentity:   fmt.Sprintf("%s@%s", api.GetValueFor(api.OidcUser), api.GetValueFor(api.OidcUserDomain)),
+			CertificateOIDCIssuer: api.GetValueFor(api.OidcIssuerURL),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.LogIndex).To(BeNumerically(">=", 0))
+		testData.LogIndex = strconv.FormatInt(result.LogIndex, 10)
 
 		// Get the entry data from Rekor
-		rekorURL := api.GetValueFor(api.RekorURL)
-		full, _ := rekorCli.CommandOutput(testsupport.TestContext, "get", "--rekor_server", rekorURL, "--log-index", testData.LogIndex)
-		out := string(full)
-
-		// Extract UUID
-		uuidRe := regexp.MustCompile(`(?m)^UUID:\s+([0-9a-f]+)`)
-		m := uuidRe.FindStringSubmatch(out)
-		Expect(m).To(HaveLen(2))
-		testData.EntryUUID = m[1]
-
-		// Extract hash
-		hashRe := regexp.MustCompile(`"value":\s+"([0-9a-f]{64})"`)
-		m = hashRe.FindStringSubmatch(out)
-		Expect(m).To(HaveLen(2))
-		testData.Hash = m[1]
+		entry, err := rekorCli.Get(ctx, clients.RekorEntryRef{LogIndex: result.LogIndex})
+		Expect(err).ToNot(HaveOccurred())
+		testData.EntryUUID = entry.UUID
+		testData.Hash = entry.Body.Hash().Value
+		Expect(testData.EntryUUID).ToNot(BeEmpty())
+		Expect(testData.Hash).To(MatchRegexp(`^[0-9a-f]{64}$`))
 
 		logrus.Infof("Email = %s", testData.Email)
 		logrus.Infof("Hash = %s", testData.Hash)
@@ -654,7 +625,7 @@ var _ = Describe("Test the Rekor Search UI", Ordered, func() {
 				BeforeEach(func() {
 					logrus.Infof("\n=== Starting %s browser test suite ===", bt)
 
-					headless := api.GetValueFor(api.HeadlessUI) == "true"
+					headless := api.GetBool(api.HeadlessUI)
 					var err error
 					browserTest, err = newBrowserTest(bt, headless, appURL, &testData)
 					Expect(err).ToNot(HaveOccurred())
@@ -0,0 +1,113 @@
+package replay
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/securesign/sigstore-e2e/pkg/clients"
+	"github.com/securesign/sigstore-e2e/test/testsupport"
+	"github.com/sirupsen/logrus"
+)
+
+// The key pair was generated with 'cosign generate-key-pair' and an empty password.
+const (
+	cosignPrivateKey = "testdata/cosign.key"
+	cosignPublicKey  = "testdata/cosign.pub"
+)
+
+var _ = Describe("Replay cosign", Ordered, func() {
+	var (
+		cosign       *clients.Cosign
+		dir          string
+		blob         string
+		keyIndex     int64
+		keylessIndex int64
+	)
+
+	BeforeAll(func() {
+		cosign = clients.NewCosign()
+		Expect(testsupport.InstallPrerequisites(cosign)).To(Succeed())
+		DeferCleanup(func() {
+			if err := testsupport.DestroyPrerequisites(); err != nil {
+				logrus.Warn("Env was not cleaned-up" + err.Error())
+			}
+		})
+		cosign.Env().Set("COSIGN_PASSWORD", "")
+
+		dir = GinkgoT().TempDir()
+		blob = filepath.Join(dir, "artifact.txt")
+		Expect(os.WriteFile(blob, []byte(artifact), 0600)).To(Succeed())
+	})
+
+	It("should initialize the TUF root", func(ctx SpecContext) {
+		tufURL := api.GetValueFor(api.TufURL)
+		Expect(cosign.Initialize(ctx, clients.InitializeOptions{
+			Mirror: tufURL,
+			Root:   tufURL + "/root.json",
+		})).To(Succeed())
+	})
+
+	It("should sign the blob with a key", func(ctx SpecContext) {
+		result, err := cosign.SignBlob(ctx, blob, clients.SignBlobOptions{
+			Key:        cosignPrivateKey,
+			BundlePath: filepath.Join(dir, "key.bundle.json"),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Signature).ToNot(BeEmpty())
+		Expect(result.LogIndex).To(BeNumerically(">=", 0))
+		Expect(result.Bundle.LogIndex()).To(Equal(result.LogIndex))
+		keyIndex = result.LogIndex
+	})
+
+	It("should verify the blob with the public key", func(ctx SpecContext) {
+		result, err := cosign.VerifyBlob(ctx, blob, clients.VerifyBlobOptions{
+			VerifyOptions: clients.VerifyOptions{Key: cosignPublicKey},
+			BundlePath:    filepath.Join(dir, "key.bundle.json"),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.LogIndex).To(Equal(keyIndex))
+		Expect(result.CertificateIdentity).To(BeEmpty())
+	})
+
+	It("should sign the blob with an identity token", func(ctx SpecContext) {
+		token, err := testsupport.GetOIDCToken(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		result, err := cosign.SignBlob(ctx, blob, clients.SignBlobOptions{
+			IdentityToken: token,
+			BundlePath:    filepath.Join(dir, "keyless.bundle.json"),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.LogIndex).To(BeNumerically(">", keyIndex))
+		Expect(result.Bundle.LogIndex()).To(Equal(result.LogIndex))
+		keylessIndex = result.LogIndex
+	})
+
+	It("should verify the blob with the identity of the signer", func(ctx SpecContext) {
+		identity := testsupport.GetIdentity(api.DefaultIdentity)
+		result, err := cosign.VerifyBlob(ctx, blob, clients.VerifyBlobOptions{
+			VerifyOptions: clients.VerifyOptions{
+				CertificateIdentity:   identity.Email(),
+				CertificateOIDCIssuer: identity.IssuerURL,
+			},
+			BundlePath: filepath.Join(dir, "keyless.bundle.json"),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.LogIndex).To(Equal(keylessIndex))
+		Expect(result.CertificateIdentity).To(Equal(identity.Email()))
+	})
+
+	It("should reject a modified blob", func(ctx SpecContext) {
+		modified := filepath.Join(dir, "modified.txt")
+		Expect(os.WriteFile(modified, []byte("modified "+artifact), 0600)).To(Succeed())
+		_, err := cosign.VerifyBlob(ctx, modified, clients.VerifyBlobOptions{
+			VerifyOptions: clients.VerifyOptions{Key: cosignPublicKey},
+			BundlePath:    filepath.Join(dir, "key.bundle.json"),
+		})
+		var cmdErr *clients.CommandError
+		Expect(errors.As(err, &cmdErr)).To(BeTrue(), "expected a failed cosign invocation, got %v", err)
+	})
+})
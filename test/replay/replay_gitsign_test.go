@@ -0,0 +1,100 @@
+package replay
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/securesign/sigstore-e2e/pkg/clients"
+	"github.com/securesign/sigstore-e2e/test/testsupport"
+	"github.com/sirupsen/logrus"
+)
+
+// gitsign is replayed behind the real git, which passes it the commit or tag
+// to sign and stores the recorded signature. The recorded gitsign has no
+// verify-tag command, so only commits are verified.
+var _ = Describe("Replay gitsign", Ordered, func() {
+	var (
+		gitsign     *clients.Gitsign
+		dir         string
+		identity    testsupport.Identity
+		commitIndex int64
+	)
+
+	BeforeAll(func(ctx SpecContext) {
+		gitsign = clients.NewGitsign()
+		Expect(testsupport.InstallPrerequisites(gitsign)).To(Succeed())
+		DeferCleanup(func() {
+			if err := testsupport.DestroyPrerequisites(); err != nil {
+				logrus.Warn("Env was not cleaned-up" + err.Error())
+			}
+		})
+
+		identity = testsupport.GetIdentity(api.DefaultIdentity)
+		dir = GinkgoT().TempDir()
+		Expect(gitsign.GitWithGitSign(ctx, dir, "", "init", "--quiet")).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "artifact.txt"), []byte(artifact), 0600)).To(Succeed())
+	})
+
+	It("should initialize the TUF root", func(ctx SpecContext) {
+		tufURL := api.GetValueFor(api.TufURL)
+		Expect(gitsign.Initialize(ctx, clients.InitializeOptions{
+			Mirror: tufURL,
+			Root:   tufURL + "/root.json",
+		})).To(Succeed())
+	})
+
+	It("should configure the repository", func(ctx SpecContext) {
+		Expect(gitsign.SetupRepository(ctx, dir, clients.GitsignRepoConfig{
+			UserName:  "John Doe",
+			UserEmail: identity.Email(),
+			Fulcio:    api.GetValueFor(api.FulcioURL),
+			Rekor:     api.GetValueFor(api.RekorURL),
+			Issuer:    identity.IssuerURL,
+			ClientID:  identity.ClientID,
+		})).To(Succeed())
+	})
+
+	It("should sign a commit", func(ctx SpecContext) {
+		Expect(gitsign.GitWithGitSign(ctx, dir, "", "add", "artifact.txt")).To(Succeed())
+		token, err := testsupport.GetOIDCToken(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		result, err := gitsign.Commit(ctx, dir, token, "Replayed commit")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.LogIndex).To(BeNumerically(">=", 0))
+		commitIndex = result.LogIndex
+	})
+
+	It("should verify the commit", func(ctx SpecContext) {
+		result, err := gitsign.Verify(ctx, dir, "HEAD", clients.GitsignVerifyOptions{
+			CertificateIdentity:   identity.Email(),
+			CertificateOIDCIssuer: identity.IssuerURL,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.GitSignatureValid).To(BeTrue())
+		Expect(result.RekorEntryValid).To(BeTrue())
+		Expect(result.CertificateClaimsValid).To(BeTrue())
+		Expect(result.CertificateIdentity).To(Equal(identity.Email()))
+		Expect(result.LogIndex).To(Equal(commitIndex))
+	})
+
+	It("should sign a tag", func(ctx SpecContext) {
+		token, err := testsupport.GetOIDCToken(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		result, err := gitsign.Tag(ctx, dir, token, "v1.0.0", "Replayed tag")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.LogIndex).To(BeNumerically(">", commitIndex))
+	})
+
+	It("should reject the commit for another identity", func(ctx SpecContext) {
+		_, err := gitsign.Verify(ctx, dir, "HEAD", clients.GitsignVerifyOptions{
+			CertificateIdentity:   "someone-else@" + identity.Domain,
+			CertificateOIDCIssuer: identity.IssuerURL,
+		})
+		var cmdErr *clients.CommandError
+		Expect(errors.As(err, &cmdErr)).To(BeTrue(), "expected a failed gitsign invocation, got %v", err)
+	})
+})
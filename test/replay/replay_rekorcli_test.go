@@ -0,0 +1,105 @@
+package replay
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/securesign/sigstore-e2e/pkg/clients"
+	"github.com/securesign/sigstore-e2e/test/testsupport"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	publicKey = "testdata/ec_public.pem"
+	artifact  = "replayed artifact\n"
+)
+
+var _ = Describe("Replay rekor-cli", Ordered, func() {
+	var (
+		rekorCli *clients.RekorCli
+		options  clients.RekorArtifactOptions
+		index    int64
+	)
+
+	BeforeAll(func() {
+		rekorCli = clients.NewRekorCli()
+		Expect(testsupport.InstallPrerequisites(rekorCli)).To(Succeed())
+		DeferCleanup(func() {
+			if err := testsupport.DestroyPrerequisites(); err != nil {
+				logrus.Warn("Env was not cleaned-up" + err.Error())
+			}
+		})
+
+		dir := GinkgoT().TempDir()
+		options = clients.RekorArtifactOptions{
+			Artifact:  filepath.Join(dir, "artifact.txt"),
+			Signature: filepath.Join(dir, "artifact.sig"),
+			PublicKey: publicKey,
+			PKIFormat: "x509",
+		}
+		Expect(os.WriteFile(options.Artifact, []byte(artifact), 0600)).To(Succeed())
+		signature, err := sign("testdata/ec_private.pem", []byte(artifact))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.WriteFile(options.Signature, signature, 0600)).To(Succeed())
+	})
+
+	It("should upload the artifact", func(ctx SpecContext) {
+		result, err := rekorCli.Upload(ctx, options)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Index).To(BeNumerically(">=", 0))
+		index = result.Index
+	})
+
+	It("should verify the uploaded artifact", func(ctx SpecContext) {
+		result, err := rekorCli.Verify(ctx, options, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Index).To(Equal(index))
+		Expect(result.RootHash).ToNot(BeEmpty())
+	})
+
+	It("should get the entry of the artifact", func(ctx SpecContext) {
+		entry, err := rekorCli.Get(ctx, clients.RekorEntryRef{LogIndex: index})
+		Expect(err).ToNot(HaveOccurred())
+		// rekor-cli uploads an artifact with its signature as a rekord entry
+		Expect(entry.Body.Type()).To(Equal(clients.RekorTypeRekord))
+		sum := sha256.Sum256([]byte(artifact))
+		Expect(entry.Body.Hash().Value).To(Equal(hex.EncodeToString(sum[:])))
+	})
+
+	It("should report a missing entry", func(ctx SpecContext) {
+		_, err := rekorCli.Get(ctx, clients.RekorEntryRef{LogIndex: 99999999})
+		var rekorErr *clients.RekorError
+		Expect(errors.As(err, &rekorErr)).To(BeTrue(), "expected a Rekor API error, got %v", err)
+		Expect(rekorErr.StatusCode).To(Equal(404))
+	})
+})
+
+// sign returns the ASN.1 ECDSA signature of the SHA-256 digest of data with
+// the PEM encoded EC key at keyPath.
+func sign(keyPath string, data []byte) ([]byte, error) {
+	content, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	for block, rest := pem.Decode(content); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "EC PRIVATE KEY" {
+			continue
+		}
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		digest := sha256.Sum256(data)
+		return ecdsa.SignASN1(rand.Reader, key, digest[:])
+	}
+	return nil, errors.New(keyPath + ": no EC private key")
+}
@@ -0,0 +1,39 @@
+package replay
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/securesign/sigstore-e2e/test/testsupport"
+)
+
+// replayedServiceURL stands in for the services when replaying. The
+// recordings are made against a deployment on the local machine (e.g.
+// LOCAL_STACK=true), whose URLs are normalized to the same "<host>".
+const replayedServiceURL = "http://localhost:8080"
+
+// TestReplayE2E runs offline against the recordings in testdata/recordings.
+// Identity tokens come from a mock OIDC provider, whose default identity is
+// the one the recordings were made with. With CLI_STRATEGY=record, the
+// recordings are appended to from the configured deployment instead.
+func TestReplayE2E(t *testing.T) {
+	t.Setenv(api.ReplayDir, "testdata/recordings")
+	t.Setenv(api.Discovery, "false")
+	t.Setenv(api.Preflight, "false")
+	if os.Getenv(api.CliStrategy) != "record" {
+		t.Setenv(api.CliStrategy, "replay")
+		for _, key := range []string{api.RekorURL, api.FulcioURL, api.TufURL} {
+			t.Setenv(key, replayedServiceURL)
+		}
+		mock, err := testsupport.StartMockOIDC()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(mock.Close)
+	}
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Replay recorded cosign, gitsign and rekor-cli invocations without network access")
+}
@@ -0,0 +1,90 @@
+package testsupport
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/ginkgo/v2/types"
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/securesign/sigstore-e2e/pkg/clients"
+	"github.com/sirupsen/logrus"
+)
+
+// buildInspectable is a prerequisite whose installed binary can be inspected after setup.
+type buildInspectable interface {
+	BuildInfo() (*clients.BuildInfo, error)
+}
+
+// buildInfoReportEntry prefixes the names of the report entries with the build
+// info of an installed tool.
+const buildInfoReportEntry = "build info of "
+
+// inspectBuild attaches the build info of p to the report of the running node
+// and checks it against BUILD_EXPECTATIONS. Binaries that are not built with
+// Go are only logged.
+func inspectBuild(p api.TestPrerequisite, b buildInspectable) error {
+	name := api.PrerequisiteName(p)
+	info, err := b.BuildInfo()
+	if errors.Is(err, clients.ErrNotGoBinary) {
+		logrus.WithField("app", name).Infof("No Go build info: %v", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	logrus.WithField("app", name).Infof("Built with %s (CGO_ENABLED=%t, GOEXPERIMENT=%q), crypto backend %s, revision %s",
+		info.GoVersion, info.CGOEnabled, info.Experiments, info.CryptoBackend, info.VCSRevision)
+	if ginkgo.CurrentSpecReport().LeafNodeType != types.NodeTypeInvalid {
+		ginkgo.AddReportEntry(buildInfoReportEntry+name, info, ginkgo.ReportEntryVisibilityNever)
+	}
+
+	expectations, err := clients.ParseBuildExpectations(api.GetValueFor(api.BuildExpectations))
+	if err != nil {
+		return err
+	}
+	if e, ok := expectations[name]; ok {
+		return e.Check(info)
+	}
+	return nil
+}
+
+// Logs the build inventory of the run, collected from the report entries of
+// all parallel processes.
+var _ = ginkgo.ReportAfterSuite("CLI build inventory", func(report ginkgo.Report) {
+	inventory := map[string]*clients.BuildInfo{}
+	for _, spec := range report.SpecReports {
+		for _, entry := range spec.ReportEntries {
+			name, ok := strings.CutPrefix(entry.Name, buildInfoReportEntry)
+			if !ok {
+				continue
+			}
+			info, err := buildInfoOfEntry(entry)
+			if err != nil {
+				logrus.Warnf("Cannot read build info of %s: %v", name, err)
+				continue
+			}
+			inventory[name] = info
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(inventory)) {
+		info := inventory[name]
+		logrus.WithField("app", name).Infof("Build inventory: %s %s, %s, crypto backend %s, %d dependencies",
+			info.Main.Path, info.Main.Version, info.GoVersion, info.CryptoBackend, len(info.Deps))
+	}
+})
+
+// buildInfoOfEntry returns the value of a build info report entry. Entries of
+// other processes only carry their JSON encoding.
+func buildInfoOfEntry(entry types.ReportEntry) (*clients.BuildInfo, error) {
+	if info, ok := entry.GetRawValue().(*clients.BuildInfo); ok {
+		return info, nil
+	}
+	info := &clients.BuildInfo{}
+	err := json.Unmarshal([]byte(entry.Value.AsJSON), info)
+	return info, err
+}
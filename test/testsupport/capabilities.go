@@ -0,0 +1,48 @@
+package testsupport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/securesign/sigstore-e2e/pkg/clients"
+)
+
+// CapabilityProber is a CLI client whose binary can be probed for capabilities.
+type CapabilityProber interface {
+	GetName() string
+	Capabilities(ctx context.Context) *clients.Capabilities
+}
+
+// RequireCapabilities skips the running spec unless the binary of client
+// provides all capabilities. It fails the spec when the binary could not be
+// probed, so a broken binary is not mistaken for a missing feature.
+func RequireCapabilities(client CapabilityProber, capabilities ...clients.Capability) {
+	caps := client.Capabilities(TestContext)
+	for _, capability := range capabilities {
+		err := caps.Check(capability)
+		var missing *clients.MissingCapabilityError
+		switch {
+		case errors.As(err, &missing):
+			ginkgo.Skip(fmt.Sprintf("%s %s: %v", client.GetName(), caps.Version, err))
+		case err != nil:
+			ginkgo.Fail(fmt.Sprintf("%s: %v", client.GetName(), err))
+		}
+	}
+}
+
+// SkipWithCapability skips the running spec when the binary of client provides
+// capability, for specs covering behaviour it replaces. Like
+// RequireCapabilities, it fails the spec when the binary could not be probed.
+func SkipWithCapability(client CapabilityProber, capability clients.Capability) {
+	caps := client.Capabilities(TestContext)
+	err := caps.Check(capability)
+	var missing *clients.MissingCapabilityError
+	switch {
+	case err == nil:
+		ginkgo.Skip(fmt.Sprintf("%s %s provides %s", client.GetName(), caps.Version, capability))
+	case !errors.As(err, &missing):
+		ginkgo.Fail(fmt.Sprintf("%s: %v", client.GetName(), err))
+	}
+}
@@ -0,0 +1,107 @@
+package testsupport
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/securesign/sigstore-e2e/pkg/preflight"
+	"github.com/securesign/sigstore-e2e/pkg/redact"
+	"github.com/sirupsen/logrus"
+)
+
+// EffectiveConfig returns the non-empty configuration values as KEY=value
+// lines sorted by key, with secrets masked. Secret references are shown as
+// configured.
+func EffectiveConfig() []string {
+	var lines []string
+	for key, value := range api.Settings() {
+		if value == "" {
+			continue
+		}
+		if redact.IsSecretKey(key) && !api.IsSecretReference(value) {
+			value = redact.Mask
+		}
+		lines = append(lines, key+"="+redact.String(value))
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// suiteSetup is the outcome of the suite setup on the first Ginkgo process,
+// shared with the other parallel processes.
+type suiteSetup struct {
+	// Overrides are the configuration values changed by the local stack and
+	// endpoint discovery.
+	Overrides     map[string]string
+	TUFDiscovered []string
+	Preflight     map[string]preflight.Result
+}
+
+// The local stack, endpoint discovery and the preflight check run once, on the
+// first process. The other processes take over the resulting configuration,
+// so they use the same services and skip the same specs.
+var _ = ginkgo.SynchronizedBeforeSuite(func(ctx ginkgo.SpecContext) []byte {
+	before := api.Settings()
+	if err := startLocalStack(ctx); err != nil {
+		ginkgo.Fail(err.Error())
+	}
+	discoverEndpoints(ctx)
+	runPreflight(ctx)
+	reportUsage()
+
+	setup := suiteSetup{Overrides: map[string]string{}, Preflight: map[string]preflight.Result{}}
+	for key, value := range api.Settings() {
+		if before[key] != value {
+			setup.Overrides[key] = value
+		}
+	}
+	tufDiscoveredMu.Lock()
+	setup.TUFDiscovered = slices.Clone(tufDiscovered)
+	tufDiscoveredMu.Unlock()
+	preflightMu.Lock()
+	maps.Copy(setup.Preflight, preflightResults)
+	preflightMu.Unlock()
+	data, err := json.Marshal(setup)
+	if err != nil {
+		ginkgo.Fail(fmt.Sprintf("cannot share the suite setup: %v", err))
+	}
+	return data
+}, func(data []byte) {
+	armRunDeadline()
+	if ginkgo.GinkgoParallelProcess() == 1 {
+		return
+	}
+	var setup suiteSetup
+	if err := json.Unmarshal(data, &setup); err != nil {
+		ginkgo.Fail(fmt.Sprintf("cannot read the suite setup: %v", err))
+	}
+	for key, value := range setup.Overrides {
+		api.Values.Set(key, value)
+	}
+	tufDiscoveredMu.Lock()
+	tufDiscovered = setup.TUFDiscovered
+	tufDiscoveredMu.Unlock()
+	preflightMu.Lock()
+	maps.Copy(preflightResults, setup.Preflight)
+	preflightMu.Unlock()
+})
+
+// The local stack is torn down on the first process once all processes are done.
+var _ = ginkgo.SynchronizedAfterSuite(reportUsage, stopLocalStack)
+
+var _ = ginkgo.ReportBeforeSuite(func(_ ginkgo.Report) {
+	source := "environment"
+	if profile := api.GetValueFor(api.Profile); profile != "" {
+		source = "profile " + profile + " and environment"
+	}
+	logrus.Infof("Effective configuration (%s):\n  %s", source, strings.Join(EffectiveConfig(), "\n  "))
+	if err := api.Validate(); err != nil {
+		logrus.Warnf("Invalid configuration:\n  %s", strings.ReplaceAll(err.Error(), "\n", "\n  "))
+	}
+})
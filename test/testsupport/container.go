@@ -0,0 +1,181 @@
+package testsupport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/sirupsen/logrus"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// Container is a prerequisite running an image with testcontainers (Docker,
+// or Podman through DOCKER_HOST). Images found locally are used as they are,
+// missing ones are pulled.
+type Container struct {
+	// Name is the alias of the container in Network.
+	Name  string
+	Image string
+	// Network is a Docker network, or "host" for the network of the host.
+	Network string
+	Env     map[string]string
+	Cmd     []string
+	// Mounts are host directories mounted read-only at the same path.
+	Mounts []string
+	// Ports are container ports, e.g. "3000/tcp", published on the Docker host.
+	Ports    []string
+	Requires []api.TestPrerequisite
+	// Configure is called at setup, before the container is created, e.g. to
+	// derive Cmd from prerequisites that are running by then.
+	Configure func(ctx context.Context, c *Container) error
+	// Probe reports whether the started container serves requests.
+	Probe func(ctx context.Context, c *Container) error
+	// RestartOn is matched against the output of a container that stopped
+	// while starting. On a match the container is set up again, up to
+	// maxContainerRestarts times, e.g. to pick other host ports in Configure
+	// after a port conflict.
+	RestartOn *regexp.Regexp
+
+	container testcontainers.Container
+	restarts  int
+}
+
+const maxContainerRestarts = 3
+
+// portConflict matches the output of a server whose port was taken.
+var portConflict = regexp.MustCompile(`address already in use`)
+
+func (c *Container) GetName() string {
+	return c.Name
+}
+
+func (c *Container) DependsOn() []api.TestPrerequisite {
+	return c.Requires
+}
+
+func (c *Container) Setup(ctx context.Context) error {
+	if c.Configure != nil {
+		if err := c.Configure(ctx, c); err != nil {
+			return err
+		}
+	}
+	req := testcontainers.ContainerRequest{
+		Image:        c.Image,
+		Env:          c.Env,
+		Cmd:          c.Cmd,
+		ExposedPorts: c.Ports,
+		HostConfigModifier: func(hc *container.HostConfig) {
+			hc.RestartPolicy = container.RestartPolicy{Name: container.RestartPolicyOnFailure, MaximumRetryCount: 10}
+			for _, dir := range c.Mounts {
+				hc.Binds = append(hc.Binds, dir+":"+dir+":ro,z")
+			}
+			if c.Network == "host" {
+				hc.NetworkMode = "host"
+			}
+		},
+	}
+	if c.Network != "" && c.Network != "host" {
+		req.Networks = []string{c.Network}
+		req.NetworkAliases = map[string][]string{c.Network: {c.Name}}
+	}
+	var err error
+	c.container, err = testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{ContainerRequest: req, Started: true})
+	if err != nil && c.container != nil {
+		// a container that was created but did not start is returned with the error
+		_ = c.Destroy(context.WithoutCancel(ctx))
+	}
+	return err
+}
+
+func (c *Container) Ready(ctx context.Context) error {
+	state, err := c.container.State(ctx)
+	if err != nil {
+		return err
+	}
+	if !state.Running || state.Restarting {
+		if c.RestartOn != nil && c.restarts < maxContainerRestarts && c.RestartOn.MatchString(c.logs(ctx)) {
+			c.restarts++
+			logrus.Infof("Setting up container %s again (attempt %d)", c.Name, c.restarts+1)
+			if err := c.Destroy(ctx); err != nil {
+				return err
+			}
+			if err := c.Setup(ctx); err != nil {
+				return err
+			}
+			return fmt.Errorf("container %s was %s and is set up again", c.Name, state.Status)
+		}
+		return fmt.Errorf("container %s is %s", c.Name, state.Status)
+	}
+	if c.Probe == nil {
+		return nil
+	}
+	return c.Probe(ctx, c)
+}
+
+// HostAddress returns the address the container port is published at.
+func (c *Container) HostAddress(ctx context.Context, port string) (string, error) {
+	host, err := c.container.Host(ctx)
+	if err != nil {
+		return "", err
+	}
+	mapped, err := c.container.MappedPort(ctx, nat.Port(port))
+	if err != nil {
+		return "", err
+	}
+	return host + ":" + mapped.Port(), nil
+}
+
+// Destroy terminates the container. Its output is logged at debug level first.
+func (c *Container) Destroy(ctx context.Context) error {
+	if c.container == nil {
+		return nil
+	}
+	logrus.WithField("app", c.Name).Debug(c.logs(ctx))
+	err := c.container.Terminate(ctx)
+	c.container = nil
+	return err
+}
+
+func (c *Container) logs(ctx context.Context) string {
+	logs, err := c.container.Logs(ctx)
+	if err != nil {
+		return ""
+	}
+	defer logs.Close()
+	out, _ := io.ReadAll(logs)
+	return string(out)
+}
+
+// probeHTTP returns a probe requiring a 200 response for path on the
+// published port.
+func probeHTTP(port string, path string) func(ctx context.Context, c *Container) error {
+	return func(ctx context.Context, c *Container) error {
+		address, err := c.HostAddress(ctx, port)
+		if err != nil {
+			return err
+		}
+		_, err = httpGet(ctx, "http://"+address+path)
+		return err
+	}
+}
+
+func httpGet(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
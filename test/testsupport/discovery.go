@@ -0,0 +1,102 @@
+package testsupport
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/securesign/sigstore-e2e/pkg/discovery"
+	"github.com/securesign/sigstore-e2e/pkg/kubernetes"
+	"github.com/sirupsen/logrus"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+// discoveredKeys are filled from the cluster when they are not configured.
+var discoveredKeys = []string{api.TufURL, api.FulcioURL, api.RekorURL, api.RekorUIURL, api.TsaURL, api.OidcIssuerURL}
+
+// discoverEndpoints fills unset service URLs from the TUF repository, if
+// enabled, and then from the Securesign resources in the current cluster.
+// Without a cluster the configuration is left as is.
+func discoverEndpoints(ctx context.Context) {
+	if api.GetBool(api.TufDiscovery) {
+		discoverTUFEndpoints()
+	}
+	if !api.GetBool(api.Discovery) {
+		return
+	}
+	missing := false
+	for _, key := range discoveredKeys {
+		missing = missing || api.GetValueFor(key) == ""
+	}
+	if !missing {
+		return
+	}
+	if _, err := config.GetConfig(); err != nil {
+		logrus.Infof("Skipping endpoint discovery, no cluster configured: %v", err)
+		return
+	}
+	endpoints, err := discovery.Discover(ctx, kubernetes.GetClient(), discovery.DefaultOptions())
+	if err != nil {
+		logrus.Warnf("Endpoint discovery failed: %v", err)
+		return
+	}
+	if filled := endpoints.Apply(); len(filled) > 0 {
+		logrus.Infof("Discovered %s from the cluster", strings.Join(filled, ", "))
+	}
+}
+
+func discoverTUFEndpoints() {
+	mirror := api.GetValueFor(api.TufURL)
+	if mirror == "" {
+		logrus.Warnf("Skipping TUF endpoint discovery, %s is not set", api.TufURL)
+		return
+	}
+	cfg, err := TUFConfig()
+	if err != nil {
+		logrus.Warnf("TUF endpoint discovery failed: %v", err)
+		return
+	}
+	if filled := cfg.Endpoints().Apply(); len(filled) > 0 {
+		logrus.Infof("Discovered %s from %s", strings.Join(filled, ", "), mirror)
+		tufDiscoveredMu.Lock()
+		tufDiscovered = append(tufDiscovered, filled...)
+		tufDiscoveredMu.Unlock()
+	}
+}
+
+var (
+	tufDiscoveredMu sync.Mutex
+	// tufDiscovered are the keys filled from the signing config of the TUF
+	// repository.
+	tufDiscovered []string
+)
+
+// DiscoveredFromTUF reports whether the value of key was taken from the
+// signing config of the TUF repository, rather than configured or discovered
+// from the cluster.
+func DiscoveredFromTUF(key string) bool {
+	tufDiscoveredMu.Lock()
+	defer tufDiscoveredMu.Unlock()
+	return slices.Contains(tufDiscovered, key)
+}
+
+var (
+	tufConfigOnce sync.Once
+	tufConfig     *discovery.TUFConfig
+	tufConfigErr  error
+)
+
+// TUFConfig returns the verified trusted root and signing config of the TUF
+// repository at TUF_URL. They are fetched once per run.
+func TUFConfig() (*discovery.TUFConfig, error) {
+	tufConfigOnce.Do(func() {
+		var root []byte
+		if root, tufConfigErr = discovery.ReadRoot(api.GetValueFor(api.TufRootJSON)); tufConfigErr != nil {
+			return
+		}
+		tufConfig, tufConfigErr = discovery.FetchTUFConfig(TestContext, api.GetValueFor(api.TufURL), root)
+	})
+	return tufConfig, tufConfigErr
+}
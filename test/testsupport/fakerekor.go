@@ -0,0 +1,63 @@
+package testsupport
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/securesign/sigstore-e2e/pkg/rekor/rekortest"
+	"github.com/sirupsen/logrus"
+)
+
+// FakeRekor is a prerequisite running an in-process Rekor log that REKOR_URL
+// and REKOR_PUBLIC_KEY point at while it runs.
+//
+//	rekor := testsupport.NewFakeRekor()
+//	Expect(testsupport.InstallPrerequisites(rekor, cosign, rekorCli)).To(Succeed())
+type FakeRekor struct {
+	*rekortest.Server
+	dir       string
+	overrides configOverrides
+}
+
+func NewFakeRekor() *FakeRekor {
+	return &FakeRekor{}
+}
+
+func (f *FakeRekor) GetName() string {
+	return "fake-rekor"
+}
+
+func (f *FakeRekor) Setup(_ context.Context) error {
+	server, err := rekortest.NewServer()
+	if err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp("", "e2e-fake-rekor")
+	if err != nil {
+		server.Close()
+		return err
+	}
+	publicKey := filepath.Join(dir, "rekor.pub")
+	if err = os.WriteFile(publicKey, server.PublicKeyPEM(), 0o600); err != nil {
+		server.Close()
+		_ = os.RemoveAll(dir)
+		return err
+	}
+	f.Server, f.dir = server, dir
+	f.overrides.set(api.RekorURL, server.URL)
+	f.overrides.set(api.RekorPublicKey, publicKey)
+	logrus.Infof("Fake Rekor log %d running at %s", server.TreeID, server.URL)
+	return nil
+}
+
+func (f *FakeRekor) Destroy(_ context.Context) error {
+	if f.Server == nil {
+		return nil
+	}
+	f.Server.Close()
+	f.overrides.restore()
+	f.Server = nil
+	return os.RemoveAll(f.dir)
+}
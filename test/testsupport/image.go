@@ -0,0 +1,99 @@
+package testsupport
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/uuid"
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/sirupsen/logrus"
+)
+
+// TestImage is a prerequisite providing a container image the suites can sign.
+// Unless MANUAL_IMAGE_SETUP is enabled, the source image is pulled and pushed
+// to a new repository of Registry; otherwise TARGET_IMAGE_NAME is used as is.
+// It depends on Registry and on Requires, e.g. the client signing it.
+type TestImage struct {
+	Source   string
+	Name     string
+	Registry *Registry
+	Requires []api.TestPrerequisite
+}
+
+func NewTestImage(source string, registry *Registry, requires ...api.TestPrerequisite) *TestImage {
+	return &TestImage{Source: source, Registry: registry, Requires: requires}
+}
+
+func (i *TestImage) GetName() string {
+	return "test-image"
+}
+
+// DependsOn returns the registry, unless TARGET_IMAGE_NAME is used as is, and Requires.
+func (i *TestImage) DependsOn() []api.TestPrerequisite {
+	if api.GetBool(api.ManualImageSetup) {
+		return i.Requires
+	}
+	return append([]api.TestPrerequisite{i.Registry}, i.Requires...)
+}
+
+func (i *TestImage) Setup(ctx context.Context) error {
+	if api.GetBool(api.ManualImageSetup) {
+		i.Name = api.GetValueFor(api.TargetImageName)
+		if i.Name == "" {
+			return errors.New("TARGET_IMAGE_NAME environment variable must be set when MANUAL_IMAGE_SETUP is true")
+		}
+		return nil
+	}
+
+	i.Name = i.Registry.Reference(uuid.New().String())
+	dockerCli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return err
+	}
+	defer dockerCli.Close()
+
+	out := logrus.NewEntry(logrus.StandardLogger()).WithField("app", "docker").WriterLevel(logrus.DebugLevel)
+	defer out.Close()
+
+	pull, err := dockerCli.ImagePull(ctx, i.Source, image.PullOptions{})
+	if err != nil {
+		return err
+	}
+	defer pull.Close()
+	if _, err = io.Copy(out, pull); err != nil {
+		return err
+	}
+
+	if err = dockerCli.ImageTag(ctx, i.Source, i.Name); err != nil {
+		return err
+	}
+	// use empty auth to avoid  "invalid X-Registry-Auth header: EOF" (https://github.com/moby/moby/issues/10983
+	push, err := dockerCli.ImagePush(ctx, i.Name, image.PushOptions{RegistryAuth: base64.StdEncoding.EncodeToString([]byte("{}"))})
+	if err != nil {
+		return err
+	}
+	defer push.Close()
+	_, err = io.Copy(out, push)
+	return err
+}
+
+// Ready reports whether the image manifest can be resolved from its registry.
+func (i *TestImage) Ready(ctx context.Context) error {
+	ref, err := name.ParseReference(i.Name)
+	if err != nil {
+		return err
+	}
+	_, err = remote.Head(ref, remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	return err
+}
+
+func (i *TestImage) Destroy(_ context.Context) error {
+	return nil
+}
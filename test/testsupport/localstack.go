@@ -0,0 +1,399 @@
+package testsupport
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/securesign/sigstore-e2e/pkg/clients"
+	"github.com/securesign/sigstore-e2e/pkg/localstack"
+	"github.com/sirupsen/logrus"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/network"
+)
+
+const (
+	stackDatabase   = "test:zaphod@tcp(" + localstack.MySQL + ":3306)/test"
+	stackKeyPasswd  = "sigstore-e2e"
+	trillianRPCPort = "8090/tcp"
+)
+
+// LocalStack is a prerequisite running a Sigstore deployment on the local
+// machine: Trillian with MySQL, Rekor, a CT log, Fulcio trusting a mock OIDC
+// provider, a timestamp authority and a TUF repository distributing their
+// keys. The components are upstream images (see localstack.DefaultImages and
+// LOCAL_STACK_IMAGES) started with testcontainers. While it runs, the service
+// URLs and the OIDC configuration point at it.
+//
+// The CT log tree is created with the createtree CLI of the CLI strategy.
+// Fulcio runs on the host network to reach the mock OIDC provider, so the
+// stack needs a Linux Docker or Podman host.
+type LocalStack struct {
+	TUF   *LocalTUF
+	Trust *localstack.Trust
+
+	base       *stackBase
+	createTree *clients.CreateTree
+	logServer  *Container
+	rekor      *Container
+	ctlog      *Container
+	tsa        *Container
+	fulcio     *Container
+	fulcioPort int
+	overrides  configOverrides
+}
+
+// NewLocalStack returns the stack with the images configured by LOCAL_STACK_IMAGES.
+func NewLocalStack() (*LocalStack, error) {
+	images, err := localstack.Images(api.GetValueFor(api.LocalStackImages))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", api.LocalStackImages, err)
+	}
+	s := &LocalStack{base: &stackBase{}, createTree: clients.NewCreateTree()}
+	base := s.base
+
+	mysql := &Container{
+		Name:     localstack.MySQL,
+		Image:    images[localstack.MySQL],
+		Env:      map[string]string{"MYSQL_ROOT_PASSWORD": "zaphod", "MYSQL_DATABASE": "test", "MYSQL_USER": "test", "MYSQL_PASSWORD": "zaphod"},
+		Requires: []api.TestPrerequisite{base},
+	}
+	trillian := func(name string, args ...string) *Container {
+		return &Container{
+			Name:  name,
+			Image: images[name],
+			Cmd: append([]string{"--storage_system=mysql", "--mysql_uri=" + stackDatabase,
+				"--rpc_endpoint=0.0.0.0:8090", "--http_endpoint=0.0.0.0:8091", "--alsologtostderr"}, args...),
+			Ports:    []string{trillianRPCPort, "8091/tcp"},
+			Requires: []api.TestPrerequisite{base, mysql},
+			Probe:    probeHTTP("8091/tcp", "/healthz"),
+		}
+	}
+	s.logServer = trillian(localstack.TrillianLogServer)
+	logSigner := trillian(localstack.TrillianLogSigner, "--force_master")
+
+	s.rekor = &Container{
+		Name:  localstack.Rekor,
+		Image: images[localstack.Rekor],
+		Cmd: []string{"serve",
+			"--trillian_log_server.address=" + localstack.TrillianLogServer, "--trillian_log_server.port=8090",
+			"--rekor_server.address=0.0.0.0", "--port=3000", "--rekor_server.signer=memory",
+			"--search_index.storage_provider=mysql", "--search_index.mysql.dsn=" + stackDatabase,
+			"--enable_attestation_storage=false"},
+		Ports:    []string{"3000/tcp"},
+		Requires: []api.TestPrerequisite{base, s.logServer, logSigner},
+		Probe:    probeHTTP("3000/tcp", "/api/v1/log"),
+	}
+	s.ctlog = &Container{
+		Name:      localstack.CTLog,
+		Image:     images[localstack.CTLog],
+		Ports:     []string{"6962/tcp"},
+		Requires:  []api.TestPrerequisite{base, s.logServer, logSigner, s.createTree},
+		Configure: s.configureCTLog,
+		Probe:     probeHTTP("6962/tcp", "/test/ct/v1/get-roots"),
+	}
+	s.fulcio = &Container{
+		Name:      localstack.Fulcio,
+		Image:     images[localstack.Fulcio],
+		Network:   "host",
+		RestartOn: portConflict,
+		Requires:  []api.TestPrerequisite{base, s.ctlog},
+		Configure: s.configureFulcio,
+		Probe: func(ctx context.Context, _ *Container) error {
+			_, err := httpGet(ctx, fmt.Sprintf("http://127.0.0.1:%d/api/v1/rootCert", s.fulcioPort))
+			return err
+		},
+	}
+	s.tsa = &Container{
+		Name:     localstack.TSA,
+		Image:    images[localstack.TSA],
+		Cmd:      []string{"serve", "--host=0.0.0.0", "--port=3000", "--timestamp-signer=memory"},
+		Ports:    []string{"3000/tcp"},
+		Requires: []api.TestPrerequisite{base},
+		Probe:    probeHTTP("3000/tcp", "/api/v1/timestamp/certchain"),
+	}
+	for _, c := range []*Container{mysql, s.logServer, logSigner, s.rekor, s.ctlog, s.tsa} {
+		c.Configure = joinNetwork(base, c.Configure)
+	}
+	return s, nil
+}
+
+// joinNetwork sets the network of the stack, which only exists once the
+// base is set up, before running configure.
+func joinNetwork(base *stackBase, configure func(context.Context, *Container) error) func(context.Context, *Container) error {
+	return func(ctx context.Context, c *Container) error {
+		c.Network = base.network.Name
+		if configure == nil {
+			return nil
+		}
+		return configure(ctx, c)
+	}
+}
+
+func (s *LocalStack) GetName() string {
+	return "local-stack"
+}
+
+func (s *LocalStack) DependsOn() []api.TestPrerequisite {
+	return []api.TestPrerequisite{s.base, s.rekor, s.ctlog, s.fulcio, s.tsa}
+}
+
+// Setup publishes the keys of the running components in a local TUF
+// repository and points the configuration at the stack.
+func (s *LocalStack) Setup(ctx context.Context) error {
+	rekorAddress, err := s.rekor.HostAddress(ctx, "3000/tcp")
+	if err != nil {
+		return err
+	}
+	ctlogAddress, err := s.ctlog.HostAddress(ctx, "6962/tcp")
+	if err != nil {
+		return err
+	}
+	tsaAddress, err := s.tsa.HostAddress(ctx, "3000/tcp")
+	if err != nil {
+		return err
+	}
+	s.Trust = &localstack.Trust{
+		Since:         s.base.started,
+		RekorURL:      "http://" + rekorAddress,
+		CTLogURL:      "http://" + ctlogAddress + "/test",
+		CTLogKey:      s.base.ctlogKey,
+		FulcioURL:     fmt.Sprintf("http://127.0.0.1:%d", s.fulcioPort),
+		FulcioChain:   s.base.ca.CertificatePEM(),
+		TSAURL:        "http://" + tsaAddress + "/api/v1/timestamp",
+		OIDCIssuerURL: s.base.oidc.URL,
+	}
+	if s.Trust.RekorKey, err = httpGet(ctx, s.Trust.RekorURL+"/api/v1/log/publicKey"); err != nil {
+		return err
+	}
+	if s.Trust.TSAChain, err = httpGet(ctx, s.Trust.TSAURL+"/certchain"); err != nil {
+		return err
+	}
+	targets, err := s.Trust.Targets()
+	if err != nil {
+		return err
+	}
+
+	s.TUF = NewLocalTUF(targets)
+	if err := s.TUF.Setup(ctx); err != nil {
+		_ = s.TUF.Destroy(ctx)
+		return err
+	}
+	s.overrides.set(api.FulcioURL, s.Trust.FulcioURL)
+	s.overrides.set(api.RekorURL, s.Trust.RekorURL)
+	s.overrides.set(api.TsaURL, s.Trust.TSAURL)
+	logrus.Infof("Local Sigstore stack running: TUF %s, Fulcio %s, Rekor %s, CT log %s, TSA %s, OIDC %s",
+		s.TUF.URL, s.Trust.FulcioURL, s.Trust.RekorURL, s.Trust.CTLogURL, s.Trust.TSAURL, s.Trust.OIDCIssuerURL)
+	return nil
+}
+
+func (s *LocalStack) Destroy(ctx context.Context) error {
+	s.overrides.restore()
+	if s.TUF == nil {
+		return nil
+	}
+	return s.TUF.Destroy(ctx)
+}
+
+// configureCTLog creates the Trillian tree of the CT log and writes its configuration.
+func (s *LocalStack) configureCTLog(ctx context.Context, c *Container) error {
+	rpc, err := s.logServer.HostAddress(ctx, trillianRPCPort)
+	if err != nil {
+		return err
+	}
+	output, err := s.createTree.Command(ctx, "--admin_server="+rpc, "--display_name=ctlog").Output()
+	if err != nil {
+		return fmt.Errorf("cannot create the CT log tree: %w", err)
+	}
+	lines := strings.Fields(string(output))
+	if len(lines) == 0 {
+		return fmt.Errorf("createtree returned no tree ID")
+	}
+	treeID, err := strconv.ParseInt(lines[len(lines)-1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid tree ID from createtree: %w", err)
+	}
+	config := filepath.Join(s.base.dir, "ctlog.cfg")
+	content := localstack.CTLogConfig(treeID, s.base.path("fulcio.pem"), s.base.path("ctlog.key"), stackKeyPasswd)
+	if err := os.WriteFile(config, []byte(content), 0o644); err != nil { //nolint:gosec // read by the container user
+		return err
+	}
+	c.Mounts = []string{s.base.dir}
+	c.Cmd = []string{"--log_config=" + config, "--log_rpc_server=" + localstack.TrillianLogServer + ":8090",
+		"--http_endpoint=0.0.0.0:6962", "--alsologtostderr"}
+	return nil
+}
+
+// configureFulcio picks free ports for Fulcio on the host network. Another
+// process can take them before Fulcio binds them, so Fulcio is set up again
+// with new ones on a port conflict.
+func (s *LocalStack) configureFulcio(ctx context.Context, c *Container) error {
+	ctlog, err := s.ctlog.HostAddress(ctx, "6962/tcp")
+	if err != nil {
+		return err
+	}
+	ports, err := freePorts(3)
+	if err != nil {
+		return err
+	}
+	s.fulcioPort = ports[0]
+	c.Mounts = []string{s.base.dir}
+	c.Cmd = []string{"serve", "--host=127.0.0.1",
+		fmt.Sprintf("--port=%d", ports[0]), fmt.Sprintf("--grpc-port=%d", ports[1]), fmt.Sprintf("--metrics-port=%d", ports[2]),
+		"--ca=fileca", "--fileca-cert=" + s.base.path("fulcio.pem"), "--fileca-key=" + s.base.path("fulcio.key"),
+		"--fileca-key-passwd=" + stackKeyPasswd, "--ct-log-url=http://" + ctlog + "/test",
+		"--config-path=" + s.base.path("fulcio.json")}
+	return nil
+}
+
+// stackBase holds what the components share: a Docker network, a directory
+// with their keys and configuration, and the mock OIDC provider.
+type stackBase struct {
+	dir      string
+	network  *testcontainers.DockerNetwork
+	started  time.Time
+	ca       *localstack.CA
+	ctlogKey []byte
+	oidc     *MockOIDC
+}
+
+func (b *stackBase) GetName() string {
+	return "local-stack-base"
+}
+
+func (b *stackBase) path(name string) string {
+	return filepath.Join(b.dir, name)
+}
+
+func (b *stackBase) Setup(ctx context.Context) error {
+	b.started = time.Now().Add(-time.Minute)
+	var err error
+	if b.dir, err = os.MkdirTemp("", "e2e-local-stack"); err != nil {
+		return err
+	}
+	// the components read their keys as the user of their image
+	if err = os.Chmod(b.dir, 0o755); err != nil { //nolint:gosec // throwaway keys of a local stack
+		return err
+	}
+
+	if b.ca, err = localstack.NewCA("sigstore-e2e local Fulcio", 24*time.Hour); err != nil {
+		return err
+	}
+	caKey, err := localstack.EncryptedKeyPEM(b.ca.Key, stackKeyPasswd)
+	if err != nil {
+		return err
+	}
+	ctKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	ctPrivate, err := localstack.EncryptedKeyPEM(ctKey, stackKeyPasswd)
+	if err != nil {
+		return err
+	}
+	ctPublic, err := x509.MarshalPKIXPublicKey(ctKey.Public())
+	if err != nil {
+		return err
+	}
+	b.ctlogKey = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: ctPublic})
+
+	if b.oidc, err = StartMockOIDC(); err != nil {
+		return err
+	}
+	for name, content := range map[string][]byte{
+		"fulcio.pem":  b.ca.CertificatePEM(),
+		"fulcio.key":  caKey,
+		"ctlog.key":   ctPrivate,
+		"fulcio.json": []byte(localstack.FulcioConfig(b.oidc.URL, MockOIDCClientID)),
+	} {
+		if err = os.WriteFile(b.path(name), content, 0o644); err != nil { //nolint:gosec // throwaway keys of a local stack
+			return err
+		}
+	}
+
+	b.network, err = network.New(ctx)
+	return err
+}
+
+func (b *stackBase) Destroy(ctx context.Context) error {
+	if b.oidc != nil {
+		b.oidc.Close()
+		b.oidc = nil
+	}
+	var err error
+	if b.network != nil {
+		err = b.network.Remove(ctx)
+		b.network = nil
+	}
+	if b.dir != "" {
+		_ = os.RemoveAll(b.dir)
+		b.dir = ""
+	}
+	return err
+}
+
+func freePorts(n int) ([]int, error) {
+	var ports []int
+	for range n {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, err
+		}
+		defer l.Close()
+		ports = append(ports, l.Addr().(*net.TCPAddr).Port)
+	}
+	return ports, nil
+}
+
+// suiteStack holds the prerequisites of the stack started for the whole
+// suite with LOCAL_STACK, apart from the ones installed by the specs.
+var suiteStack []api.TestPrerequisite
+
+// LocalStackOIDC returns the mock OIDC provider trusted by the Fulcio of the
+// local stack, or nil when no stack was started. The provider runs in the
+// first Ginkgo process, so specs minting tokens with it must be Serial.
+func LocalStackOIDC() *MockOIDC {
+	for _, p := range suiteStack {
+		if base, ok := p.(*stackBase); ok {
+			return base.oidc
+		}
+	}
+	return nil
+}
+
+// startLocalStack starts the local stack when LOCAL_STACK is enabled. On
+// failure, the started components are torn down and the error is returned.
+func startLocalStack(ctx context.Context) error {
+	if !api.GetBool(api.LocalStack) {
+		return nil
+	}
+	stack, err := NewLocalStack()
+	if err == nil {
+		err = install(ctx, &suiteStack, stack)
+	}
+	if err != nil {
+		if destroyErr := destroy(&suiteStack); destroyErr != nil {
+			logrus.Warnf("Local Sigstore stack was not cleaned up: %v", destroyErr)
+		}
+		return fmt.Errorf("cannot start the local Sigstore stack: %w", err)
+	}
+	return nil
+}
+
+// stopLocalStack tears the local stack down, if one was started.
+func stopLocalStack() {
+	if err := destroy(&suiteStack); err != nil {
+		logrus.Warnf("Local Sigstore stack was not cleaned up: %v", err)
+	}
+}
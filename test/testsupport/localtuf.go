@@ -0,0 +1,96 @@
+package testsupport
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/securesign/sigstore-e2e/pkg/tuf/tuftest"
+	"github.com/sirupsen/logrus"
+)
+
+// LocalTUF is a prerequisite serving a TUF repository built in-process that
+// TUF_URL points at while it runs.
+//
+// With Rekor set, the repository also distributes the key of the fake Rekor
+// log as rekor.pub. With TLS, clients must trust CertificatePath, e.g. with
+// client.Env().Set("SSL_CERT_FILE", tuf.CertificatePath).
+type LocalTUF struct {
+	*tuftest.Server
+	Targets map[string][]byte
+	TLS     bool
+	Rekor   *FakeRekor
+	// RootPath is the root.json clients are initialized with.
+	RootPath string
+	// CertificatePath is the certificate of a TLS server.
+	CertificatePath string
+
+	dir       string
+	overrides configOverrides
+}
+
+func NewLocalTUF(targets map[string][]byte) *LocalTUF {
+	return &LocalTUF{Targets: targets}
+}
+
+func (l *LocalTUF) GetName() string {
+	return "local-tuf"
+}
+
+func (l *LocalTUF) DependsOn() []api.TestPrerequisite {
+	if l.Rekor == nil {
+		return nil
+	}
+	return []api.TestPrerequisite{l.Rekor}
+}
+
+func (l *LocalTUF) Setup(ctx context.Context) error {
+	targets := map[string][]byte{}
+	for name, content := range l.Targets {
+		targets[name] = content
+	}
+	if _, ok := targets[tuftest.RekorTarget]; !ok && l.Rekor != nil && l.Rekor.Server != nil {
+		targets[tuftest.RekorTarget] = l.Rekor.PublicKeyPEM()
+	}
+	start := tuftest.NewServer
+	if l.TLS {
+		start = tuftest.NewTLSServer
+	}
+	server, err := start(targets)
+	if err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp("", "e2e-local-tuf")
+	if err != nil {
+		server.Close()
+		return err
+	}
+	l.Server, l.dir = server, dir
+
+	l.RootPath = filepath.Join(dir, "root.json")
+	if err = os.WriteFile(l.RootPath, server.RootJSON(), 0o600); err != nil {
+		_ = l.Destroy(ctx)
+		return err
+	}
+	if l.TLS {
+		l.CertificatePath = filepath.Join(dir, "ca.pem")
+		if err = os.WriteFile(l.CertificatePath, server.Certificate(), 0o600); err != nil {
+			_ = l.Destroy(ctx)
+			return err
+		}
+	}
+	l.overrides.set(api.TufURL, server.URL)
+	logrus.Infof("Local TUF repository with %d targets running at %s", len(targets), server.URL)
+	return nil
+}
+
+func (l *LocalTUF) Destroy(_ context.Context) error {
+	if l.Server == nil {
+		return nil
+	}
+	l.Server.Close()
+	l.overrides.restore()
+	l.Server = nil
+	return os.RemoveAll(l.dir)
+}
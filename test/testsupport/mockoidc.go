@@ -0,0 +1,105 @@
+package testsupport
+
+import (
+	"context"
+	"strings"
+
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/securesign/sigstore-e2e/pkg/oidc/oidctest"
+)
+
+// Credentials of the default identity of the mock OIDC provider.
+const (
+	MockOIDCClientID = "sigstore"
+	MockOIDCUser     = "jdoe"
+	MockOIDCPassword = "secure"
+	MockOIDCDomain   = "example.com"
+)
+
+// MockOIDC is a prerequisite running an in-process OIDC provider the OIDC
+// configuration points at while it runs. Fulcio must be configured to trust
+// its URL for certificates to be issued; token acquisition and claim checks
+// work without it.
+//
+//	oidc := testsupport.NewMockOIDC()
+//	Expect(testsupport.InstallPrerequisites(oidc, cosign)).To(Succeed())
+type MockOIDC struct {
+	*oidctest.Server
+	overrides configOverrides
+}
+
+func NewMockOIDC() *MockOIDC {
+	return &MockOIDC{}
+}
+
+// StartMockOIDC starts a mock OIDC provider outside of the prerequisite
+// lifecycle. Close restores the configuration.
+//
+//	mock, err := testsupport.StartMockOIDC()
+//	Expect(err).ToNot(HaveOccurred())
+//	DeferCleanup(mock.Close)
+func StartMockOIDC() (*MockOIDC, error) {
+	m := NewMockOIDC()
+	if err := m.Setup(context.Background()); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *MockOIDC) GetName() string {
+	return "mock-oidc"
+}
+
+// Setup starts the provider and makes it the issuer of the default identity,
+// jdoe@example.com.
+func (m *MockOIDC) Setup(_ context.Context) error {
+	server, err := oidctest.NewServer()
+	if err != nil {
+		return err
+	}
+	m.Server = server
+	m.AddUser(MockOIDCUser, MockOIDCPassword, oidctest.Email(MockOIDCUser+"@"+MockOIDCDomain))
+	m.overrides.set(api.OidcIssuerURL, server.URL)
+	m.overrides.set(api.OidcRealm, MockOIDCClientID)
+	m.overrides.set(api.OidcClientID, MockOIDCClientID)
+	m.overrides.set(api.OidcUser, MockOIDCUser)
+	m.overrides.set(api.OidcPassword, MockOIDCPassword)
+	m.overrides.set(api.OidcUserDomain, MockOIDCDomain)
+	m.overrides.set(api.OidcGrantType, "password")
+	m.overrides.set(api.OidcToken, "")
+	resetTokenCaches()
+	return nil
+}
+
+func (m *MockOIDC) Destroy(_ context.Context) error {
+	m.Close()
+	return nil
+}
+
+// AddIdentity adds the identity name, user@example.com, obtainable with
+// GetOIDCTokenFor.
+func (m *MockOIDC) AddIdentity(name string, user string) {
+	password := MockOIDCPassword + "-" + user
+	m.AddUser(user, password, oidctest.Email(user+"@"+MockOIDCDomain))
+	names := api.ConfiguredIdentities()[1:]
+	m.overrides.set(api.Identities, strings.Join(append(names, name), ","))
+	m.overrides.set(api.IdentityKey(name, api.OidcUser), user)
+	m.overrides.set(api.IdentityKey(name, api.OidcPassword), password)
+}
+
+// Token mints a token of identity for the configured client, e.g. an
+// identity.Expired() or identity.WithAudience("other") token.
+func (m *MockOIDC) Token(identity oidctest.Identity) (string, error) {
+	return m.Mint(identity, MockOIDCClientID)
+}
+
+// Close stops the provider and restores the OIDC configuration.
+func (m *MockOIDC) Close() {
+	if m.Server == nil {
+		return
+	}
+	m.Server.Close()
+	m.Server = nil
+	m.overrides.restore()
+	resetTokenCaches()
+}
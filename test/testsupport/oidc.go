@@ -0,0 +1,163 @@
+package testsupport
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/securesign/sigstore-e2e/pkg/oidc"
+	"github.com/securesign/sigstore-e2e/pkg/redact"
+	"github.com/sirupsen/logrus"
+)
+
+// Identity is an OIDC identity the suites sign with, as configured by the
+// OIDC keys, or the IDENTITY_<NAME>_OIDC_* keys for further identities.
+type Identity struct {
+	Name      string
+	IssuerURL string
+	ClientID  string
+	User      string
+	Domain    string
+}
+
+// Email is the identity in the signing certificates of password grant tokens.
+func (i Identity) Email() string {
+	return i.User + "@" + i.Domain
+}
+
+// GetIdentity returns the configuration of the identity name.
+func GetIdentity(name string) Identity {
+	return Identity{
+		Name:      name,
+		IssuerURL: api.GetIdentityValueFor(name, api.OidcIssuerURL),
+		ClientID:  api.GetIdentityValueFor(name, api.OidcClientID),
+		User:      api.GetIdentityValueFor(name, api.OidcUser),
+		Domain:    api.GetIdentityValueFor(name, api.OidcUserDomain),
+	}
+}
+
+// OtherIdentities returns the identities configured besides the default one.
+func OtherIdentities() []Identity {
+	var identities []Identity
+	for _, name := range api.ConfiguredIdentities()[1:] {
+		identities = append(identities, GetIdentity(name))
+	}
+	return identities
+}
+
+var (
+	tokenCachesMu sync.Mutex
+	tokenCaches   = map[string]*oidc.Cache{}
+)
+
+func tokenCache(identity string) *oidc.Cache {
+	tokenCachesMu.Lock()
+	defer tokenCachesMu.Unlock()
+	c, ok := tokenCaches[identity]
+	if !ok {
+		c = oidc.NewCache(func(ctx context.Context, previous *oidc.Token) (*oidc.Token, error) {
+			return fetchOIDCToken(ctx, identity, previous)
+		})
+		tokenCaches[identity] = c
+	}
+	return c
+}
+
+// GetOIDCToken returns a token of the default identity.
+func GetOIDCToken(ctx context.Context) (string, error) {
+	return GetOIDCTokenFor(ctx, api.DefaultIdentity)
+}
+
+// GetOIDCTokenFor returns a token of identity. Tokens are cached per identity
+// and renewed shortly before they expire.
+func GetOIDCTokenFor(ctx context.Context, identity string) (string, error) {
+	token, err := tokenCache(identity).Token(ctx)
+	if err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+// fetchOIDCToken returns OIDC_TOKEN or requests a token from OIDC_ISSUER_URL
+// with the grant selected by OIDC_GRANT_TYPE. A previous token is renewed with
+// its refresh token when it has one. The claims of the token are checked
+// against the values the suites later verify certificates with.
+func fetchOIDCToken(ctx context.Context, identity string, previous *oidc.Token) (*oidc.Token, error) {
+	value := func(key string) string { return api.GetIdentityValueFor(identity, key) }
+	if token := value(api.OidcToken); token != "" {
+		redact.AddSecrets(token)
+		logrus.Infof("Using OIDC token of identity %s from ENV var", identity)
+		if err := checkTokenClaims(identity, token, ""); err != nil {
+			return nil, fmt.Errorf("%s: %w", api.IdentityKey(identity, api.OidcToken), err)
+		}
+		return &oidc.Token{AccessToken: token}, nil
+	}
+
+	issuer := value(api.OidcIssuerURL)
+	provider, err := oidc.Discover(ctx, nil, issuer)
+	if err != nil {
+		return nil, err
+	}
+	request := oidc.TokenRequest{
+		Grant:        value(api.OidcGrantType),
+		ClientID:     value(api.OidcRealm),
+		ClientSecret: value(api.OidcClientSecret),
+		Username:     value(api.OidcUser),
+		Password:     value(api.OidcPassword),
+		RefreshToken: value(api.OidcRefreshToken),
+		Scopes:       []string{"openid"},
+	}
+	if !provider.SupportsGrant(request.Grant) {
+		return nil, fmt.Errorf("OIDC provider %s does not support the %s grant (supported: %v)", issuer, request.Grant, provider.GrantTypesSupported)
+	}
+
+	var token *oidc.Token
+	if previous != nil && previous.RefreshToken != "" && provider.SupportsGrant(oidc.GrantRefreshToken) {
+		refresh := request
+		refresh.Grant, refresh.RefreshToken = oidc.GrantRefreshToken, previous.RefreshToken
+		if token, err = oidc.RequestToken(ctx, nil, provider.TokenEndpoint, refresh); err != nil {
+			logrus.Infof("Cannot refresh OIDC token of identity %s, requesting a new one: %v", identity, err)
+		}
+	}
+	if token == nil {
+		if token, err = oidc.RequestToken(ctx, nil, provider.TokenEndpoint, request); err != nil {
+			return nil, fmt.Errorf("cannot get OIDC token of identity %s from %s: %w", identity, issuer, err)
+		}
+	}
+	redact.AddSecrets(token.AccessToken, token.IDToken, token.RefreshToken)
+	if err := checkTokenClaims(identity, token.AccessToken, request.Grant); err != nil {
+		return nil, fmt.Errorf("OIDC token of identity %s from %s: %w", identity, issuer, err)
+	}
+	logrus.Infof("Obtained OIDC token of identity %s, valid until %s", identity, oidc.Expiry(token, time.Now()).Format(time.RFC3339))
+	return token, nil
+}
+
+// checkTokenClaims compares the claims of token with the issuer, client and
+// identity the suites expect in the signing certificates. The email of a
+// password grant must be OIDC_USER@OIDC_USER_DOMAIN, other user tokens only
+// need an email in OIDC_USER_DOMAIN and client tokens need no email at all.
+func checkTokenClaims(identity string, token string, grant string) error {
+	claims, err := oidc.ParseClaims(token)
+	if err != nil {
+		return err
+	}
+	id := GetIdentity(identity)
+	expected := oidc.Expectation{Issuer: id.IssuerURL, Audience: id.ClientID}
+	switch grant {
+	case oidc.GrantPassword:
+		expected.Email = id.Email()
+	case oidc.GrantClientCredentials:
+	default:
+		expected.EmailDomain = id.Domain
+	}
+	return claims.Check(expected, time.Now())
+}
+
+// resetTokenCaches drops the cached tokens, e.g. after the OIDC configuration changed.
+func resetTokenCaches() {
+	tokenCachesMu.Lock()
+	defer tokenCachesMu.Unlock()
+	tokenCaches = map[string]*oidc.Cache{}
+}
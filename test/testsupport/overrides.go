@@ -0,0 +1,29 @@
+package testsupport
+
+import "github.com/securesign/sigstore-e2e/pkg/api"
+
+// configOverrides points configuration values at the services run by a
+// prerequisite and remembers the previous values until they are restored.
+// Prerequisites using it must be installed before the clients, as their
+// environment is taken from the configuration at client setup.
+type configOverrides map[string]string
+
+// set overrides key with value, keeping the value it had before the first
+// override.
+func (o *configOverrides) set(key string, value string) {
+	if *o == nil {
+		*o = configOverrides{}
+	}
+	if _, ok := (*o)[key]; !ok {
+		(*o)[key] = api.Values.GetString(key)
+	}
+	api.Values.Set(key, value)
+}
+
+// restore sets the overridden keys back to their previous values.
+func (o *configOverrides) restore() {
+	for key, value := range *o {
+		api.Values.Set(key, value)
+	}
+	*o = nil
+}
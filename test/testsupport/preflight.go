@@ -0,0 +1,90 @@
+package testsupport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/securesign/sigstore-e2e/pkg/preflight"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	preflightMu sync.Mutex
+	// preflightResults caches the results by service and URL, so services are
+	// probed again once a prerequisite points their configuration elsewhere.
+	preflightResults = map[string]preflight.Result{}
+)
+
+// runPreflight probes the configured services and logs the report.
+func runPreflight(ctx context.Context) {
+	if !api.GetBool(api.Preflight) {
+		return
+	}
+	report := preflight.Run(ctx, http.DefaultClient)
+	preflightMu.Lock()
+	for _, result := range report {
+		preflightResults[result.Service+" "+result.URL] = result
+	}
+	preflightMu.Unlock()
+
+	table := "  " + strings.ReplaceAll(report.String(), "\n", "\n  ")
+	if failed := report.Failed(); len(failed) > 0 {
+		logrus.Warnf("Preflight check, %d service(s) failed:\n%s", len(failed), table)
+		return
+	}
+	logrus.Infof("Preflight check:\n%s", table)
+}
+
+// ServiceHealth returns the preflight result of the service called name at
+// its configured URL, probing it on first use.
+func ServiceHealth(name string) preflight.Result {
+	service, ok := preflight.Lookup(name)
+	if !ok {
+		return preflight.Result{Service: name, Status: preflight.StatusFailed, Reason: "unknown service"}
+	}
+	url := api.GetValueFor(service.Key)
+	preflightMu.Lock()
+	defer preflightMu.Unlock()
+	result, ok := preflightResults[name+" "+url]
+	if !ok {
+		result = preflight.Check(TestContext, http.DefaultClient, service, url)
+		preflightResults[name+" "+url] = result
+	}
+	return result
+}
+
+// RequireServices skips the running spec, or the ordered container from
+// BeforeAll, when one of the services failed its preflight check, naming the
+// probed endpoint and its failure. Services that are not configured are left
+// to the configuration checks.
+func RequireServices(names ...string) {
+	if !api.GetBool(api.Preflight) {
+		return
+	}
+	var reasons []string
+	for _, name := range names {
+		if result := ServiceHealth(name); result.Status == preflight.StatusFailed {
+			reasons = append(reasons, skipReason(result))
+		}
+	}
+	if len(reasons) > 0 {
+		ginkgo.Skip(fmt.Sprintf("preflight check failed: %s (set %s=false to run anyway)", strings.Join(reasons, "; "), api.Preflight))
+	}
+}
+
+// skipReason describes a failed result by the endpoint that was probed,
+// e.g. "Rekor: GET https://rekor.example.com/api/v1/log: 503 Service Unavailable".
+func skipReason(result preflight.Result) string {
+	switch {
+	case result.Probe == "":
+		return result.String()
+	case strings.Contains(result.Reason, result.Probe):
+		return fmt.Sprintf("%s: %s", result.Service, result.Reason)
+	}
+	return fmt.Sprintf("%s: GET %s: %s", result.Service, result.Probe, result.Reason)
+}
@@ -0,0 +1,143 @@
+package testsupport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// PrerequisiteReadyTimeout is the maximum time to wait for a prerequisite
+	// implementing api.ReadinessChecker to become ready after Setup.
+	PrerequisiteReadyTimeout  = 2 * time.Minute
+	PrerequisiteReadyInterval = 2 * time.Second
+)
+
+// LifecycleEvent is a single step in the prerequisite lifecycle timeline.
+type LifecycleEvent struct {
+	Prerequisite string
+	Phase        string
+	Start        time.Time
+	Duration     time.Duration
+	Err          error
+}
+
+var (
+	installedStack []api.TestPrerequisite = make([]api.TestPrerequisite, 0)
+	timeline       []LifecycleEvent
+)
+
+// InstallPrerequisites sets up the given prerequisites together with their
+// declared dependencies, in dependency order. Prerequisites implementing
+// api.ReadinessChecker are waited for before their dependents are set up.
+// Prerequisites that are already installed are skipped.
+func InstallPrerequisites(prerequisite ...api.TestPrerequisite) error {
+	return install(TestContext, &installedStack, prerequisite...)
+}
+
+// install sets up the prerequisites and their dependencies with ctx,
+// appending them to stack as they come up.
+func install(ctx context.Context, stack *[]api.TestPrerequisite, prerequisite ...api.TestPrerequisite) error {
+	ordered, err := api.ResolvePrerequisites(prerequisite...)
+	if err != nil {
+		return err
+	}
+	for _, p := range ordered {
+		if slices.Contains(*stack, p) {
+			continue
+		}
+		if err := record(ctx, p, "setup", p.Setup); err != nil {
+			return fmt.Errorf("setup of %s failed: %w", api.PrerequisiteName(p), err)
+		}
+		*stack = append(*stack, p)
+
+		if b, ok := p.(buildInspectable); ok {
+			if err := record(ctx, p, "inspect", func(context.Context) error { return inspectBuild(p, b) }); err != nil {
+				return fmt.Errorf("build of %s does not meet expectations: %w", api.PrerequisiteName(p), err)
+			}
+		}
+
+		if r, ok := p.(api.ReadinessChecker); ok {
+			if err := record(ctx, p, "ready", func(ctx context.Context) error { return waitReady(ctx, r) }); err != nil {
+				return fmt.Errorf("%s is not ready: %w", api.PrerequisiteName(p), err)
+			}
+		}
+	}
+	return nil
+}
+
+func DestroyPrerequisites() error {
+	return destroy(&installedStack)
+}
+
+// destroy tears the prerequisites of stack down in reverse order.
+func destroy(stack *[]api.TestPrerequisite) error {
+	var errs []error
+	for i := len(*stack) - 1; i >= 0; i-- {
+		err := record(TestContext, (*stack)[i], "destroy", (*stack)[i].Destroy)
+		if err != nil {
+			logrus.Warn(err)
+			errs = append(errs, err)
+		}
+	}
+	*stack = (*stack)[:0]
+	if len(errs) != 0 {
+		return fmt.Errorf("can't destroy all prerequisites %s", errs)
+	}
+	return nil
+}
+
+// PrerequisiteTimeline returns the lifecycle events recorded so far.
+func PrerequisiteTimeline() []LifecycleEvent {
+	return slices.Clone(timeline)
+}
+
+// record runs a lifecycle step of p with ctx. Destroy steps run with a
+// CleanupContext instead, so that they also run after an interrupt.
+func record(ctx context.Context, p api.TestPrerequisite, phase string, step func(ctx context.Context) error) error {
+	event := LifecycleEvent{
+		Prerequisite: api.PrerequisiteName(p),
+		Phase:        phase,
+		Start:        time.Now(),
+	}
+	if phase == "destroy" {
+		var cancel context.CancelFunc
+		ctx, cancel = CleanupContext()
+		defer cancel()
+	}
+	event.Err = step(ctx)
+	event.Duration = time.Since(event.Start)
+	timeline = append(timeline, event)
+
+	entry := logrus.WithField("prerequisite", event.Prerequisite).WithField("phase", phase)
+	if event.Err != nil {
+		entry.Warnf("%s failed after %v: %v", phase, event.Duration.Round(time.Millisecond), event.Err)
+	} else {
+		entry.Infof("%s finished in %v", phase, event.Duration.Round(time.Millisecond))
+	}
+	return event.Err
+}
+
+func waitReady(ctx context.Context, r api.ReadinessChecker) error {
+	ctx, cancel := context.WithTimeout(ctx, PrerequisiteReadyTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(PrerequisiteReadyInterval)
+	defer ticker.Stop()
+	for {
+		err := r.Ready(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Join(ctx.Err(), err)
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package testsupport
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/securesign/sigstore-e2e/pkg/api"
+)
+
+// lifecycle records the steps of fakePrerequisites in the order they run.
+type lifecycle []string
+
+type fakePrerequisite struct {
+	name       string
+	deps       []api.TestPrerequisite
+	log        *lifecycle
+	destroyErr error
+}
+
+func (f *fakePrerequisite) GetName() string                   { return f.name }
+func (f *fakePrerequisite) DependsOn() []api.TestPrerequisite { return f.deps }
+
+func (f *fakePrerequisite) Setup(_ context.Context) error {
+	*f.log = append(*f.log, "setup "+f.name)
+	return nil
+}
+
+func (f *fakePrerequisite) Destroy(_ context.Context) error {
+	*f.log = append(*f.log, "destroy "+f.name)
+	return f.destroyErr
+}
+
+// readyPrerequisite becomes ready after failing its first readiness checks.
+type readyPrerequisite struct {
+	fakePrerequisite
+	notReady int
+}
+
+func (r *readyPrerequisite) Ready(_ context.Context) error {
+	if r.notReady > 0 {
+		r.notReady--
+		return errors.New("not ready yet")
+	}
+	*r.log = append(*r.log, "ready "+r.name)
+	return nil
+}
+
+func fastReadiness(t *testing.T, timeout time.Duration) {
+	previousTimeout, previousInterval := PrerequisiteReadyTimeout, PrerequisiteReadyInterval
+	PrerequisiteReadyTimeout, PrerequisiteReadyInterval = timeout, time.Millisecond
+	t.Cleanup(func() {
+		PrerequisiteReadyTimeout, PrerequisiteReadyInterval = previousTimeout, previousInterval
+	})
+}
+
+func TestInstallPrerequisitesOrder(t *testing.T) {
+	fastReadiness(t, time.Second)
+	var log lifecycle
+	registry := &readyPrerequisite{fakePrerequisite: fakePrerequisite{name: "registry", log: &log}, notReady: 2}
+	cosign := &fakePrerequisite{name: "cosign", log: &log}
+	image := &fakePrerequisite{name: "image", deps: []api.TestPrerequisite{registry, cosign}, log: &log}
+	rekorCli := &fakePrerequisite{name: "rekor-cli", log: &log}
+
+	var stack []api.TestPrerequisite
+	if err := install(context.Background(), &stack, cosign); err != nil {
+		t.Fatal(err)
+	}
+	if err := install(context.Background(), &stack, image, rekorCli); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "setup cosign,setup registry,ready registry,setup image,setup rekor-cli"
+	if got := strings.Join(log, ","); got != want {
+		t.Fatalf("got lifecycle %s, want %s", got, want)
+	}
+	if len(stack) != 4 {
+		t.Fatalf("expected 4 installed prerequisites, got %d", len(stack))
+	}
+}
+
+func TestInstallPrerequisitesReadyTimeout(t *testing.T) {
+	fastReadiness(t, 20*time.Millisecond)
+	var log lifecycle
+	registry := &readyPrerequisite{fakePrerequisite: fakePrerequisite{name: "registry", log: &log}, notReady: 1 << 30}
+	image := &fakePrerequisite{name: "image", deps: []api.TestPrerequisite{registry}, log: &log}
+
+	var stack []api.TestPrerequisite
+	err := install(context.Background(), &stack, image)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "registry is not ready: ") ||
+		!strings.Contains(err.Error(), "not ready yet") {
+		t.Fatalf("expected a readiness timeout with the last readiness error, got %v", err)
+	}
+	if got := strings.Join(log, ","); got != "setup registry" {
+		t.Fatalf("expected the dependents not to be set up, got %s", got)
+	}
+	if len(stack) != 1 {
+		t.Fatalf("expected the registry to be destroyable, got %d installed prerequisites", len(stack))
+	}
+}
+
+func TestDestroyPrerequisitesReverseOrder(t *testing.T) {
+	var log lifecycle
+	registry := &fakePrerequisite{name: "registry", log: &log}
+	cosign := &fakePrerequisite{name: "cosign", log: &log, destroyErr: errors.New("busy")}
+	image := &fakePrerequisite{name: "image", log: &log}
+
+	stack := []api.TestPrerequisite{registry, cosign, image}
+	err := destroy(&stack)
+	if err == nil || !strings.Contains(err.Error(), "busy") {
+		t.Fatalf("expected the destroy error of cosign, got %v", err)
+	}
+	if got, want := strings.Join(log, ","), "destroy image,destroy cosign,destroy registry"; got != want {
+		t.Fatalf("got lifecycle %s, want %s", got, want)
+	}
+	if len(stack) != 0 {
+		t.Fatalf("expected an empty stack, got %d prerequisites", len(stack))
+	}
+}
+
+func TestLocalRegistry(t *testing.T) {
+	registry := NewLocalRegistry()
+	var stack []api.TestPrerequisite
+	if err := install(context.Background(), &stack, registry); err != nil {
+		t.Fatal(err)
+	}
+	if ref := registry.Reference("img"); !strings.HasPrefix(ref, "127.0.0.1:") || !strings.HasSuffix(ref, "/img:latest") {
+		t.Fatalf("unexpected reference %s", ref)
+	}
+	if err := destroy(&stack); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Ready(context.Background()); err == nil {
+		t.Fatal("expected a destroyed registry not to be ready")
+	}
+}
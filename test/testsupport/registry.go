@@ -0,0 +1,95 @@
+package testsupport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/sirupsen/logrus"
+)
+
+// Registry is a prerequisite providing the OCI registry test images are
+// pushed to: the anonymous, short-lived ttl.sh by default, or an in-process
+// stand-in listening on localhost.
+//
+//	registry := testsupport.NewLocalRegistry()
+//	image := testsupport.NewTestImage(source, registry, cosign)
+//	Expect(testsupport.InstallPrerequisites(image)).To(Succeed())
+type Registry struct {
+	// Host is the registry host, e.g. "ttl.sh" or "127.0.0.1:40123" once a
+	// local registry is set up.
+	Host string
+	// Tag is the tag of pushed images. ttl.sh removes images after the
+	// duration it names.
+	Tag string
+
+	local  bool
+	server *httptest.Server
+}
+
+func NewRegistry() *Registry {
+	return &Registry{Host: "ttl.sh", Tag: "5m"}
+}
+
+// NewLocalRegistry returns an in-process registry for suites that run
+// without network access. Images are kept in memory until it is destroyed.
+func NewLocalRegistry() *Registry {
+	return &Registry{Tag: "latest", local: true}
+}
+
+func (r *Registry) GetName() string {
+	if r.local {
+		return "local-registry"
+	}
+	return "registry"
+}
+
+func (r *Registry) Setup(_ context.Context) error {
+	if !r.local {
+		return nil
+	}
+	r.server = httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	r.Host = strings.TrimPrefix(r.server.URL, "http://")
+	logrus.Infof("Local registry running at %s", r.Host)
+	return nil
+}
+
+// Ready reports whether the registry answers the version check of the
+// distribution API. Registries requiring a login answer it as well.
+func (r *Registry) Ready(ctx context.Context) error {
+	scheme := "https"
+	if r.local {
+		scheme = "http"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, scheme+"://"+r.Host+"/v2/", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusUnauthorized {
+		return fmt.Errorf("%s/v2/ returned %s", r.Host, resp.Status)
+	}
+	return nil
+}
+
+// Reference returns the reference of the image repository in the registry.
+func (r *Registry) Reference(repository string) string {
+	return r.Host + "/" + repository + ":" + r.Tag
+}
+
+func (r *Registry) Destroy(_ context.Context) error {
+	if r.server != nil {
+		r.server.Close()
+		r.server = nil
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package testsupport
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/onsi/ginkgo/v2"
+)
+
+var (
+	// RunDeadlineMargin is how long before the Ginkgo suite timeout TestContext
+	// is cancelled, leaving time for cleanup nodes to run.
+	RunDeadlineMargin = 30 * time.Second
+	// CleanupTimeout bounds the destroy phase of prerequisites, which still runs
+	// after the context of the spec was cancelled.
+	CleanupTimeout = 2 * time.Minute
+
+	runStart  time.Time
+	cancelRun context.CancelCauseFunc
+)
+
+func init() {
+	runStart = time.Now()
+	TestContext, cancelRun = context.WithCancelCause(context.Background())
+}
+
+// armRunDeadline cancels TestContext shortly before the Ginkgo suite timeout.
+// It needs the parsed Ginkgo flags, so it is called from the suite setup of
+// every process.
+func armRunDeadline() {
+	suiteConfig, _ := ginkgo.GinkgoConfiguration()
+	if suiteConfig.Timeout <= 0 {
+		return
+	}
+	deadline := runStart.Add(suiteConfig.Timeout - RunDeadlineMargin)
+	time.AfterFunc(time.Until(deadline), func() {
+		cancelRun(errors.New("suite timeout reached"))
+	})
+}
+
+// CleanupContext returns a context for tearing down resources. It is not
+// cancelled together with TestContext or the context of a spec, so cleanup
+// also runs after an interrupt or a timeout.
+func CleanupContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(TestContext), CleanupTimeout)
+}
@@ -2,18 +2,17 @@ package testsupport
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
-	"net/url"
+	"os"
 	"slices"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/securesign/sigstore-e2e/pkg/redact"
 	"github.com/sirupsen/logrus"
 )
 
@@ -23,24 +22,18 @@ const (
 )
 
 var (
+	// TestContext is cancelled shortly before the Ginkgo suite timeout. Specs
+	// run commands with their SpecContext instead, which Ginkgo cancels on an
+	// interrupt or a node timeout.
 	TestContext       context.Context
 	TestTimeoutMedium = 5 * time.Minute
 
-	// CommandRetryTimeout is the maximum time to retry transient command
-	// failures (e.g. TUF httpd returning 503 during cosign/gitsign init).
-	CommandRetryTimeout  = 2 * time.Minute
-	CommandRetryInterval = 10 * time.Second
-
 	// Config keys that must be defined for any test.
-	mandatoryAPIConfigKeys = []string{api.TufURL}
+	mandatoryAPIConfigKeys = api.RequiredKeys()
 )
 
-var installedStack []api.TestPrerequisite = make([]api.TestPrerequisite, 0)
-
 func init() {
-	TestContext = context.TODO()
-
-	logrus.SetFormatter(&logrus.TextFormatter{
+	logrus.SetFormatter(&redact.Formatter{Formatter: &logrus.TextFormatter{
 		SortingFunc: func(s []string) {
 			l := len(s)
 			if l < 1 {
@@ -60,70 +53,7 @@ func init() {
 			logrus.FieldKeyMsg:   "m",
 		},
 		DisableQuote: true,
-	})
-}
-
-func InstallPrerequisites(prerequisite ...api.TestPrerequisite) error {
-	for _, p := range prerequisite {
-		err := p.Setup(TestContext)
-		if err != nil {
-			return err
-		}
-		installedStack = append(installedStack, p)
-	}
-	return nil
-}
-
-func DestroyPrerequisites() error {
-	var errors []error
-	for i := len(installedStack) - 1; i >= 0; i-- {
-		err := installedStack[i].Destroy(TestContext)
-		if err != nil {
-			logrus.Warn(err)
-			errors = append(errors, err)
-		}
-	}
-	if len(errors) != 0 {
-		return fmt.Errorf("can't destroy all prerequisites %s", errors)
-	}
-	return nil
-}
-
-func GetOIDCToken(ctx context.Context) (string, error) {
-	if token := api.GetValueFor(api.OidcToken); token != "" {
-		logrus.Info("Using OIDC token from ENV var")
-		return token, nil
-	}
-	urlString := api.GetValueFor(api.OidcIssuerURL) + "/protocol/openid-connect/token"
-
-	client := &http.Client{}
-	data := url.Values{}
-	data.Set("username", api.GetValueFor(api.OidcUser))
-	data.Set("password", api.GetValueFor(api.OidcPassword))
-	data.Set("scope", "openid")
-	data.Set("client_id", api.GetValueFor(api.OidcRealm))
-	data.Set("grant_type", "password")
-
-	r, _ := http.NewRequestWithContext(ctx, http.MethodPost, urlString, strings.NewReader(data.Encode())) // URL-encoded payload
-	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	r.Header.Add("Content-Length", strconv.Itoa(len(data.Encode())))
-
-	resp, err := client.Do(r)
-	if err != nil {
-		return "", err
-	}
-	b, err := io.ReadAll(resp.Body)
-
-	defer resp.Body.Close()
-	if err != nil {
-		return "", err
-	}
-	jsonOut := make(map[string]interface{})
-	err = json.Unmarshal(b, &jsonOut)
-	if err != nil {
-		return "", err
-	}
-	return fmt.Sprintf("%v", jsonOut["access_token"]), nil
+	}})
 }
 
 func CheckAnyTestMandatoryAPIConfigValues() error {
@@ -140,39 +70,98 @@ func CheckOptionalAPIConfigValues(keys ...string) error {
 }
 
 func checkAPIConfigValues(failOnMissing bool, keys ...string) error {
-	mandatoryMissing := false
-	errorMessage := "Missing configuration for"
+	var errs []error
 	if failOnMissing {
 		logrus.Info("Mandatory configuration:")
 	} else {
 		logrus.Info("Optional configuration:")
 	}
+	if failOnMissing {
+		for _, err := range []error{api.ProfileError(), api.ResolveSecrets(TestContext)} {
+			if err != nil {
+				logrus.Warn(err)
+				errs = append(errs, err)
+			}
+		}
+	}
 	for _, key := range keys {
 		value := api.GetValueFor(key)
-		if value == "" && failOnMissing {
-			mandatoryMissing = true
-			errorMessage += " " + key
-			logrus.Warn(key, "=", value)
-			hintPresent, hint := getHint(key)
-			if hintPresent {
-				logrus.Warn("   Hint: " + hint)
+		schema, known := api.Lookup(key)
+		var err error
+		switch {
+		case value == "" && failOnMissing:
+			err = fmt.Errorf("missing configuration for %s", key)
+		case value == "":
+		case known:
+			err = schema.Validate(value)
+			if err == nil && schema.Type == api.TypeURL {
+				err = checkReachable(value)
 			}
-		} else {
-			logrus.Info(key, "=", value)
+		}
+
+		logged := value
+		if redact.IsSecretKey(key) && value != "" {
+			logged = redact.Mask
+		}
+		if err == nil {
+			logrus.Info(key, "=", logged)
+			continue
+		}
+		logrus.Warn(key, "=", logged, ": ", err)
+		if known {
+			logrus.Warn("   Hint: " + schema.Help())
+		}
+		if failOnMissing {
+			errs = append(errs, err)
 		}
 	}
-	if mandatoryMissing {
+	if len(errs) != 0 {
 		logrus.Warn("   Hint: Missing config values should be provided during cluster installation. " +
 			"Export them as environment variables.")
-		return errors.New(errorMessage)
-	} else {
-		return nil
 	}
+	return errors.Join(errs...)
 }
 
-func getHint(apiKey string) (bool, string) {
-	if apiKey == api.GithubToken {
-		return true, fmt.Sprintf("Authorization token for github client. Export it as environment variable %s.", apiKey)
+// URLReachabilityTimeout bounds the check that a configured URL answers.
+var URLReachabilityTimeout = 10 * time.Second
+
+// checkReachable returns an error if nothing answers HTTP requests at rawURL.
+// Any HTTP response, including an error status, counts as reachable.
+func checkReachable(rawURL string) error {
+	ctx, cancel := context.WithTimeout(TestContext, URLReachabilityTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	client, err := reachabilityClient()
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s is not reachable: %w", rawURL, err)
+	}
+	return resp.Body.Close()
+}
+
+// reachabilityClient returns a client bounded by URLReachabilityTimeout that
+// trusts the system roots and the CA bundle in SSL_CERT_FILE, as the CLIs do.
+func reachabilityClient() (*http.Client, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if file := os.Getenv("SSL_CERT_FILE"); file != "" {
+		bundle, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read the CA bundle in SSL_CERT_FILE: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("no certificates in the CA bundle %s (SSL_CERT_FILE)", file)
+		}
 	}
-	return false, ""
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	return &http.Client{Timeout: URLReachabilityTimeout, Transport: transport}, nil
 }
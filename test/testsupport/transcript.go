@@ -0,0 +1,82 @@
+package testsupport
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/ginkgo/v2/types"
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/securesign/sigstore-e2e/pkg/clients"
+	"github.com/sirupsen/logrus"
+)
+
+// transcript writes every CLI invocation of the run as one JSON line to a
+// file in TRANSCRIPT_DIR and attaches it to the report of the running spec.
+type transcript struct {
+	mu   sync.Mutex
+	file *os.File
+	err  error
+}
+
+var runTranscript = &transcript{}
+
+func init() {
+	clients.ObserveInvocations(runTranscript.record)
+}
+
+// TranscriptPath returns the JSONL file of the current run, or an empty string
+// if no command has run yet.
+func TranscriptPath() string {
+	runTranscript.mu.Lock()
+	defer runTranscript.mu.Unlock()
+	if runTranscript.file == nil {
+		return ""
+	}
+	return runTranscript.file.Name()
+}
+
+func (t *transcript) record(inv clients.Invocation) {
+	if ginkgo.CurrentSpecReport().LeafNodeType != types.NodeTypeInvalid {
+		ginkgo.AddReportEntry(fmt.Sprintf("%s %s", inv.Tool, strings.Join(inv.Args, " ")),
+			inv, ginkgo.ReportEntryVisibilityFailureOrVerbose)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.open(); err != nil {
+		return
+	}
+	line, err := json.Marshal(inv)
+	if err != nil {
+		logrus.Warnf("Cannot encode invocation of %s: %v", inv.Tool, err)
+		return
+	}
+	if _, err := t.file.Write(append(line, '\n')); err != nil {
+		logrus.Warnf("Cannot write transcript %s: %v", t.file.Name(), err)
+	}
+}
+
+// open creates the transcript file on first use. A failure is reported once
+// and disables the file transcript for the rest of the run.
+func (t *transcript) open() error {
+	if t.file != nil || t.err != nil {
+		return t.err
+	}
+	dir := api.GetValueFor(api.TranscriptDir)
+	if t.err = os.MkdirAll(dir, 0755); t.err == nil {
+		name := fmt.Sprintf("invocations-%s-%d.jsonl", time.Now().Format("20060102-150405"), os.Getpid())
+		t.file, t.err = os.Create(filepath.Join(dir, name))
+	}
+	if t.err != nil {
+		logrus.Warnf("CLI transcript disabled: %v", t.err)
+		return t.err
+	}
+	logrus.Infof("Writing CLI transcript to %s", t.file.Name())
+	return nil
+}
@@ -0,0 +1,146 @@
+package testsupport
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/ginkgo/v2/types"
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/securesign/sigstore-e2e/pkg/clients"
+	"github.com/sirupsen/logrus"
+)
+
+// usageReportEntry names the report entries with the resource usage of the
+// commands run by a spec or suite node.
+const usageReportEntry = "CLI resource usage"
+
+var (
+	// nodeUsage collects the usage of the commands run since the last report entry.
+	nodeUsageMu sync.Mutex
+	nodeUsage   = &clients.UsageSummary{}
+
+	thresholdsMu   sync.Mutex
+	thresholds     map[string]clients.UsageThreshold
+	thresholdsOnce sync.Once
+
+	// usageViolations are recorded by checkUsage, which may run on goroutines
+	// of the clients, and reported by the spec that ran the commands.
+	usageMu         sync.Mutex
+	usageViolations []string
+)
+
+func init() {
+	clients.ObserveInvocations(checkUsage)
+}
+
+// SetUsageThreshold limits the resources of command (e.g. "cosign verify") for
+// the rest of the run. It takes precedence over USAGE_THRESHOLDS.
+func SetUsageThreshold(command string, t clients.UsageThreshold) {
+	loadUsageThresholds()
+	thresholdsMu.Lock()
+	defer thresholdsMu.Unlock()
+	thresholds[command] = t
+}
+
+func loadUsageThresholds() {
+	thresholdsOnce.Do(func() {
+		parsed, err := clients.ParseUsageThresholds(api.GetValueFor(api.UsageThresholds))
+		if err != nil {
+			logrus.Errorf("Ignoring %s: %v", api.UsageThresholds, err)
+			parsed = map[string]clients.UsageThreshold{}
+		}
+		thresholdsMu.Lock()
+		defer thresholdsMu.Unlock()
+		thresholds = parsed
+	})
+}
+
+// checkUsage records invocations that exceed the threshold of their command.
+// Outside of a spec they are only logged.
+func checkUsage(inv clients.Invocation) {
+	if inv.Usage == nil {
+		return
+	}
+	nodeUsageMu.Lock()
+	nodeUsage.Add(inv)
+	nodeUsageMu.Unlock()
+
+	loadUsageThresholds()
+	thresholdsMu.Lock()
+	threshold, ok := thresholds[inv.Command()]
+	thresholdsMu.Unlock()
+	if !ok {
+		return
+	}
+	if err := threshold.Check(*inv.Usage); err != nil {
+		msg := fmt.Sprintf("%s exceeded its resource threshold: %v", inv.Command(), err)
+		if ginkgo.CurrentSpecReport().LeafNodeType == types.NodeTypeInvalid {
+			logrus.Error(msg)
+			return
+		}
+		usageMu.Lock()
+		usageViolations = append(usageViolations, msg)
+		usageMu.Unlock()
+	}
+}
+
+// reportUsage attaches the usage of the commands run since the last call to
+// the report of the running spec or suite node.
+func reportUsage() {
+	nodeUsageMu.Lock()
+	stats := nodeUsage.Stats()
+	nodeUsage = &clients.UsageSummary{}
+	nodeUsageMu.Unlock()
+	if len(stats) > 0 {
+		ginkgo.AddReportEntry(usageReportEntry, stats, ginkgo.ReportEntryVisibilityNever)
+	}
+}
+
+// Reports the usage of the spec and fails it if its commands exceeded their
+// threshold, from the spec goroutine.
+var _ = ginkgo.AfterEach(func() {
+	reportUsage()
+	usageMu.Lock()
+	violations := usageViolations
+	usageViolations = nil
+	usageMu.Unlock()
+	if len(violations) > 0 {
+		ginkgo.Fail(strings.Join(violations, "\n"))
+	}
+})
+
+// Logs the usage per command of the whole run, aggregated from the report
+// entries of all parallel processes.
+var _ = ginkgo.ReportAfterSuite("CLI resource usage", func(report ginkgo.Report) {
+	var summary clients.UsageSummary
+	for _, spec := range report.SpecReports {
+		for _, entry := range spec.ReportEntries {
+			if entry.Name != usageReportEntry {
+				continue
+			}
+			stats, err := usageOfEntry(entry)
+			if err != nil {
+				logrus.Warnf("Cannot read resource usage of %q: %v", spec.FullText(), err)
+				continue
+			}
+			summary.Merge(stats)
+		}
+	}
+	if len(summary.Stats()) > 0 {
+		logrus.Info("CLI resource usage:\n" + summary.String())
+	}
+})
+
+// usageOfEntry returns the value of a usage report entry. Entries of other
+// processes only carry their JSON encoding.
+func usageOfEntry(entry types.ReportEntry) (map[string]clients.UsageStats, error) {
+	if stats, ok := entry.GetRawValue().(map[string]clients.UsageStats); ok {
+		return stats, nil
+	}
+	var stats map[string]clients.UsageStats
+	err := json.Unmarshal([]byte(entry.Value.AsJSON), &stats)
+	return stats, err
+}
@@ -38,18 +38,18 @@ var _ = Describe("Trillian tools - CreateTree and UpdateTree", Ordered, func() {
 	})
 
 	Describe("Execute createtree help", func() {
-		It("run help command", func() {
+		It("run help command", func(ctx SpecContext) {
 
-			output, err := createTree.CommandOutput(testsupport.TestContext, "--help")
+			output, err := createTree.CommandOutput(ctx, "--help")
 			Expect(err).NotTo(HaveOccurred())
 			Expect(output).To(ContainSubstring("Usage of ")) // just to make sure output appeared
 		})
 	})
 
 	Describe("Execute updatetree help", func() {
-		It("run help command", func() {
+		It("run help command", func(ctx SpecContext) {
 
-			output, err := updateTree.CommandOutput(testsupport.TestContext, "--help")
+			output, err := updateTree.CommandOutput(ctx, "--help")
 			Expect(err).NotTo(HaveOccurred())
 			Expect(output).To(ContainSubstring("Usage of ")) // just to make sure output appeared
 		})
@@ -0,0 +1,13 @@
+package tufconfig
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestTUFConfig(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Validate the trusted root and signing config distributed by TUF against the running services")
+}
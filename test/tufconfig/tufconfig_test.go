@@ -0,0 +1,141 @@
+package tufconfig
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/securesign/sigstore-e2e/pkg/api"
+	"github.com/securesign/sigstore-e2e/pkg/discovery"
+	"github.com/securesign/sigstore-e2e/pkg/preflight"
+	"github.com/securesign/sigstore-e2e/test/testsupport"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TUF distributed configuration", Ordered, func() {
+
+	var cfg *discovery.TUFConfig
+
+	BeforeAll(func() {
+		Expect(testsupport.CheckAnyTestMandatoryAPIConfigValues()).To(Succeed())
+		testsupport.RequireServices(preflight.TUF)
+
+		var err error
+		cfg, err = testsupport.TUFConfig()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should distribute a trusted root with a Rekor log and a certificate authority", func() {
+		Expect(cfg.TrustedRoot.Tlogs).ToNot(BeEmpty())
+		Expect(cfg.TrustedRoot.CertificateAuthorities).ToNot(BeEmpty())
+	})
+
+	It("should list the Rekor public key served by Rekor", func(ctx SpecContext) {
+		for _, tlog := range cfg.TrustedRoot.Tlogs {
+			if tlog.PublicKey.ValidFor != nil && tlog.PublicKey.ValidFor.End != nil {
+				continue
+			}
+			blocks := getPEM(ctx, strings.TrimRight(tlog.BaseURL, "/")+"/api/v1/log/publicKey")
+			Expect(blocks).To(HaveLen(1))
+			Expect(blocks[0]).To(Equal(tlog.PublicKey.RawBytes), "public key of %s", tlog.BaseURL)
+
+			keyID := sha256.Sum256(blocks[0])
+			Expect(tlog.LogID.KeyID).To(Equal(keyID[:]), "log ID of %s", tlog.BaseURL)
+		}
+	})
+
+	It("should list the certificate chain served by Fulcio", func(ctx SpecContext) {
+		ca := cfg.TrustedRoot.CertificateAuthorities[len(cfg.TrustedRoot.CertificateAuthorities)-1]
+
+		var bundle struct {
+			Chains []struct {
+				Certificates []string `json:"certificates"`
+			} `json:"chains"`
+		}
+		Expect(json.Unmarshal(get(ctx, strings.TrimRight(ca.URI, "/")+"/api/v2/trustBundle"), &bundle)).To(Succeed())
+
+		var served [][]byte
+		for _, chain := range bundle.Chains {
+			for _, cert := range chain.Certificates {
+				served = append(served, decodePEM([]byte(cert))...)
+			}
+		}
+		for _, cert := range ca.CertChain.Certificates {
+			Expect(served).To(ContainElement(cert.RawBytes), "certificate of %s", ca.URI)
+		}
+	})
+
+	It("should list the certificate chain served by the timestamp authority", func(ctx SpecContext) {
+		if len(cfg.TrustedRoot.TimestampAuthorities) == 0 {
+			Skip("the trusted root has no timestamp authority")
+		}
+		tsa := cfg.TrustedRoot.TimestampAuthorities[len(cfg.TrustedRoot.TimestampAuthorities)-1]
+		served := getPEM(ctx, strings.TrimRight(tsa.URI, "/")+"/api/v1/timestamp/certchain")
+		for _, cert := range tsa.CertChain.Certificates {
+			Expect(served).To(ContainElement(cert.RawBytes), "certificate of %s", tsa.URI)
+		}
+	})
+
+	It("should match the configured service URLs", func() {
+		if cfg.SigningConfig == nil {
+			Skip("the TUF repository has no signing config")
+		}
+		distributed := cfg.Endpoints().Vars()
+		compared := 0
+		for _, key := range []string{api.FulcioURL, api.RekorURL, api.TsaURL, api.OidcIssuerURL} {
+			configured := api.GetValueFor(key)
+			// URLs filled from the signing config would be compared with themselves
+			if configured == "" || distributed[key] == "" || testsupport.DiscoveredFromTUF(key) {
+				continue
+			}
+			Expect(strings.TrimRight(distributed[key], "/")).To(Equal(strings.TrimRight(configured, "/")),
+				"%s distributed by %s", key, discovery.SigningConfigTarget)
+			compared++
+		}
+		if compared == 0 {
+			Skip(fmt.Sprintf("no service URL is configured or discovered from the cluster to compare with %s; "+
+				"with %s=true, set them explicitly", discovery.SigningConfigTarget, api.TufDiscovery))
+		}
+	})
+})
+
+func get(ctx context.Context, url string) []byte {
+	GinkgoHelper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	Expect(err).ToNot(HaveOccurred())
+	resp, err := http.DefaultClient.Do(req)
+	Expect(err).ToNot(HaveOccurred())
+	defer resp.Body.Close()
+	Expect(resp.StatusCode).To(Equal(http.StatusOK), "GET %s", url)
+	body, err := io.ReadAll(resp.Body)
+	Expect(err).ToNot(HaveOccurred())
+	return body
+}
+
+func getPEM(ctx context.Context, url string) [][]byte {
+	GinkgoHelper()
+	blocks := decodePEM(get(ctx, url))
+	Expect(blocks).ToNot(BeEmpty(), "expected PEM from %s", url)
+	return blocks
+}
+
+// decodePEM returns the DER bytes of every PEM block in data.
+func decodePEM(data []byte) [][]byte {
+	var blocks [][]byte
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(bytes.TrimSpace(data))
+		if block == nil {
+			return blocks
+		}
+		blocks = append(blocks, block.Bytes)
+	}
+}
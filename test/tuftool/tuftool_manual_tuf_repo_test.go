@@ -1,6 +1,7 @@
 package tuftool
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -57,12 +58,13 @@ var _ = Describe("TUF manual repo test", Ordered, func() {
 
 		workdir, err = os.MkdirTemp("", "trustroot_example")
 		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(os.RemoveAll, workdir)
 
 		logrus.Infof("Created temporary directory: %s", workdir)
 	})
 
-	It("should setup repository via tuftool", func() {
-		setupManualTufRepo(tuftool)
+	It("should setup repository via tuftool", func(ctx SpecContext) {
+		setupManualTufRepo(ctx, tuftool)
 	})
 
 	It("should verify workdir structure", func() {
@@ -70,7 +72,7 @@ var _ = Describe("TUF manual repo test", Ordered, func() {
 	})
 })
 
-func setupManualTufRepo(tuftool *clients.Tuftool) {
+func setupManualTufRepo(ctx context.Context, tuftool *clients.Tuftool) {
 	// "Tuf repo directory"
 	root = filepath.Join(workdir, "root", "root.json")
 	rootDir = filepath.Join(workdir, "root")
@@ -87,22 +89,22 @@ func setupManualTufRepo(tuftool *clients.Tuftool) {
 	err = os.MkdirAll(tufRepo, os.ModePerm)
 	Expect(err).ToNot(HaveOccurred())
 
-	Expect(tuftool.Command(testsupport.TestContext, "root", "init", root).Run()).To(Succeed())
-	Expect(tuftool.Command(testsupport.TestContext, "root", "expire", root, expirationWeeks52).Run()).To(Succeed())
+	Expect(tuftool.Command(ctx, "root", "init", root).Run()).To(Succeed())
+	Expect(tuftool.Command(ctx, "root", "expire", root, expirationWeeks52).Run()).To(Succeed())
 
-	Expect(tuftool.Command(testsupport.TestContext, "root", "set-threshold", root, "root", "1").Run()).To(Succeed())
-	Expect(tuftool.Command(testsupport.TestContext, "root", "set-threshold", root, "snapshot", "1").Run()).To(Succeed())
-	Expect(tuftool.Command(testsupport.TestContext, "root", "set-threshold", root, "targets", "1").Run()).To(Succeed())
-	Expect(tuftool.Command(testsupport.TestContext, "root", "set-threshold", root, "timestamp", "1").Run()).To(Succeed())
+	Expect(tuftool.Command(ctx, "root", "set-threshold", root, "root", "1").Run()).To(Succeed())
+	Expect(tuftool.Command(ctx, "root", "set-threshold", root, "snapshot", "1").Run()).To(Succeed())
+	Expect(tuftool.Command(ctx, "root", "set-threshold", root, "targets", "1").Run()).To(Succeed())
+	Expect(tuftool.Command(ctx, "root", "set-threshold", root, "timestamp", "1").Run()).To(Succeed())
 
-	Expect(tuftool.Command(testsupport.TestContext, "root", "gen-rsa-key", root, keyDir+"/root.pem", "--role", "root").Run()).To(Succeed())
-	Expect(tuftool.Command(testsupport.TestContext, "root", "gen-rsa-key", root, keyDir+"/snapshot.pem", "--role", "snapshot").Run()).To(Succeed())
-	Expect(tuftool.Command(testsupport.TestContext, "root", "gen-rsa-key", root, keyDir+"/targets.pem", "--role", "targets").Run()).To(Succeed())
-	Expect(tuftool.Command(testsupport.TestContext, "root", "gen-rsa-key", root, keyDir+"/timestamp.pem", "--role", "timestamp").Run()).To(Succeed())
+	Expect(tuftool.Command(ctx, "root", "gen-rsa-key", root, keyDir+"/root.pem", "--role", "root").Run()).To(Succeed())
+	Expect(tuftool.Command(ctx, "root", "gen-rsa-key", root, keyDir+"/snapshot.pem", "--role", "snapshot").Run()).To(Succeed())
+	Expect(tuftool.Command(ctx, "root", "gen-rsa-key", root, keyDir+"/targets.pem", "--role", "targets").Run()).To(Succeed())
+	Expect(tuftool.Command(ctx, "root", "gen-rsa-key", root, keyDir+"/timestamp.pem", "--role", "timestamp").Run()).To(Succeed())
 
-	Expect(tuftool.Command(testsupport.TestContext, "root", "sign", root, "-k", keyDir+"/root.pem").Run()).To(Succeed())
+	Expect(tuftool.Command(ctx, "root", "sign", root, "-k", keyDir+"/root.pem").Run()).To(Succeed())
 
-	Expect(tuftool.Command(testsupport.TestContext, "create",
+	Expect(tuftool.Command(ctx, "create",
 		"--root", root,
 		"--key", keyDir+"/root.pem",
 		"--key", keyDir+"/snapshot.pem",
@@ -117,7 +119,7 @@ func setupManualTufRepo(tuftool *clients.Tuftool) {
 		"--timestamp-version", "1",
 		"--outdir", tufRepo).Run()).To(Succeed())
 
-	Expect(tuftool.Command(testsupport.TestContext, "rhtas",
+	Expect(tuftool.Command(ctx, "rhtas",
 		"--root", root,
 		"--key", keyDir+"/root.pem",
 		"--key", keyDir+"/snapshot.pem",
@@ -135,7 +137,7 @@ func setupManualTufRepo(tuftool *clients.Tuftool) {
 		"--outdir", tufRepo,
 		"--metadata-url", "file://"+tufRepo).Run()).To(Succeed())
 
-	Expect(tuftool.Command(testsupport.TestContext, "rhtas",
+	Expect(tuftool.Command(ctx, "rhtas",
 		"--root", root,
 		"--key", keyDir+"/root.pem",
 		"--key", keyDir+"/snapshot.pem",
@@ -153,7 +155,7 @@ func setupManualTufRepo(tuftool *clients.Tuftool) {
 		"--outdir", tufRepo,
 		"--metadata-url", "file://"+tufRepo).Run()).To(Succeed())
 
-	Expect(tuftool.Command(testsupport.TestContext, "rhtas",
+	Expect(tuftool.Command(ctx, "rhtas",
 		"--root", root,
 		"--key", keyDir+"/root.pem",
 		"--key", keyDir+"/snapshot.pem",
@@ -171,7 +173,7 @@ func setupManualTufRepo(tuftool *clients.Tuftool) {
 		"--outdir", tufRepo,
 		"--metadata-url", "file://"+tufRepo).Run()).To(Succeed())
 
-	Expect(tuftool.Command(testsupport.TestContext, "rhtas",
+	Expect(tuftool.Command(ctx, "rhtas",
 		"--root", root,
 		"--key", keyDir+"/root.pem",
 		"--key", keyDir+"/snapshot.pem",
@@ -290,8 +292,3 @@ func verifyWorkdirStructure(rootPath string) {
 		}
 	}
 }
-
-var _ = AfterSuite(func() {
-	// Cleanup shared resources after all tests have run.
-	Expect(os.RemoveAll(workdir)).To(Succeed())
-})